@@ -0,0 +1,170 @@
+package gormrepo
+
+import (
+	"database/sql"
+
+	"github.com/yyle88/gormcnm"
+	"gorm.io/gorm"
+)
+
+// Number is the constraint of column types that SUM accepts
+//
+// Number 是 SUM 可接受的列类型约束
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Aggregate holds the aggregate values of one column in one group
+// SQL aggregates are NULL when no value exists, so each one is a sql.Null that keeps the Valid flag
+//
+// Aggregate 保存一个分组中某列的聚合值
+// 没有值时 SQL 聚合结果为 NULL，因此每个值都是保留 Valid 标志的 sql.Null
+type Aggregate[T Number] struct {
+	Count int64             // Number of records in the group // 分组中的记录数
+	Sum   sql.Null[T]       // SUM of the column // 列的 SUM
+	Avg   sql.Null[float64] // AVG of the column // 列的 AVG
+	Min   sql.Null[T]       // MIN of the column // 列的 MIN
+	Max   sql.Null[T]       // MAX of the column // 列的 MAX
+}
+
+// Sum returns SUM of the column in records matching the where condition
+// Result is not Valid when no record matches or all values are NULL, instead of being silently zero
+//
+// Sum 返回符合 where 条件的记录中该列的 SUM
+// 当没有匹配记录或所有值为 NULL 时，结果的 Valid 为 false，而不是静默返回零
+func Sum[MOD any, CLS any, T Number](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, column func(cls CLS) gormcnm.ColumnName[T]) (sql.Null[T], error) {
+	return aggregateOf[MOD, CLS, T](repo, OpSum, where, "SUM("+column(repo.cls).Name()+")")
+}
+
+// Avg returns AVG of the column in records matching the where condition
+// Result is not Valid when no record matches or all values are NULL
+//
+// Avg 返回符合 where 条件的记录中该列的 AVG
+// 当没有匹配记录或所有值为 NULL 时，结果的 Valid 为 false
+func Avg[MOD any, CLS any, T Number](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, column func(cls CLS) gormcnm.ColumnName[T]) (sql.Null[float64], error) {
+	return aggregateOf[MOD, CLS, float64](repo, OpAvg, where, "AVG("+column(repo.cls).Name()+")")
+}
+
+// Min returns MIN of the column in records matching the where condition
+// Result is not Valid when no record matches or all values are NULL
+//
+// Min 返回符合 where 条件的记录中该列的 MIN
+// 当没有匹配记录或所有值为 NULL 时，结果的 Valid 为 false
+func Min[MOD any, CLS any, T any](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, column func(cls CLS) gormcnm.ColumnName[T]) (sql.Null[T], error) {
+	return aggregateOf[MOD, CLS, T](repo, OpMin, where, "MIN("+column(repo.cls).Name()+")")
+}
+
+// Max returns MAX of the column in records matching the where condition
+// Result is not Valid when no record matches or all values are NULL
+//
+// Max 返回符合 where 条件的记录中该列的 MAX
+// 当没有匹配记录或所有值为 NULL 时，结果的 Valid 为 false
+func Max[MOD any, CLS any, T any](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, column func(cls CLS) gormcnm.ColumnName[T]) (sql.Null[T], error) {
+	return aggregateOf[MOD, CLS, T](repo, OpMax, where, "MAX("+column(repo.cls).Name()+")")
+}
+
+func aggregateOf[MOD any, CLS any, T any](repo *GormRepo[MOD, CLS], op Operation, where func(db *gorm.DB, cls CLS) *gorm.DB, expression string) (sql.Null[T], error) {
+	var result sql.Null[T]
+	if _, err := repo.intercept(&Invocation{Operation: op, Where: where, Dest: &result}, func(db *gorm.DB) *gorm.DB {
+		db = where(db, repo.cls).Model(new(MOD)).Select(expression)
+		return scanRows(db, func(rows *sql.Rows) error {
+			return rows.Scan(&result)
+		})
+	}); err != nil {
+		return sql.Null[T]{}, err
+	}
+	return result, nil
+}
+
+// GroupCount counts records matching the where condition, grouped by the column
+// Returns a map from group value to record count, groups without records are absent
+// Records whose group column is NULL are skipped, count them with Count and an IS NULL where condition
+//
+// GroupCount 按列分组统计符合 where 条件的记录数
+// 返回分组值到记录数的映射，没有记录的分组不会出现
+// 分组列为 NULL 的记录被跳过，可使用 Count 加上 IS NULL 的 where 条件统计它们
+func GroupCount[MOD any, CLS any, K comparable](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, groupColumn func(cls CLS) gormcnm.ColumnName[K]) (map[K]int64, error) {
+	var name = groupColumn(repo.cls).Name()
+	var results map[K]int64
+	if _, err := repo.intercept(&Invocation{Operation: OpGroupCount, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		results = make(map[K]int64)
+		db = where(db, repo.cls).Model(new(MOD)).Select(name + ", COUNT(*)").Group(name)
+		return scanRows(db, func(rows *sql.Rows) error {
+			var key sql.Null[K]
+			var count int64
+			if err := rows.Scan(&key, &count); err != nil {
+				return err
+			}
+			if key.Valid {
+				results[key.V] = count
+			}
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GroupAgg aggregates the value column in records matching the where condition, grouped by the group column
+// Returns a map from group value to Aggregate, where NULL aggregates are kept as not Valid
+// Records whose group column is NULL are skipped, like in GroupCount
+//
+// GroupAgg 按分组列聚合符合 where 条件的记录的值列
+// 返回分组值到 Aggregate 的映射，NULL 聚合结果保留为 Valid=false
+// 分组列为 NULL 的记录被跳过，与 GroupCount 相同
+func GroupAgg[MOD any, CLS any, K comparable, T Number](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, groupColumn func(cls CLS) gormcnm.ColumnName[K], valueColumn func(cls CLS) gormcnm.ColumnName[T]) (map[K]*Aggregate[T], error) {
+	var name = groupColumn(repo.cls).Name()
+	var value = valueColumn(repo.cls).Name()
+	var results map[K]*Aggregate[T]
+	if _, err := repo.intercept(&Invocation{Operation: OpGroupAgg, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		results = make(map[K]*Aggregate[T])
+		db = where(db, repo.cls).Model(new(MOD)).
+			Select(name + ", COUNT(*), SUM(" + value + "), AVG(" + value + "), MIN(" + value + "), MAX(" + value + ")").
+			Group(name)
+		return scanRows(db, func(rows *sql.Rows) error {
+			var key sql.Null[K]
+			var aggregate = &Aggregate[T]{}
+			if err := rows.Scan(&key, &aggregate.Count, &aggregate.Sum, &aggregate.Avg, &aggregate.Min, &aggregate.Max); err != nil {
+				return err
+			}
+			if key.Valid {
+				results[key.V] = aggregate
+			}
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// scanRows runs the query and invokes scan on each row, closing rows at the end
+// Returns db carrying the error, like the other operations running through intercept
+//
+// scanRows 执行查询并对每一行调用 scan，最后关闭 rows
+// 返回携带错误的 db，与其他经过 intercept 运行的操作一致
+func scanRows(db *gorm.DB, scan func(rows *sql.Rows) error) *gorm.DB {
+	rows, err := db.Rows()
+	if err != nil {
+		if db.Error == nil {
+			_ = db.AddError(err)
+		}
+		return db
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = db.AddError(err)
+	}
+	return db
+}
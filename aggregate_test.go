@@ -0,0 +1,158 @@
+package gormrepo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"gorm.io/gorm"
+)
+
+// setupAggregateData creates accounts in two nickname groups
+// setupAggregateData 创建分属两个昵称分组的账户
+func setupAggregateData(t *testing.T, db *gorm.DB) {
+	must.Done(db.AutoMigrate(&Account{}))
+	for _, item := range []struct{ username, nickname string }{
+		{"aggregate-1", "group-a"},
+		{"aggregate-2", "group-a"},
+		{"aggregate-3", "group-b"},
+	} {
+		account := newAccount(item.username)
+		account.Nickname = item.nickname
+		must.Done(db.Create(account).Error)
+	}
+}
+
+// TestSum tests SUM with matching records and the NULL result on empty sets
+// TestSum 测试有匹配记录时的 SUM 以及空集时的 NULL 结果
+func TestSum(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupAggregateData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	t.Run("case-1", func(t *testing.T) {
+		sum, err := gormrepo.Sum(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Nickname.Eq("group-a"))
+		}, func(cls *AccountColumns) gormcnm.ColumnName[uint] {
+			return cls.ID
+		})
+		require.NoError(t, err)
+		require.True(t, sum.Valid)
+		require.Equal(t, uint(3), sum.V)
+	})
+
+	t.Run("case-2", func(t *testing.T) {
+		sum, err := gormrepo.Sum(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Nickname.Eq("group-x"))
+		}, func(cls *AccountColumns) gormcnm.ColumnName[uint] {
+			return cls.ID
+		})
+		require.NoError(t, err)
+		require.False(t, sum.Valid)
+	})
+}
+
+// TestAvg tests AVG returning float64 regardless of the column type
+// TestAvg 测试 AVG 无论列类型如何都返回 float64
+func TestAvg(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupAggregateData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	avg, err := gormrepo.Avg(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Nickname.Eq("group-a"))
+	}, func(cls *AccountColumns) gormcnm.ColumnName[uint] {
+		return cls.ID
+	})
+	require.NoError(t, err)
+	require.True(t, avg.Valid)
+	require.Equal(t, 1.5, avg.V)
+}
+
+// TestMinMax tests MIN and MAX on string columns
+// TestMinMax 测试字符串列上的 MIN 和 MAX
+func TestMinMax(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupAggregateData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	where := func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Like("aggregate-%"))
+	}
+	column := func(cls *AccountColumns) gormcnm.ColumnName[string] {
+		return cls.Username
+	}
+
+	minValue, err := gormrepo.Min(repo, where, column)
+	require.NoError(t, err)
+	require.Equal(t, "aggregate-1", minValue.V)
+
+	maxValue, err := gormrepo.Max(repo, where, column)
+	require.NoError(t, err)
+	require.Equal(t, "aggregate-3", maxValue.V)
+}
+
+// TestGroupCount tests counting records grouped by a column
+// TestGroupCount 测试按列分组计数
+func TestGroupCount(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupAggregateData(t, db)
+
+	// Records with a NULL group key are skipped
+	// 分组键为 NULL 的记录被跳过
+	must.Done(db.Exec("INSERT INTO accounts (username, nickname) VALUES (?, NULL)", "aggregate-null").Error)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	counts, err := gormrepo.GroupCount(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+		return cls.Nickname
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"group-a": 2, "group-b": 1}, counts)
+
+	count, err := repo.Count(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Nickname.IsNull())
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+// TestGroupAgg tests aggregates grouped by a column
+// TestGroupAgg 测试按列分组的聚合
+func TestGroupAgg(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupAggregateData(t, db)
+
+	must.Done(db.Exec("INSERT INTO accounts (username, nickname) VALUES (?, NULL)", "aggregate-null").Error)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	results, err := gormrepo.GroupAgg(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+		return cls.Nickname
+	}, func(cls *AccountColumns) gormcnm.ColumnName[uint] {
+		return cls.ID
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	groupA := results["group-a"]
+	require.Equal(t, int64(2), groupA.Count)
+	require.Equal(t, uint(3), groupA.Sum.V)
+	require.Equal(t, 1.5, groupA.Avg.V)
+	require.Equal(t, uint(1), groupA.Min.V)
+	require.Equal(t, uint(2), groupA.Max.V)
+
+	groupB := results["group-b"]
+	require.Equal(t, int64(1), groupB.Count)
+	require.Equal(t, uint(3), groupB.Sum.V)
+}
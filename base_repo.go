@@ -21,7 +21,8 @@ import (
 // 提供方法来创建带数据库连接的 GormRepo/GormWrap 实例
 // 使用泛型确保 MOD 和 CLS 定义的类型安全
 type BaseRepo[MOD any, CLS any] struct {
-	cls CLS // Column definitions // 列定义
+	cls          CLS           // Column definitions // 列定义
+	interceptors []Interceptor // Interceptors of the repos created // 所创建仓储的拦截器
 }
 
 // NewBaseRepo creates a new BaseRepo instance with CLS definitions
@@ -41,7 +42,11 @@ func NewBaseRepo[MOD any, CLS any](_ *MOD, cls CLS) *BaseRepo[MOD, CLS] {
 // Repo 使用给定的数据库连接创建 GormRepo 实例
 // GormRepo 方法返回 (T, error) 签名
 func (repo *BaseRepo[MOD, CLS]) Repo(db *gorm.DB) *GormRepo[MOD, CLS] {
-	return NewGormRepo(db, (*MOD)(nil), repo.cls)
+	return &GormRepo[MOD, CLS]{
+		db:           db,
+		cls:          repo.cls,
+		interceptors: repo.interceptors,
+	}
 }
 
 // Gorm creates a GormWrap instance with the given database connection
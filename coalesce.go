@@ -0,0 +1,146 @@
+package gormrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// CoalesceReads returns an interceptor sharing one database round trip among concurrent identical reads
+// Reads are identical when they have the same MOD, operation, rendered SQL and args, on the same connection or transaction
+// Each caller gets its own copy of the results: structs are copied, nested pointers and slices in them are shared
+// Coalesces First, Take, Last, FindOne, Exist, Find, FindN, FindPage and Count; other operations run directly
+// Put it after the interceptors changing DB (e.g. adding predicates), so the rendered SQL includes their changes
+// Use one returned interceptor across the repos meant to share round trips, e.g. on the BaseRepo
+//
+// CoalesceReads 返回让并发的相同读取共享一次数据库往返的拦截器
+// 相同的读取指 MOD、操作、渲染的 SQL 和参数都相同，且在同一连接或同一事务上
+// 每个调用方得到其结果的独立副本：结构体被复制，其中嵌套的指针和切片是共享的
+// 合并 First、Take、Last、FindOne、Exist、Find、FindN、FindPage 和 Count；其他操作直接运行
+// 应放在修改 DB 的拦截器（例如添加条件）之后，使渲染的 SQL 包含其修改
+// 在需要共享往返的仓储之间使用同一个返回的拦截器，例如设置在 BaseRepo 上
+func CoalesceReads() Interceptor {
+	var group singleflight.Group
+	return func(inv *Invocation, next func() error) error {
+		if !coalescible(inv) {
+			return next()
+		}
+		key, ok := coalesceKey(inv)
+		if !ok {
+			return next()
+		}
+		var leader bool
+		value, err, _ := group.Do(key, func() (interface{}, error) {
+			leader = true
+			if err := next(); err != nil {
+				return nil, err
+			}
+			return &coalescedRead{dest: cloneValue(reflect.ValueOf(inv.Dest).Elem()), rowsAffected: inv.RowsAffected}, nil
+		})
+		if leader {
+			return err
+		}
+		if err != nil {
+			// The leader was canceled, while this caller may still run the read itself
+			// 领头者被取消，而此调用方仍可自行运行该读取
+			if (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) && inv.Context.Err() == nil {
+				return next()
+			}
+			return err
+		}
+		read := value.(*coalescedRead)
+		reflect.ValueOf(inv.Dest).Elem().Set(cloneValue(read.dest))
+		inv.RowsAffected = read.rowsAffected
+		return nil
+	}
+}
+
+// coalescedRead holds the results of the leader, which followers copy but never modify
+// coalescedRead 保存领头者的结果，跟随者复制但不修改它
+type coalescedRead struct {
+	dest         reflect.Value
+	rowsAffected int64
+}
+
+// coalescible reports whether the results of the operation are all in Dest
+// coalescible 判断操作的结果是否全部位于 Dest 中
+func coalescible(inv *Invocation) bool {
+	switch inv.Operation {
+	case OpFirst, OpTake, OpLast, OpFindOne, OpExist, OpFind, OpFindN, OpFindPage, OpCount:
+		return inv.run != nil && inv.Dest != nil && reflect.TypeOf(inv.Dest).Kind() == reflect.Ptr
+	default:
+		return false
+	}
+}
+
+// coalesceKey renders the first statement of the operation without running it, and keys it by the connection scope
+// Returns false when the statement cannot be rendered
+//
+// coalesceKey 在不运行操作的情况下渲染其第一条语句，并以连接范围作为键的一部分
+// 无法渲染语句时返回 false
+func coalesceKey(inv *Invocation) (string, bool) {
+	pool := &renderPool{}
+	// Setting the context clones the statement, so swapping its pool leaves inv.DB intact
+	// 设置上下文会克隆语句，因此替换其连接池不影响 inv.DB
+	db := inv.DB.Session(&gorm.Session{Context: inv.DB.Statement.Context, Logger: logger.Discard})
+	db.Statement.ConnPool = pool
+	inv.run(db)
+	if !pool.rendered {
+		return "", false
+	}
+	return fmt.Sprintf("%s|%s|%p|%s|%#v", inv.ModelType, inv.Operation, inv.DB.Statement.ConnPool, pool.query, pool.args), true
+}
+
+var errRendered = errors.New("statement rendered, not run")
+
+// renderPool records the first query sent to it, failing everything so that nothing runs
+// renderPool 记录发送给它的第一条查询，使所有调用失败从而不运行任何语句
+type renderPool struct {
+	rendered bool
+	query    string
+	args     []interface{}
+}
+
+func (pool *renderPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errRendered
+}
+
+func (pool *renderPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errRendered
+}
+
+func (pool *renderPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if !pool.rendered {
+		pool.rendered, pool.query, pool.args = true, query, args
+	}
+	return nil, errRendered
+}
+
+func (pool *renderPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+// cloneValue copies the value, giving slices of pointers their own elements
+// cloneValue 复制值，使指针切片拥有独立的元素
+func cloneValue(value reflect.Value) reflect.Value {
+	res := reflect.New(value.Type()).Elem()
+	if value.Kind() != reflect.Slice || value.Type().Elem().Kind() != reflect.Ptr || value.IsNil() {
+		res.Set(value)
+		return res
+	}
+	res.Set(reflect.MakeSlice(value.Type(), value.Len(), value.Len()))
+	for idx := 0; idx < value.Len(); idx++ {
+		if elem := value.Index(idx); !elem.IsNil() {
+			one := reflect.New(elem.Type().Elem())
+			one.Elem().Set(elem.Elem())
+			res.Index(idx).Set(one)
+		}
+	}
+	return res
+}
@@ -0,0 +1,118 @@
+package gormrepo_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"gorm.io/gorm"
+)
+
+// TestCoalesceReads tests concurrent identical reads sharing one round trip, each caller getting its own copy
+// TestCoalesceReads 测试并发的相同读取共享一次往返，每个调用方得到独立的副本
+func TestCoalesceReads(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	const callers = 8
+	var arrived, queried atomic.Int32
+	base := gormrepo.NewBaseRepo(&Account{}, (&Account{}).Columns()).
+		WithInterceptors(func(inv *gormrepo.Invocation, next func() error) error {
+			arrived.Add(1)
+			return next()
+		}).
+		WithInterceptors(gormrepo.CoalesceReads()).
+		WithInterceptors(func(inv *gormrepo.Invocation, next func() error) error {
+			// Holds the round trip until all the callers are waiting on it
+			// 保持往返直到所有调用方都在等待它
+			queried.Add(1)
+			for arrived.Load() < callers {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond)
+			return next()
+		})
+
+	var results = make([][]*Account, callers)
+	var wg sync.WaitGroup
+	for idx := 0; idx < callers; idx++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			accounts, err := base.Repo(db).Find(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+				return db.Where(cls.Username.In([]string{"demo-1-username", "demo-2-username"})).Order(cls.ID.Name())
+			})
+			require.NoError(t, err)
+			results[idx] = accounts
+		}(idx)
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), queried.Load())
+
+	for idx := 0; idx < callers; idx++ {
+		require.Len(t, results[idx], 2)
+		require.Equal(t, "demo-1-username", results[idx][0].Username)
+	}
+	// Mutating one result leaves the others unchanged
+	// 修改一个结果不影响其他结果
+	results[0][0].Username = "modified"
+	for idx := 1; idx < callers; idx++ {
+		require.Equal(t, "demo-1-username", results[idx][0].Username)
+		require.NotSame(t, results[0][0], results[idx][0])
+	}
+}
+
+// TestCoalesceReads_Distinct tests that reads with different args or operations run on their own
+// TestCoalesceReads_Distinct 测试参数或操作不同的读取各自运行
+func TestCoalesceReads_Distinct(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	var queried atomic.Int32
+	var gate sync.WaitGroup
+	gate.Add(1)
+	base := gormrepo.NewBaseRepo(&Account{}, (&Account{}).Columns()).
+		WithInterceptors(gormrepo.CoalesceReads()).
+		WithInterceptors(func(inv *gormrepo.Invocation, next func() error) error {
+			queried.Add(1)
+			gate.Wait()
+			return next()
+		})
+
+	var wg sync.WaitGroup
+	var run = func(read func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, read(base.Repo(db)))
+		}()
+	}
+	for _, username := range []string{"demo-1-username", "demo-2-username"} {
+		run(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			account, err := repo.First(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+				return db.Where(cls.Username.Eq(username))
+			})
+			if err == nil {
+				require.Equal(t, username, account.Username)
+			}
+			return err
+		})
+	}
+	run(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+		count, err := repo.Count(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq("demo-1-username"))
+		})
+		require.Equal(t, int64(1), count)
+		return err
+	})
+	for queried.Load() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	gate.Done()
+	wg.Wait()
+	require.Equal(t, int32(3), queried.Load())
+}
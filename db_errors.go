@@ -0,0 +1,353 @@
+package gormrepo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Sentinels of the database error kinds, matched with errors.Is on the result of ClassifyError
+//
+// 数据库错误类别的哨兵错误，通过 errors.Is 匹配 ClassifyError 的结果
+var (
+	ErrUniqueViolation      = errors.New("gormrepo: unique violation")
+	ErrForeignKeyViolation  = errors.New("gormrepo: foreign key violation")
+	ErrNotNullViolation     = errors.New("gormrepo: not null violation")
+	ErrCheckViolation       = errors.New("gormrepo: check violation")
+	ErrLockTimeout          = errors.New("gormrepo: lock timeout")
+	ErrSerializationFailure = errors.New("gormrepo: serialization failure")
+	ErrDeadlock             = errors.New("gormrepo: deadlock")
+	ErrCanceled             = errors.New("gormrepo: canceled")
+)
+
+// DBError is the normalized database error produced by ClassifyError
+// errors.Is matches both the Kind sentinel and the original driver error
+// Constraint, Table and Column are filled when the driver reports them
+//
+// DBError 是 ClassifyError 生成的规范化数据库错误
+// errors.Is 既能匹配 Kind 哨兵错误，也能匹配原始驱动错误
+// 当驱动报告时填充 Constraint、Table 和 Column
+type DBError struct {
+	Kind       error  // One of the Err* sentinels // Err* 哨兵错误之一
+	Constraint string // Offending constraint or index name // 违反的约束或索引名称
+	Table      string // Table of the constraint // 约束所在的表
+	Column     string // Offending column, comma separated for composite keys // 违反约束的列，复合键时以逗号分隔
+	Cause      error  // Original driver error // 原始驱动错误
+}
+
+// Error returns the kind with the reported details and the driver message
+// Error 返回错误类别、报告的详情以及驱动错误信息
+func (e *DBError) Error() string {
+	var details []string
+	if e.Constraint != "" {
+		details = append(details, "constraint="+e.Constraint)
+	}
+	if e.Table != "" {
+		details = append(details, "table="+e.Table)
+	}
+	if e.Column != "" {
+		details = append(details, "column="+e.Column)
+	}
+	if len(details) == 0 {
+		return fmt.Sprintf("%s: %s", e.Kind, e.Cause)
+	}
+	return fmt.Sprintf("%s (%s): %s", e.Kind, strings.Join(details, " "), e.Cause)
+}
+
+// Unwrap exposes the kind sentinel and the original error to errors.Is and errors.As
+// Unwrap 向 errors.Is 和 errors.As 暴露类别哨兵错误和原始错误
+func (e *DBError) Unwrap() []error {
+	return []error{e.Kind, e.Cause}
+}
+
+// ClassifyError normalizes driver errors of SQLite, Postgres and MySQL into *DBError
+// Returns nil for nil, and the error unchanged when it matches no known kind (e.g. gorm.ErrRecordNotFound)
+//
+// ClassifyError 将 SQLite、Postgres 和 MySQL 的驱动错误规范化为 *DBError
+// 传入 nil 返回 nil，不属于任何已知类别时原样返回错误（例如 gorm.ErrRecordNotFound）
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+	if res := classifyError(err); res != nil {
+		res.Cause = err
+		return res
+	}
+	return err
+}
+
+func classifyError(err error) *DBError {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return &DBError{Kind: ErrCanceled}
+	}
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		if res := classifyPostgres(stateErr.SQLState(), stateErr); res != nil {
+			return res
+		}
+	}
+	if number, ok := mysqlNumber(err); ok {
+		if res := classifyMySQL(number, err.Error()); res != nil {
+			return res
+		}
+	}
+	if code, ok := sqliteCode(err); ok {
+		if res := classifySQLite(code, err.Error()); res != nil {
+			return res
+		}
+	}
+	// GORM translated errors (with TranslateError) lose the driver details
+	// GORM 转换后的错误（开启 TranslateError 时）丢失了驱动详情
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return &DBError{Kind: ErrUniqueViolation}
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return &DBError{Kind: ErrForeignKeyViolation}
+	case errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return &DBError{Kind: ErrCheckViolation}
+	}
+	return nil
+}
+
+// classifyPostgres maps SQLSTATE codes, and reads details from pgconn.PgError or pq.Error fields
+// classifyPostgres 映射 SQLSTATE 错误码，并从 pgconn.PgError 或 pq.Error 的字段读取详情
+func classifyPostgres(state string, source interface{}) *DBError {
+	var kind error
+	switch state {
+	case "23505":
+		kind = ErrUniqueViolation
+	case "23503":
+		kind = ErrForeignKeyViolation
+	case "23502":
+		kind = ErrNotNullViolation
+	case "23514":
+		kind = ErrCheckViolation
+	case "55P03":
+		kind = ErrLockTimeout
+	case "40001":
+		kind = ErrSerializationFailure
+	case "40P01":
+		kind = ErrDeadlock
+	case "57014":
+		kind = ErrCanceled
+	default:
+		return nil
+	}
+	return &DBError{
+		Kind:       kind,
+		Constraint: stringField(source, "ConstraintName", "Constraint"),
+		Table:      stringField(source, "TableName", "Table"),
+		Column:     stringField(source, "ColumnName", "Column"),
+	}
+}
+
+var (
+	mysqlKeyRegexp        = regexp.MustCompile(`for key '([^']+)'`)
+	mysqlForeignKeyRegexp = regexp.MustCompile("CONSTRAINT `([^`]+)` FOREIGN KEY \\(`([^`]+)`\\)")
+	mysqlColumnRegexp     = regexp.MustCompile(`(?:Column|Field) '([^']+)'`)
+	mysqlCheckRegexp      = regexp.MustCompile(`Check constraint '([^']+)'`)
+)
+
+// classifyMySQL maps error numbers of go-sql-driver MySQLError, reading the details from its message
+// classifyMySQL 映射 go-sql-driver MySQLError 的错误号，并从其错误信息中读取详情
+func classifyMySQL(number uint64, message string) *DBError {
+	submatch := func(re *regexp.Regexp) string {
+		if matches := re.FindStringSubmatch(message); matches != nil {
+			return matches[1]
+		}
+		return ""
+	}
+	switch number {
+	case 1062, 1586:
+		constraint := submatch(mysqlKeyRegexp)
+		// MySQL 8 prefixes the key with the table name
+		// MySQL 8 会在键名前加上表名
+		if table, name, ok := strings.Cut(constraint, "."); ok {
+			return &DBError{Kind: ErrUniqueViolation, Constraint: name, Table: table}
+		}
+		return &DBError{Kind: ErrUniqueViolation, Constraint: constraint}
+	case 1451, 1452:
+		res := &DBError{Kind: ErrForeignKeyViolation}
+		if matches := mysqlForeignKeyRegexp.FindStringSubmatch(message); matches != nil {
+			res.Constraint, res.Column = matches[1], matches[2]
+		}
+		return res
+	case 1048, 1364:
+		return &DBError{Kind: ErrNotNullViolation, Column: submatch(mysqlColumnRegexp)}
+	case 3819:
+		return &DBError{Kind: ErrCheckViolation, Constraint: submatch(mysqlCheckRegexp)}
+	case 1205, 3572:
+		return &DBError{Kind: ErrLockTimeout}
+	case 1213:
+		return &DBError{Kind: ErrDeadlock}
+	case 1317, 3024:
+		return &DBError{Kind: ErrCanceled}
+	}
+	return nil
+}
+
+// SQLite result codes, the extended codes of constraint failures carry the constraint kind
+// SQLite 结果码，约束失败的扩展结果码携带约束类别
+const (
+	sqliteBusy             = 5
+	sqliteLocked           = 6
+	sqliteInterrupt        = 9
+	sqliteConstraintCheck  = 275
+	sqliteConstraintFK     = 787
+	sqliteConstraintNull   = 1299
+	sqliteConstraintPK     = 1555
+	sqliteConstraintUnique = 2067
+)
+
+// classifySQLite maps the extended result codes of SQLite drivers, reading the details from the message
+// The messages share the wording of sqlite3 itself, e.g. "UNIQUE constraint failed: table.column"
+//
+// classifySQLite 映射 SQLite 驱动的扩展结果码，并从错误信息中读取详情
+// 错误信息与 sqlite3 本身的措辞一致，例如 "UNIQUE constraint failed: table.column"
+func classifySQLite(code int, message string) *DBError {
+	var res *DBError
+	switch code {
+	case sqliteConstraintUnique, sqliteConstraintPK:
+		res = &DBError{Kind: ErrUniqueViolation}
+	case sqliteConstraintFK:
+		return &DBError{Kind: ErrForeignKeyViolation}
+	case sqliteConstraintNull:
+		res = &DBError{Kind: ErrNotNullViolation}
+	case sqliteConstraintCheck:
+		res = &DBError{Kind: ErrCheckViolation}
+	default:
+		switch code & 0xff {
+		case sqliteBusy, sqliteLocked:
+			return &DBError{Kind: ErrLockTimeout}
+		case sqliteInterrupt:
+			return &DBError{Kind: ErrCanceled}
+		}
+		return nil
+	}
+	_, detail, ok := strings.Cut(message, "constraint failed")
+	if !ok {
+		return res
+	}
+	detail = strings.TrimSpace(strings.TrimPrefix(detail, ":"))
+	// Drop the extended result code suffix of pure go drivers, e.g. " (2067)"
+	// 去掉纯 Go 驱动的扩展结果码后缀，例如 " (2067)"
+	if idx := strings.Index(detail, " ("); idx >= 0 {
+		detail = detail[:idx]
+	}
+	if res.Kind == ErrCheckViolation {
+		res.Constraint = detail
+		return res
+	}
+	// Reports "table.column", or "table.a, table.b" on composite keys
+	// 报告 "table.column"，复合键时为 "table.a, table.b"
+	var columns []string
+	for _, part := range strings.Split(detail, ",") {
+		table, column, ok := strings.Cut(strings.TrimSpace(part), ".")
+		if !ok {
+			continue
+		}
+		res.Table = table
+		columns = append(columns, column)
+	}
+	res.Column = strings.Join(columns, ",")
+	return res
+}
+
+// stringField reads the first non-empty string field among names, from a struct or pointer to struct
+// stringField 从结构体或结构体指针中读取 names 中第一个非空的字符串字段
+func stringField(source interface{}, names ...string) string {
+	rv := reflect.Indirect(reflect.ValueOf(source))
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+	for _, name := range names {
+		if field := rv.FieldByName(name); field.IsValid() && field.Kind() == reflect.String && field.String() != "" {
+			return field.String()
+		}
+	}
+	return ""
+}
+
+// mysqlNumber reads the Number of the go-sql-driver MySQLError in the error chain
+// mysqlNumber 读取错误链中 go-sql-driver MySQLError 的 Number
+func mysqlNumber(err error) (uint64, bool) {
+	var res uint64
+	found := walkErrors(err, func(err error) bool {
+		rv := reflect.Indirect(reflect.ValueOf(err))
+		if rv.Kind() != reflect.Struct || rv.Type().Name() != "MySQLError" {
+			return false
+		}
+		if field := rv.FieldByName("Number"); field.IsValid() && field.Kind() == reflect.Uint16 {
+			res = field.Uint()
+			return true
+		}
+		return false
+	})
+	return res, found
+}
+
+// sqliteCode reads the extended result code of the SQLite driver error in the error chain
+// Supports the Code() method of modernc.org/sqlite and the ExtendedCode field of mattn/go-sqlite3
+//
+// sqliteCode 读取错误链中 SQLite 驱动错误的扩展结果码
+// 支持 modernc.org/sqlite 的 Code() 方法和 mattn/go-sqlite3 的 ExtendedCode 字段
+func sqliteCode(err error) (int, bool) {
+	var res int
+	found := walkErrors(err, func(err error) bool {
+		rt := reflect.TypeOf(err)
+		if rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		if !strings.Contains(rt.PkgPath(), "sqlite") {
+			return false
+		}
+		if coder, ok := err.(interface{ Code() int }); ok {
+			res = coder.Code()
+			return true
+		}
+		rv := reflect.Indirect(reflect.ValueOf(err))
+		if rv.Kind() != reflect.Struct {
+			return false
+		}
+		extended, code := rv.FieldByName("ExtendedCode"), rv.FieldByName("Code")
+		if !extended.IsValid() || !extended.CanInt() || !code.IsValid() || !code.CanInt() {
+			return false
+		}
+		if res = int(extended.Int()); res == 0 {
+			res = int(code.Int())
+		}
+		return true
+	})
+	return res, found
+}
+
+// walkErrors visits the error chain depth first, following both Unwrap() error and Unwrap() []error, until visit returns true
+// walkErrors 深度优先遍历错误链，同时跟随 Unwrap() error 和 Unwrap() []error，直到 visit 返回 true
+func walkErrors(err error, visit func(err error) bool) bool {
+	if err == nil {
+		return false
+	}
+	if visit(err) {
+		return true
+	}
+	switch value := err.(type) {
+	case interface{ Unwrap() error }:
+		return walkErrors(value.Unwrap(), visit)
+	case interface{ Unwrap() []error }:
+		for _, item := range value.Unwrap() {
+			if walkErrors(item, visit) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package gormrepo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"gorm.io/gorm"
+)
+
+// classifyOwner and classifyItem declare foreign key, not null and check constraints
+// classifyOwner 和 classifyItem 声明外键、非空和检查约束
+type classifyOwner struct {
+	ID   uint
+	Name string `gorm:"uniqueIndex"`
+}
+
+type classifyItem struct {
+	ID      uint
+	OwnerID uint
+	Owner   *classifyOwner
+	Code    *string `gorm:"not null"`
+	Qty     int     `gorm:"check:chk_qty,qty > 0"`
+}
+
+// TestClassifyError_SQLite tests classification of real SQLite constraint errors
+// TestClassifyError_SQLite 测试真实 SQLite 约束错误的分类
+func TestClassifyError_SQLite(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&classifyOwner{}, &classifyItem{}))
+	must.Done(db.Create(&classifyOwner{ID: 1, Name: "owner"}).Error)
+
+	code := "code"
+
+	t.Run("unique", func(t *testing.T) {
+		err := gormrepo.ClassifyError(db.Create(&classifyOwner{Name: "owner"}).Error)
+		require.ErrorIs(t, err, gormrepo.ErrUniqueViolation)
+		var dbErr *gormrepo.DBError
+		require.ErrorAs(t, err, &dbErr)
+		require.Equal(t, "classify_owners", dbErr.Table)
+		require.Equal(t, "name", dbErr.Column)
+	})
+
+	t.Run("not-null", func(t *testing.T) {
+		err := gormrepo.ClassifyError(db.Create(&classifyItem{OwnerID: 1, Qty: 1}).Error)
+		require.ErrorIs(t, err, gormrepo.ErrNotNullViolation)
+		var dbErr *gormrepo.DBError
+		require.ErrorAs(t, err, &dbErr)
+		require.Equal(t, "code", dbErr.Column)
+	})
+
+	t.Run("check", func(t *testing.T) {
+		err := gormrepo.ClassifyError(db.Create(&classifyItem{OwnerID: 1, Code: &code, Qty: 0}).Error)
+		require.ErrorIs(t, err, gormrepo.ErrCheckViolation)
+		var dbErr *gormrepo.DBError
+		require.ErrorAs(t, err, &dbErr)
+		require.Equal(t, "chk_qty", dbErr.Constraint)
+	})
+
+	t.Run("foreign-key", func(t *testing.T) {
+		// SQLite enforces foreign keys per connection
+		// SQLite 按连接启用外键约束
+		must.Done(db.Connection(func(db *gorm.DB) error {
+			must.Done(db.Exec("PRAGMA foreign_keys = ON").Error)
+			err := gormrepo.ClassifyError(db.Create(&classifyItem{OwnerID: 100, Code: &code, Qty: 1}).Error)
+			require.ErrorIs(t, err, gormrepo.ErrForeignKeyViolation)
+			return nil
+		}))
+	})
+
+	t.Run("not-classified", func(t *testing.T) {
+		_, err := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{})).First(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db
+		})
+		require.Error(t, err)
+		require.Equal(t, err, gormrepo.ClassifyError(err))
+		require.NoError(t, gormrepo.ClassifyError(nil))
+	})
+}
+
+// pgError mimics pgconn.PgError carrying SQLState and constraint details
+// pgError 模拟携带 SQLState 和约束详情的 pgconn.PgError
+type pgError struct {
+	Code           string
+	ConstraintName string
+	TableName      string
+	ColumnName     string
+}
+
+func (e *pgError) Error() string    { return "ERROR: violation (SQLSTATE " + e.Code + ")" }
+func (e *pgError) SQLState() string { return e.Code }
+
+// MySQLError mimics go-sql-driver MySQLError carrying the error number
+// MySQLError 模拟携带错误号的 go-sql-driver MySQLError
+type MySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *MySQLError) Error() string { return e.Message }
+
+// TestClassifyError tests classification of Postgres, MySQL and context errors
+// TestClassifyError 测试 Postgres、MySQL 和上下文错误的分类
+func TestClassifyError(t *testing.T) {
+	t.Run("postgres", func(t *testing.T) {
+		cause := &pgError{Code: "23503", ConstraintName: "fk_items_owner", TableName: "items", ColumnName: "owner_id"}
+		err := gormrepo.ClassifyError(errors.WithMessage(cause, "create item"))
+		require.ErrorIs(t, err, gormrepo.ErrForeignKeyViolation)
+		require.ErrorIs(t, err, cause)
+		var dbErr *gormrepo.DBError
+		require.ErrorAs(t, err, &dbErr)
+		require.Equal(t, "fk_items_owner", dbErr.Constraint)
+		require.Equal(t, "items", dbErr.Table)
+		require.Equal(t, "owner_id", dbErr.Column)
+
+		require.ErrorIs(t, gormrepo.ClassifyError(&pgError{Code: "23505"}), gormrepo.ErrUniqueViolation)
+		require.ErrorIs(t, gormrepo.ClassifyError(&pgError{Code: "55P03"}), gormrepo.ErrLockTimeout)
+		require.ErrorIs(t, gormrepo.ClassifyError(&pgError{Code: "40001"}), gormrepo.ErrSerializationFailure)
+
+		// Deadlocks are told apart from serialization failures
+		// 死锁与序列化失败可以区分
+		err = gormrepo.ClassifyError(&pgError{Code: "40P01"})
+		require.ErrorIs(t, err, gormrepo.ErrDeadlock)
+		require.NotErrorIs(t, err, gormrepo.ErrSerializationFailure)
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		err := gormrepo.ClassifyError(&MySQLError{Number: 1062, Message: "Error 1062 (23000): Duplicate entry 'abc' for key 'accounts.idx_username'"})
+		require.ErrorIs(t, err, gormrepo.ErrUniqueViolation)
+		var dbErr *gormrepo.DBError
+		require.ErrorAs(t, err, &dbErr)
+		require.Equal(t, "idx_username", dbErr.Constraint)
+		require.Equal(t, "accounts", dbErr.Table)
+
+		err = gormrepo.ClassifyError(errors.WithMessage(&MySQLError{Number: 1452, Message: "Error 1452 (23000): Cannot add or update a child row: a foreign key constraint fails (`db`.`items`, CONSTRAINT `fk_items_owner` FOREIGN KEY (`owner_id`) REFERENCES `owners` (`id`))"}, "create item"))
+		require.ErrorAs(t, err, &dbErr)
+		require.ErrorIs(t, err, gormrepo.ErrForeignKeyViolation)
+		require.Equal(t, "fk_items_owner", dbErr.Constraint)
+		require.Equal(t, "owner_id", dbErr.Column)
+
+		err = gormrepo.ClassifyError(&MySQLError{Number: 1048, Message: "Error 1048 (23000): Column 'code' cannot be null"})
+		require.ErrorAs(t, err, &dbErr)
+		require.ErrorIs(t, err, gormrepo.ErrNotNullViolation)
+		require.Equal(t, "code", dbErr.Column)
+
+		require.ErrorIs(t, gormrepo.ClassifyError(&MySQLError{Number: 1205, Message: "Error 1205 (HY000): Lock wait timeout exceeded"}), gormrepo.ErrLockTimeout)
+
+		err = gormrepo.ClassifyError(&MySQLError{Number: 1213, Message: "Error 1213 (40001): Deadlock found when trying to get lock"})
+		require.ErrorIs(t, err, gormrepo.ErrDeadlock)
+		require.NotErrorIs(t, err, gormrepo.ErrSerializationFailure)
+	})
+
+	t.Run("sqlite-codes", func(t *testing.T) {
+		require.ErrorIs(t, gormrepo.ClassifyError(sqlite3.Error{Code: sqlite3.ErrBusy}), gormrepo.ErrLockTimeout)
+		require.ErrorIs(t, gormrepo.ClassifyError(sqlite3.Error{Code: sqlite3.ErrLocked, ExtendedCode: sqlite3.ErrLockedSharedCache}), gormrepo.ErrLockTimeout)
+		require.ErrorIs(t, gormrepo.ClassifyError(sqlite3.Error{Code: sqlite3.ErrInterrupt}), gormrepo.ErrCanceled)
+	})
+
+	t.Run("messages-only", func(t *testing.T) {
+		// Errors are classified by driver types and codes, not by look-alike messages
+		// 按驱动的错误类型和错误码分类，而不是按相似的错误信息
+		for _, message := range []string{
+			"Error 1062 (23000): Duplicate entry 'abc' for key 'idx_username'",
+			"UNIQUE constraint failed: accounts.username",
+			"database is locked",
+			"job interrupted by the operator",
+		} {
+			err := errors.New(message)
+			require.Equal(t, err, gormrepo.ClassifyError(err))
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		err := gormrepo.ClassifyError(errors.Wrap(context.DeadlineExceeded, "query"))
+		require.ErrorIs(t, err, gormrepo.ErrCanceled)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("translated", func(t *testing.T) {
+		require.ErrorIs(t, gormrepo.ClassifyError(gorm.ErrDuplicatedKey), gormrepo.ErrUniqueViolation)
+		require.ErrorIs(t, gormrepo.ClassifyError(gorm.ErrForeignKeyViolated), gormrepo.ErrForeignKeyViolation)
+	})
+}
@@ -5,13 +5,21 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrMultipleRecords is returned by FindOne when more than one record matches
+// ErrMultipleRecords 在 FindOne 匹配到多于一条记录时返回
+var ErrMultipleRecords = errors.New("gormrepo: multiple records found")
+
 // ErrorOrNotExist wraps an error with additional flag indicating record not found
 // Provides convenient way to distinguish between actual errors and missing records
-// Used by FirstE method to return structured error information
+// Used by the E-variants (FirstE, TakeE, LastE, FindOneE, UpdatesOE, DeleteWE) to return structured error information
+// Implements error and Unwrap, so errors.Is(erb, gorm.ErrRecordNotFound) works when NotExist
+// Note: assign it to an error variable only when not nil, a nil *ErrorOrNotExist in an error interface is not nil
 //
 // ErrorOrNotExist 封装错误并附加标志指示记录是否未找到
 // 提供便捷方式区分实际错误和记录缺失
-// 被 FirstE 方法用于返回结构化的错误信息
+// 被 E 系列方法（FirstE、TakeE、LastE、FindOneE、UpdatesOE、DeleteWE）用于返回结构化的错误信息
+// 实现了 error 和 Unwrap，因此 NotExist 时 errors.Is(erb, gorm.ErrRecordNotFound) 成立
+// 注意：只在非 nil 时赋值给 error 变量，error 接口中的 nil *ErrorOrNotExist 并不等于 nil
 type ErrorOrNotExist struct {
 	Cause    error // Original error // 原始错误
 	NotExist bool  // True when record not found // 记录未找到时为 true
@@ -28,3 +36,24 @@ func NewErrorOrNotExist(cause error) *ErrorOrNotExist {
 		NotExist: errors.Is(cause, gorm.ErrRecordNotFound),
 	}
 }
+
+// notMatched creates ErrorOrNotExist reporting that no row matched an update or delete
+// notMatched 创建 ErrorOrNotExist，报告更新或删除没有匹配到任何行
+func notMatched(operation string) *ErrorOrNotExist {
+	return NewErrorOrNotExist(errors.Wrapf(gorm.ErrRecordNotFound, "%s matched nothing", operation))
+}
+
+// Error returns the message of the cause
+// Error 返回原因的错误信息
+func (erb *ErrorOrNotExist) Error() string {
+	if erb.Cause == nil {
+		return "gormrepo: unknown error"
+	}
+	return erb.Cause.Error()
+}
+
+// Unwrap returns the cause, enabling errors.Is and errors.As
+// Unwrap 返回原因，使 errors.Is 和 errors.As 可用
+func (erb *ErrorOrNotExist) Unwrap() error {
+	return erb.Cause
+}
@@ -23,3 +23,16 @@ func TestNewErrorOrNotExist(t *testing.T) {
 		require.True(t, erb.NotExist)
 	})
 }
+
+// TestErrorOrNotExist_Unwrap tests ErrorOrNotExist used as a wrapped error
+// TestErrorOrNotExist_Unwrap 测试将 ErrorOrNotExist 作为被包装的错误使用
+func TestErrorOrNotExist_Unwrap(t *testing.T) {
+	var err error = NewErrorOrNotExist(errors.WithMessage(gorm.ErrRecordNotFound, "first"))
+	err = errors.WithMessage(err, "load account")
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	require.Equal(t, "load account: first: record not found", err.Error())
+
+	var erb *ErrorOrNotExist
+	require.ErrorAs(t, err, &erb)
+	require.True(t, erb.NotExist)
+}
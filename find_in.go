@@ -0,0 +1,156 @@
+package gormrepo
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormcnm"
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultChunkSize is the IN-list chunk size when ChunkOptions.ChunkSize is not set
+// It stays below the bound-variable limit of SQLite (999 in old versions), MySQL and Postgres (65535)
+//
+// DefaultChunkSize 是未设置 ChunkOptions.ChunkSize 时的 IN 列表分块大小
+// 低于 SQLite（旧版本为 999）、MySQL 和 Postgres（65535）的绑定变量上限
+const DefaultChunkSize = 500
+
+// ChunkOptions configures how FindByIDs and FindByColumnIn split and run the IN-list queries
+// A nil ChunkOptions runs sequential chunks of DefaultChunkSize without reordering
+//
+// ChunkOptions 配置 FindByIDs 和 FindByColumnIn 如何拆分和执行 IN 列表查询
+// ChunkOptions 为 nil 时按 DefaultChunkSize 顺序执行分块且不重排结果
+type ChunkOptions struct {
+	ChunkSize   int  // Values per IN list, zero means DefaultChunkSize // 每个 IN 列表的值数量，为零时使用 DefaultChunkSize
+	Parallelism int  // Max concurrent chunk queries, below 2 or in a transaction means sequential // 最大并发分块查询数，小于 2 或在事务中时顺序执行
+	KeepOrder   bool // Sort records by the order of input values // 按输入值的顺序排列记录
+}
+
+// ChunkResult is the merged result of chunked IN-list queries
+// Missing lists the input values without matching records, in input order
+//
+// ChunkResult 是分块 IN 列表查询合并后的结果
+// Missing 按输入顺序列出没有匹配记录的输入值
+type ChunkResult[MOD any, K comparable] struct {
+	Records []*MOD // Merged records // 合并后的记录
+	Missing []K    // Values without matching records // 没有匹配记录的值
+}
+
+// FindByIDs retrieves records by primary key values, splitting the IN list into chunks
+// Duplicate ids are queried once, parallel chunks run in independent sessions
+// In transactions all chunks share one connection, so Parallelism is ignored and the chunks run sequentially
+//
+// FindByIDs 按主键值检索记录，将 IN 列表拆分为多个分块
+// 重复的 id 只查询一次，并行分块在独立会话中执行
+// 在事务中所有分块共享同一连接，因此忽略 Parallelism 并顺序执行分块
+func FindByIDs[MOD any, CLS any, K comparable](repo *GormRepo[MOD, CLS], ids []K, options *ChunkOptions) (*ChunkResult[MOD, K], error) {
+	sch, err := ParseSchema[MOD](repo.db)
+	if err != nil {
+		return nil, err
+	}
+	field := sch.PrioritizedPrimaryField
+	if field == nil {
+		return nil, errors.Errorf("table %s has no primary key", sch.Table)
+	}
+	return findByFieldIn[MOD, CLS, K](repo, OpFindByIDs, field, field.DBName, ids, options)
+}
+
+// FindByColumnIn retrieves records whose column value is in values, splitting the IN list into chunks
+// With KeepOrder, records sharing one value are grouped at the position of that value
+//
+// FindByColumnIn 检索列值在 values 中的记录，将 IN 列表拆分为多个分块
+// 设置 KeepOrder 时，共享同一值的记录被归组在该值的位置
+func FindByColumnIn[MOD any, CLS any, K comparable](repo *GormRepo[MOD, CLS], column func(cls CLS) gormcnm.ColumnName[K], values []K, options *ChunkOptions) (*ChunkResult[MOD, K], error) {
+	sch, err := ParseSchema[MOD](repo.db)
+	if err != nil {
+		return nil, err
+	}
+	columnName := column(repo.cls).Name()
+	field, err := lookupField(sch, columnName)
+	if err != nil {
+		return nil, err
+	}
+	return findByFieldIn[MOD, CLS, K](repo, OpFindByColumnIn, field, columnName, values, options)
+}
+
+func findByFieldIn[MOD any, CLS any, K comparable](repo *GormRepo[MOD, CLS], op Operation, field *schema.Field, columnName string, values []K, options *ChunkOptions) (*ChunkResult[MOD, K], error) {
+	if options == nil {
+		options = &ChunkOptions{}
+	}
+	chunkSize := options.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	var uniqueValues = make([]K, 0, len(values))
+	var seen = make(map[K]bool, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			uniqueValues = append(uniqueValues, value)
+		}
+	}
+
+	// All the chunks pass through the interceptors as one operation, Dest receiving the merged records
+	// 所有分块作为一次操作经过拦截器，Dest 接收合并后的记录
+	var records []*MOD
+	if _, err := repo.intercept(&Invocation{Operation: op, Dest: &records}, func(db *gorm.DB) *gorm.DB {
+		return findChunks(db, columnName, slices.Collect(slices.Chunk(uniqueValues, chunkSize)), options.Parallelism, &records)
+	}); err != nil {
+		return nil, err
+	}
+
+	var groups = make(map[K][]*MOD, len(uniqueValues))
+	for _, one := range records {
+		key, err := fieldValueOf[K](repo.db, field, one)
+		if err != nil {
+			return nil, err
+		}
+		groups[key] = append(groups[key], one)
+	}
+	var result = &ChunkResult[MOD, K]{Records: records}
+	if options.KeepOrder {
+		result.Records = make([]*MOD, 0, len(records))
+	}
+	for _, value := range uniqueValues {
+		if len(groups[value]) == 0 {
+			result.Missing = append(result.Missing, value)
+		} else if options.KeepOrder {
+			result.Records = append(result.Records, groups[value]...)
+		}
+	}
+	return result, nil
+}
+
+// findChunks runs one IN-list query per chunk and stores the records in chunk order, returning db with the rows and error
+// findChunks 为每个分块运行一次 IN 列表查询，并按分块顺序保存记录，返回携带行数和错误的 db
+func findChunks[MOD any, K comparable](db *gorm.DB, columnName string, chunks [][]K, parallelism int, records *[]*MOD) *gorm.DB {
+	// Session makes each chunk query start from a cloned statement, so chunks never share conditions
+	// Session 使每个分块查询从克隆的 statement 开始，分块之间不会共享条件
+	db = db.Session(&gorm.Session{})
+	parts := make([][]*MOD, len(chunks))
+	parallelism = max(1, parallelism)
+	if InTransaction(db) {
+		parallelism = 1
+	}
+	var eg errgroup.Group
+	eg.SetLimit(parallelism)
+	for idx, chunk := range chunks {
+		eg.Go(func() error {
+			var results []*MOD
+			if err := db.Where(columnName+" IN ?", chunk).Find(&results).Error; err != nil {
+				return err
+			}
+			parts[idx] = results
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return withError(db, err)
+	}
+	*records = slices.Concat(parts...)
+	db.RowsAffected = int64(len(*records))
+	return db
+}
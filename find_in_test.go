@@ -0,0 +1,118 @@
+package gormrepo_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"gorm.io/gorm"
+)
+
+// TestFindByIDs tests chunked primary key lookups with input ordering and missing ids
+// TestFindByIDs 测试按输入顺序的分块主键查询以及缺失的 id
+func TestFindByIDs(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+	for idx := 1; idx <= 7; idx++ {
+		must.Done(db.Create(newAccount(fmt.Sprintf("chunk-%d-username", idx))).Error)
+	}
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	t.Run("keep-order", func(t *testing.T) {
+		result, err := gormrepo.FindByIDs(repo, []uint{7, 100, 2, 5, 2, 1, 200, 6}, &gormrepo.ChunkOptions{
+			ChunkSize: 2,
+			KeepOrder: true,
+		})
+		require.NoError(t, err)
+		var ids []uint
+		for _, one := range result.Records {
+			ids = append(ids, one.ID)
+		}
+		require.Equal(t, []uint{7, 2, 5, 1, 6}, ids)
+		require.Equal(t, []uint{100, 200}, result.Missing)
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		result, err := gormrepo.FindByIDs(repo, []uint{1, 2, 3, 4, 5, 6, 7, 8}, &gormrepo.ChunkOptions{
+			ChunkSize:   3,
+			Parallelism: 3,
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Records, 7)
+		require.Equal(t, []uint{8}, result.Missing)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		result, err := gormrepo.FindByIDs(repo, []uint{3}, nil)
+		require.NoError(t, err)
+		require.Len(t, result.Records, 1)
+		require.Empty(t, result.Missing)
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		// Chunks in a transaction run one by one on the transaction connection
+		// 事务中的分块在事务连接上逐个执行
+		var mutex sync.Mutex
+		var running, maxRunning int
+		must.Done(db.Callback().Query().Before("gorm:query").Register("test:enter_query", func(db *gorm.DB) {
+			mutex.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mutex.Unlock()
+			time.Sleep(5 * time.Millisecond) // Gives parallel chunks the time to overlap // 给并行分块留出重叠的时间
+		}))
+		must.Done(db.Callback().Query().After("gorm:query").Register("test:leave_query", func(db *gorm.DB) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			running--
+		}))
+
+		require.NoError(t, gormrepo.Transaction(context.Background(), db, func(uow *gormrepo.UnitOfWork) error {
+			txRepo := gormrepo.NewGormRepo(gormrepo.Use(uow.DB(), &Account{}))
+			if err := txRepo.Create(newAccount("chunk-8-username")); err != nil {
+				return err
+			}
+			result, err := gormrepo.FindByIDs(txRepo, []uint{1, 2, 3, 4, 5, 6, 7, 8}, &gormrepo.ChunkOptions{
+				ChunkSize:   2,
+				Parallelism: 4,
+			})
+			if err != nil {
+				return err
+			}
+			require.Len(t, result.Records, 8)
+			require.Empty(t, result.Missing)
+			return nil
+		}))
+		require.Equal(t, 1, maxRunning)
+	})
+}
+
+// TestFindByColumnIn tests chunked lookups on a non primary key column
+// TestFindByColumnIn 测试非主键列上的分块查询
+func TestFindByColumnIn(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupAggregateData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	result, err := gormrepo.FindByColumnIn(repo, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+		return cls.Nickname
+	}, []string{"group-b", "group-x", "group-a"}, &gormrepo.ChunkOptions{
+		ChunkSize: 1,
+		KeepOrder: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Records, 3)
+	require.Equal(t, "group-b", result.Records[0].Nickname)
+	require.Equal(t, "group-a", result.Records[1].Nickname)
+	require.Equal(t, "group-a", result.Records[2].Nickname)
+	require.Equal(t, []string{"group-x"}, result.Missing)
+}
@@ -0,0 +1,93 @@
+package gormrepo
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormcnm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrDuplicateMapKey is returned by FindMap when two records share the same key value
+//
+// ErrDuplicateMapKey 当两条记录的键值相同时由 FindMap 返回
+var ErrDuplicateMapKey = errors.New("gormrepo: duplicate map key")
+
+// FindMap retrieves records matching the where condition as a map keyed by the column value
+// Key values are read from the loaded records using the GORM schema field of the column
+// Returns ErrDuplicateMapKey when two records share the same key value
+//
+// FindMap 检索符合 where 条件的记录，返回以列值为键的映射
+// 键值通过列对应的 GORM schema 字段从加载的记录中读取
+// 当两条记录的键值相同时返回 ErrDuplicateMapKey
+func FindMap[MOD any, CLS any, K comparable](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, keyColumn func(cls CLS) gormcnm.ColumnName[K]) (map[K]*MOD, error) {
+	var columnName = keyColumn(repo.cls).Name()
+	results, keys, err := findWithKeys[MOD, CLS, K](repo, OpFindMap, where, columnName)
+	if err != nil {
+		return nil, err
+	}
+	var resMap = make(map[K]*MOD, len(results))
+	for idx, one := range results {
+		if _, exist := resMap[keys[idx]]; exist {
+			return nil, errors.Wrapf(ErrDuplicateMapKey, "column %s value %v", columnName, keys[idx])
+		}
+		resMap[keys[idx]] = one
+	}
+	return resMap, nil
+}
+
+// FindGroup retrieves records matching the where condition grouped by the column value
+// Records in each group keep the query ordering
+//
+// FindGroup 检索符合 where 条件的记录，按列值分组返回
+// 每个分组中的记录保持查询顺序
+func FindGroup[MOD any, CLS any, K comparable](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, keyColumn func(cls CLS) gormcnm.ColumnName[K]) (map[K][]*MOD, error) {
+	results, keys, err := findWithKeys[MOD, CLS, K](repo, OpFindGroup, where, keyColumn(repo.cls).Name())
+	if err != nil {
+		return nil, err
+	}
+	var resMap = make(map[K][]*MOD)
+	for idx, one := range results {
+		resMap[keys[idx]] = append(resMap[keys[idx]], one)
+	}
+	return resMap, nil
+}
+
+func findWithKeys[MOD any, CLS any, K comparable](repo *GormRepo[MOD, CLS], op Operation, where func(db *gorm.DB, cls CLS) *gorm.DB, columnName string) ([]*MOD, []K, error) {
+	sch, err := ParseSchema[MOD](repo.db)
+	if err != nil {
+		return nil, nil, err
+	}
+	field, err := lookupField(sch, columnName)
+	if err != nil {
+		return nil, nil, err
+	}
+	var results []*MOD
+	if _, err := repo.intercept(&Invocation{Operation: op, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		return where(db, repo.cls).Find(&results)
+	}); err != nil {
+		return nil, nil, err
+	}
+	var keys = make([]K, 0, len(results))
+	for _, one := range results {
+		key, err := fieldValueOf[K](repo.db, field, one)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+	}
+	return results, keys, nil
+}
+
+// fieldValueOf reads the field value of the record and converts it to type K
+// fieldValueOf 读取记录的字段值并转换为类型 K
+func fieldValueOf[K any](db *gorm.DB, field *schema.Field, one interface{}) (K, error) {
+	value, _ := field.ValueOf(db.Statement.Context, reflect.Indirect(reflect.ValueOf(one)))
+	key, ok := value.(K)
+	if !ok {
+		var zero K
+		return zero, errors.Errorf("column %s value type %T is not %T", field.DBName, value, zero)
+	}
+	return key, nil
+}
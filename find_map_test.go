@@ -0,0 +1,72 @@
+package gormrepo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"gorm.io/gorm"
+)
+
+// TestFindMap tests records keyed by a unique column and the duplicate key error
+// TestFindMap 测试以唯一列为键的记录映射以及重复键错误
+func TestFindMap(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupAggregateData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	t.Run("case-1", func(t *testing.T) {
+		accountMap, err := gormrepo.FindMap(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db
+		}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+			return cls.Username
+		})
+		require.NoError(t, err)
+		require.Len(t, accountMap, 3)
+		require.Equal(t, "group-b", accountMap["aggregate-3"].Nickname)
+	})
+
+	t.Run("case-2", func(t *testing.T) {
+		accountMap, err := gormrepo.FindMap(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db
+		}, func(cls *AccountColumns) gormcnm.ColumnName[uint] {
+			return cls.ID
+		})
+		require.NoError(t, err)
+		require.Len(t, accountMap, 3)
+		require.Equal(t, "aggregate-1", accountMap[1].Username)
+	})
+
+	t.Run("case-3", func(t *testing.T) {
+		_, err := gormrepo.FindMap(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db
+		}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+			return cls.Nickname
+		})
+		require.ErrorIs(t, err, gormrepo.ErrDuplicateMapKey)
+	})
+}
+
+// TestFindGroup tests records grouped by a column
+// TestFindGroup 测试按列分组的记录
+func TestFindGroup(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupAggregateData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	groups, err := gormrepo.FindGroup(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Order(cls.ID.Ob("asc").Ox())
+	}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+		return cls.Nickname
+	})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	require.Len(t, groups["group-a"], 2)
+	require.Equal(t, "aggregate-1", groups["group-a"][0].Username)
+	require.Equal(t, "aggregate-2", groups["group-a"][1].Username)
+	require.Len(t, groups["group-b"], 1)
+}
@@ -1,6 +1,7 @@
 package gormrepo
 
 import (
+	"github.com/pkg/errors"
 	"github.com/yyle88/gormcnm"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -14,8 +15,9 @@ import (
 // 提供返回 (T, error) 签名的 CRUD 操作
 // 所有方法接受使用列定义的类型安全 where 函数
 type GormRepo[MOD any, CLS any] struct {
-	db  *gorm.DB // Database connection // 数据库连接
-	cls CLS      // Column definitions // 列定义
+	db           *gorm.DB      // Database connection // 数据库连接
+	cls          CLS           // Column definitions // 列定义
+	interceptors []Interceptor // Interceptors wrapping the operations // 包装操作的拦截器
 }
 
 // NewGormRepo creates a new GormRepo instance with database connection and column definitions
@@ -37,7 +39,9 @@ func NewGormRepo[MOD any, CLS any](db *gorm.DB, _ *MOD, cls CLS) *GormRepo[MOD,
 // 返回找到的记录，如果未找到或查询失败则返回错误
 func (repo *GormRepo[MOD, CLS]) First(where func(db *gorm.DB, cls CLS) *gorm.DB) (*MOD, error) {
 	var result = new(MOD)
-	if err := repo.Gorm().First(where, result).Error; err != nil {
+	if _, err := repo.intercept(&Invocation{Operation: OpFirst, Where: where, Dest: result}, func(db *gorm.DB) *gorm.DB {
+		return repo.wrapOf(db).First(where, result)
+	}); err != nil {
 		return nil, err
 	}
 	return result, nil
@@ -49,8 +53,99 @@ func (repo *GormRepo[MOD, CLS]) First(where func(db *gorm.DB, cls CLS) *gorm.DB)
 // FirstE 查找第一条记录，带有结构化错误处理
 // 返回 ErrorOrNotExist 以区分记录未找到和其他错误
 func (repo *GormRepo[MOD, CLS]) FirstE(where func(db *gorm.DB, cls CLS) *gorm.DB) (*MOD, *ErrorOrNotExist) {
+	result, err := repo.First(where)
+	if err != nil {
+		return nil, NewErrorOrNotExist(err)
+	}
+	return result, nil
+}
+
+// Take finds one record matching the where condition, without ordering
+// Returns the found record or error if not found or query fails
+//
+// Take 查找符合 where 条件的一条记录，不排序
+// 返回找到的记录，如果未找到或查询失败则返回错误
+func (repo *GormRepo[MOD, CLS]) Take(where func(db *gorm.DB, cls CLS) *gorm.DB) (*MOD, error) {
+	var result = new(MOD)
+	if _, err := repo.intercept(&Invocation{Operation: OpTake, Where: where, Dest: result}, func(db *gorm.DB) *gorm.DB {
+		return repo.wrapOf(db).Take(where, result)
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TakeE finds one record with structured error handling
+// Returns ErrorOrNotExist to distinguish between not found and other errors
+//
+// TakeE 查找一条记录，带有结构化错误处理
+// 返回 ErrorOrNotExist 以区分记录未找到和其他错误
+func (repo *GormRepo[MOD, CLS]) TakeE(where func(db *gorm.DB, cls CLS) *gorm.DB) (*MOD, *ErrorOrNotExist) {
+	result, err := repo.Take(where)
+	if err != nil {
+		return nil, NewErrorOrNotExist(err)
+	}
+	return result, nil
+}
+
+// Last finds the last record matching the where condition, ordered by primary key
+// Returns the found record or error if not found or query fails
+//
+// Last 查找符合 where 条件的最后一条记录，按主键排序
+// 返回找到的记录，如果未找到或查询失败则返回错误
+func (repo *GormRepo[MOD, CLS]) Last(where func(db *gorm.DB, cls CLS) *gorm.DB) (*MOD, error) {
 	var result = new(MOD)
-	if err := repo.Gorm().First(where, result).Error; err != nil {
+	if _, err := repo.intercept(&Invocation{Operation: OpLast, Where: where, Dest: result}, func(db *gorm.DB) *gorm.DB {
+		return repo.wrapOf(db).Last(where, result)
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LastE finds the last record with structured error handling
+// Returns ErrorOrNotExist to distinguish between not found and other errors
+//
+// LastE 查找最后一条记录，带有结构化错误处理
+// 返回 ErrorOrNotExist 以区分记录未找到和其他错误
+func (repo *GormRepo[MOD, CLS]) LastE(where func(db *gorm.DB, cls CLS) *gorm.DB) (*MOD, *ErrorOrNotExist) {
+	result, err := repo.Last(where)
+	if err != nil {
+		return nil, NewErrorOrNotExist(err)
+	}
+	return result, nil
+}
+
+// FindOne finds exactly one record matching the where condition
+// Returns gorm.ErrRecordNotFound when none matches, ErrMultipleRecords when more than one matches
+//
+// FindOne 查找恰好一条符合 where 条件的记录
+// 没有匹配时返回 gorm.ErrRecordNotFound，匹配多于一条时返回 ErrMultipleRecords
+func (repo *GormRepo[MOD, CLS]) FindOne(where func(db *gorm.DB, cls CLS) *gorm.DB) (*MOD, error) {
+	var results []*MOD
+	if _, err := repo.intercept(&Invocation{Operation: OpFindOne, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		return where(db, repo.cls).Limit(2).Find(&results)
+	}); err != nil {
+		return nil, err
+	}
+	switch len(results) {
+	case 0:
+		return nil, errors.WithStack(gorm.ErrRecordNotFound)
+	case 1:
+		return results[0], nil
+	default:
+		return nil, errors.WithStack(ErrMultipleRecords)
+	}
+}
+
+// FindOneE finds exactly one record with structured error handling
+// NotExist is true when none matches, Cause is ErrMultipleRecords when more than one matches
+//
+// FindOneE 查找恰好一条记录，带有结构化错误处理
+// 没有匹配时 NotExist 为 true，匹配多于一条时 Cause 为 ErrMultipleRecords
+func (repo *GormRepo[MOD, CLS]) FindOneE(where func(db *gorm.DB, cls CLS) *gorm.DB) (*MOD, *ErrorOrNotExist) {
+	result, err := repo.FindOne(where)
+	if err != nil {
 		return nil, NewErrorOrNotExist(err)
 	}
 	return result, nil
@@ -72,7 +167,9 @@ func (repo *GormRepo[MOD, CLS]) Where(where func(db *gorm.DB, cls CLS) *gorm.DB)
 // 如果至少存在一条记录则返回 true，否则返回 false
 func (repo *GormRepo[MOD, CLS]) Exist(where func(db *gorm.DB, cls CLS) *gorm.DB) (bool, error) {
 	var exists bool
-	if err := where(repo.db, repo.cls).Model((*MOD)(nil)).Select("1").Limit(1).Find(&exists).Error; err != nil {
+	if _, err := repo.intercept(&Invocation{Operation: OpExist, Where: where, Dest: &exists}, func(db *gorm.DB) *gorm.DB {
+		return where(db, repo.cls).Model((*MOD)(nil)).Select("1").Limit(1).Find(&exists)
+	}); err != nil {
 		return false, err
 	}
 	return exists, nil
@@ -85,7 +182,9 @@ func (repo *GormRepo[MOD, CLS]) Exist(where func(db *gorm.DB, cls CLS) *gorm.DB)
 // 返回记录切片，如果查询失败则返回错误
 func (repo *GormRepo[MOD, CLS]) Find(where func(db *gorm.DB, cls CLS) *gorm.DB) ([]*MOD, error) {
 	var results []*MOD
-	if err := repo.Gorm().Find(where, &results).Error; err != nil {
+	if _, err := repo.intercept(&Invocation{Operation: OpFind, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		return repo.wrapOf(db).Find(where, &results)
+	}); err != nil {
 		return nil, err
 	}
 	return results, nil
@@ -98,7 +197,9 @@ func (repo *GormRepo[MOD, CLS]) Find(where func(db *gorm.DB, cls CLS) *gorm.DB)
 // 最多返回 size 条记录
 func (repo *GormRepo[MOD, CLS]) FindN(where func(db *gorm.DB, cls CLS) *gorm.DB, size int) ([]*MOD, error) {
 	var results = make([]*MOD, 0, size)
-	if err := where(repo.db, repo.cls).Limit(size).Find(&results).Error; err != nil {
+	if _, err := repo.intercept(&Invocation{Operation: OpFindN, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		return where(db, repo.cls).Limit(size).Find(&results)
+	}); err != nil {
 		return nil, err
 	}
 	return results, nil
@@ -111,19 +212,14 @@ func (repo *GormRepo[MOD, CLS]) FindN(where func(db *gorm.DB, cls CLS) *gorm.DB,
 // 执行两个查询：一个带分页，一个不带以获取总数
 func (repo *GormRepo[MOD, CLS]) FindC(where func(db *gorm.DB, cls CLS) *gorm.DB, paging func(db *gorm.DB, cls CLS) *gorm.DB) ([]*MOD, int64, error) {
 	var results []*MOD
-	{
-		db := where(repo.db, repo.cls)
-		db = paging(db, repo.cls)
-		if err := db.Find(&results).Error; err != nil {
-			return nil, 0, err
-		}
-	}
 	var count int64
-	{
-		db := repo.db.Model((*MOD)(nil))
-		if err := where(db, repo.cls).Count(&count).Error; err != nil {
-			return nil, 0, err
+	if _, err := repo.intercept(&Invocation{Operation: OpFindC, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		if result := paging(where(db, repo.cls), repo.cls).Find(&results); result.Error != nil {
+			return result
 		}
+		return where(db.Model((*MOD)(nil)), repo.cls).Count(&count)
+	}); err != nil {
+		return nil, 0, err
 	}
 	return results, count, nil
 }
@@ -134,13 +230,14 @@ func (repo *GormRepo[MOD, CLS]) FindC(where func(db *gorm.DB, cls CLS) *gorm.DB,
 // FindPageAndCount 使用排序检索分页记录并返回总数
 // 在单个方法调用中组合分页和计数查询
 func (repo *GormRepo[MOD, CLS]) FindPageAndCount(where func(db *gorm.DB, cls CLS) *gorm.DB, ordering func(cls CLS) gormcnm.OrderByBottle, page *Pagination) ([]*MOD, int64, error) {
-	results, err := repo.FindPage(where, ordering, page)
-	if err != nil {
-		return nil, 0, err
-	}
-	db := repo.db.Model((*MOD)(nil))
+	var results = make([]*MOD, 0, page.Limit)
 	var count int64
-	if err := where(db, repo.cls).Count(&count).Error; err != nil {
+	if _, err := repo.intercept(&Invocation{Operation: OpFindPageAndCount, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		if result := repo.findPage(db, where, ordering, page, &results); result.Error != nil {
+			return result
+		}
+		return where(db.Model((*MOD)(nil)), repo.cls).Count(&count)
+	}); err != nil {
 		return nil, 0, err
 	}
 	return results, count, nil
@@ -152,24 +249,32 @@ func (repo *GormRepo[MOD, CLS]) FindPageAndCount(where func(db *gorm.DB, cls CLS
 // FindPage 使用排序检索分页记录
 // 使用 Pagination 结构体指定偏移和限制
 func (repo *GormRepo[MOD, CLS]) FindPage(where func(db *gorm.DB, cls CLS) *gorm.DB, ordering func(cls CLS) gormcnm.OrderByBottle, page *Pagination) ([]*MOD, error) {
-	db := where(repo.db, repo.cls)
-	// GORM method just receives a few types, so we convert it to string
-	// GORM 方法只接受几种类型，因此我们将其转换为字符串
-	db = db.Order(string(ordering(repo.cls)))
-	db = db.Limit(page.Limit).Offset(page.Offset)
 	var results = make([]*MOD, 0, page.Limit)
-	if err := db.Find(&results).Error; err != nil {
+	if _, err := repo.intercept(&Invocation{Operation: OpFindPage, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		return repo.findPage(db, where, ordering, page, &results)
+	}); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
+func (repo *GormRepo[MOD, CLS]) findPage(db *gorm.DB, where func(db *gorm.DB, cls CLS) *gorm.DB, ordering func(cls CLS) gormcnm.OrderByBottle, page *Pagination, results *[]*MOD) *gorm.DB {
+	db = where(db, repo.cls)
+	// GORM method just receives a few types, so we convert it to string
+	// GORM 方法只接受几种类型，因此我们将其转换为字符串
+	db = db.Order(string(ordering(repo.cls)))
+	db = db.Limit(page.Limit).Offset(page.Offset)
+	return db.Find(results)
+}
+
 // Count returns the number of records matching the where condition
 //
 // Count 返回符合 where 条件的记录数量
 func (repo *GormRepo[MOD, CLS]) Count(where func(db *gorm.DB, cls CLS) *gorm.DB) (int64, error) {
 	var count int64
-	if err := where(repo.db, repo.cls).Model((*MOD)(nil)).Count(&count).Error; err != nil {
+	if _, err := repo.intercept(&Invocation{Operation: OpCount, Where: where, Dest: &count}, func(db *gorm.DB) *gorm.DB {
+		return where(db, repo.cls).Model((*MOD)(nil)).Count(&count)
+	}); err != nil {
 		return 0, err
 	}
 	return count, nil
@@ -181,10 +286,8 @@ func (repo *GormRepo[MOD, CLS]) Count(where func(db *gorm.DB, cls CLS) *gorm.DB)
 // Update 更新符合 where 条件的记录的单个列
 // 使用 valueFunc 指定列名和值
 func (repo *GormRepo[MOD, CLS]) Update(where func(db *gorm.DB, cls CLS) *gorm.DB, valueFunc func(cls CLS) (string, interface{})) error {
-	if err := repo.Gorm().Update(where, valueFunc).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.UpdateR(where, valueFunc)
+	return err
 }
 
 // Updates updates multiple columns for records matching the where condition
@@ -193,10 +296,8 @@ func (repo *GormRepo[MOD, CLS]) Update(where func(db *gorm.DB, cls CLS) *gorm.DB
 // Updates 更新符合 where 条件的记录的多个列
 // 使用 mapValues 指定列值对
 func (repo *GormRepo[MOD, CLS]) Updates(where func(db *gorm.DB, cls CLS) *gorm.DB, mapValues func(cls CLS) map[string]interface{}) error {
-	if err := repo.Gorm().Updates(where, mapValues).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.UpdatesR(where, mapValues)
+	return err
 }
 
 // UpdatesM updates multiple columns using ColumnValueMap, provides fluent API without AsMap() call
@@ -212,26 +313,44 @@ func (repo *GormRepo[MOD, CLS]) UpdatesM(where func(db *gorm.DB, cls CLS) *gorm.
 
 // UpdatesO updates object using primary key as condition, with ColumnValueMap for values
 // O = Object, the object must have valid primary key value, GORM uses it to locate the record
+// Versioned models: see VersionColumnFace
 //
 // UpdatesO 使用主键作为条件更新对象，使用 ColumnValueMap 指定更新值
 // O = Object，object 必须有有效的主键值，GORM 会用它来定位要更新的记录
+// 带版本的模型：见 VersionColumnFace
 func (repo *GormRepo[MOD, CLS]) UpdatesO(object *MOD, newValues func(cls CLS) gormcnm.ColumnValueMap) error {
-	if err := repo.db.Model(object).Updates(newValues(repo.cls).AsMap()).Error; err != nil {
-		return err
+	_, err := repo.UpdatesOR(object, newValues)
+	return err
+}
+
+// UpdatesOE updates object using primary key as condition, with structured error handling
+// NotExist is true when no row matched the primary key, instead of a silent zero-row success
+// On MySQL enable clientFoundRows in the DSN, otherwise rows updated with unchanged values count as not matched
+//
+// UpdatesOE 使用主键作为条件更新对象，带有结构化错误处理
+// 当没有行匹配主键时 NotExist 为 true，而不是静默地返回零行成功
+// 在 MySQL 上需在 DSN 中开启 clientFoundRows，否则值未变化的行会被视为未匹配
+func (repo *GormRepo[MOD, CLS]) UpdatesOE(object *MOD, newValues func(cls CLS) gormcnm.ColumnValueMap) *ErrorOrNotExist {
+	count, err := repo.UpdatesOR(object, newValues)
+	if err != nil {
+		return NewErrorOrNotExist(err)
+	}
+	if count == 0 {
+		return notMatched("updates")
 	}
 	return nil
 }
 
 // UpdatesC updates object using combined conditions: primary key from object plus where clause
 // C = Combined, uses both object primary key and where conditions for precise targeting
+// Versioned models: see VersionColumnFace
 //
 // UpdatesC 使用组合条件更新对象：object 的主键加上 where 子句
 // C = Combined，同时使用 object 主键和 where 条件进行精确定位
+// 带版本的模型：见 VersionColumnFace
 func (repo *GormRepo[MOD, CLS]) UpdatesC(object *MOD, where func(db *gorm.DB, cls CLS) *gorm.DB, newValues func(cls CLS) gormcnm.ColumnValueMap) error {
-	if err := where(repo.db.Model(object), repo.cls).Updates(newValues(repo.cls).AsMap()).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.UpdatesCR(object, where, newValues)
+	return err
 }
 
 // Invoke executes a custom operation using the database connection and column definitions
@@ -240,10 +359,10 @@ func (repo *GormRepo[MOD, CLS]) UpdatesC(object *MOD, where func(db *gorm.DB, cl
 // Invoke 使用数据库连接和列定义执行自定义操作
 // 返回自定义操作产生的错误
 func (repo *GormRepo[MOD, CLS]) Invoke(clsRun func(db *gorm.DB, cls CLS) *gorm.DB) error {
-	if err := clsRun(repo.db, repo.cls).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.intercept(&Invocation{Operation: OpInvoke, Where: clsRun}, func(db *gorm.DB) *gorm.DB {
+		return clsRun(db, repo.cls)
+	})
+	return err
 }
 
 // Create inserts a new record into the database
@@ -252,10 +371,10 @@ func (repo *GormRepo[MOD, CLS]) Invoke(clsRun func(db *gorm.DB, cls CLS) *gorm.D
 // Create 向数据库插入一条新记录
 // 创建后记录的主键将被填充
 func (repo *GormRepo[MOD, CLS]) Create(one *MOD) error {
-	if err := repo.db.Create(one).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.intercept(&Invocation{Operation: OpCreate, Object: one}, func(db *gorm.DB) *gorm.DB {
+		return db.Create(one)
+	})
+	return err
 }
 
 // Creates inserts multiple records into the database in batch
@@ -264,10 +383,10 @@ func (repo *GormRepo[MOD, CLS]) Create(one *MOD) error {
 // Creates 批量向数据库插入多条记录
 // 创建后所有记录的主键将被填充
 func (repo *GormRepo[MOD, CLS]) Creates(ones []*MOD) error {
-	if err := repo.db.Create(ones).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.intercept(&Invocation{Operation: OpCreates, Object: ones}, func(db *gorm.DB) *gorm.DB {
+		return db.Create(ones)
+	})
+	return err
 }
 
 // CreateInBatches inserts records in batches to reduce database and memory pressure
@@ -276,34 +395,38 @@ func (repo *GormRepo[MOD, CLS]) Creates(ones []*MOD) error {
 // CreateInBatches 分批插入记录以减少数据库和内存压力
 // 使用 batchSize 控制每批的记录数，适合大量数据插入
 func (repo *GormRepo[MOD, CLS]) CreateInBatches(ones []*MOD, batchSize int) error {
-	if err := repo.db.CreateInBatches(ones, batchSize).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.intercept(&Invocation{Operation: OpCreateInBatches, Object: ones}, func(db *gorm.DB) *gorm.DB {
+		return db.CreateInBatches(ones, batchSize)
+	})
+	return err
 }
 
 // Save inserts or updates a record based on primary key
 // If primary key is zero value, creates new record; otherwise updates existing
+// Versioned models: see VersionColumnFace
 //
 // Save 根据主键插入或更新记录
 // 如果主键是零值，创建新记录；否则更新现有记录
+// 带版本的模型：见 VersionColumnFace
 func (repo *GormRepo[MOD, CLS]) Save(one *MOD) error {
-	if err := repo.db.Save(one).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.intercept(&Invocation{Operation: OpSave, Object: one}, func(db *gorm.DB) *gorm.DB {
+		return repo.wrapOf(db).Save(one)
+	})
+	return err
 }
 
 // Saves inserts or updates multiple records based on primary keys
 // For each record: if primary key is zero value, creates new; otherwise updates existing
+// Versioned models: see VersionColumnFace
 //
 // Saves 根据主键批量插入或更新多条记录
 // 对于每条记录：如果主键是零值，创建新记录；否则更新现有记录
+// 带版本的模型：见 VersionColumnFace
 func (repo *GormRepo[MOD, CLS]) Saves(ones []*MOD) error {
-	if err := repo.db.Save(ones).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.intercept(&Invocation{Operation: OpSaves, Object: ones}, func(db *gorm.DB) *gorm.DB {
+		return repo.wrapOf(db).Saves(ones)
+	})
+	return err
 }
 
 // Delete deletes the given record using its primary key
@@ -312,10 +435,10 @@ func (repo *GormRepo[MOD, CLS]) Saves(ones []*MOD) error {
 // Delete 使用主键删除给定记录
 // 使用 GORM Delete 时，参数 one 不能为 nil，因为 GORM 需要有效实例
 func (repo *GormRepo[MOD, CLS]) Delete(one *MOD) error {
-	if err := repo.db.Delete(one).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.intercept(&Invocation{Operation: OpDelete, Object: one}, func(db *gorm.DB) *gorm.DB {
+		return db.Delete(one)
+	})
+	return err
 }
 
 // DeleteW deletes records matching the where condition
@@ -324,10 +447,22 @@ func (repo *GormRepo[MOD, CLS]) Delete(one *MOD) error {
 // DeleteW 删除符合 where 条件的记录
 // W = Where，使用 where 条件而不是对象主键
 func (repo *GormRepo[MOD, CLS]) DeleteW(where func(db *gorm.DB, cls CLS) *gorm.DB) error {
-	// GORM Delete needs valid instance, not nil, otherwise gets ErrInvalidValue
-	// GORM Delete 需要有效实例，不能为 nil，否则报 ErrInvalidValue 错误
-	if err := where(repo.db, repo.cls).Delete(new(MOD)).Error; err != nil {
-		return err
+	_, err := repo.DeleteWR(where)
+	return err
+}
+
+// DeleteWE deletes records matching the where condition, with structured error handling
+// NotExist is true when no row matched, instead of a silent zero-row success
+//
+// DeleteWE 删除符合 where 条件的记录，带有结构化错误处理
+// 当没有行匹配时 NotExist 为 true，而不是静默地返回零行成功
+func (repo *GormRepo[MOD, CLS]) DeleteWE(where func(db *gorm.DB, cls CLS) *gorm.DB) *ErrorOrNotExist {
+	count, err := repo.DeleteWR(where)
+	if err != nil {
+		return NewErrorOrNotExist(err)
+	}
+	if count == 0 {
+		return notMatched("delete")
 	}
 	return nil
 }
@@ -338,10 +473,10 @@ func (repo *GormRepo[MOD, CLS]) DeleteW(where func(db *gorm.DB, cls CLS) *gorm.D
 // DeleteM 删除给定对象并附加 where 条件
 // M = Model + Where，组合对象和 where 条件
 func (repo *GormRepo[MOD, CLS]) DeleteM(one *MOD, where func(db *gorm.DB, cls CLS) *gorm.DB) error {
-	if err := where(repo.db, repo.cls).Delete(one).Error; err != nil {
-		return err
-	}
-	return nil
+	_, err := repo.intercept(&Invocation{Operation: OpDeleteM, Where: where, Object: one}, func(db *gorm.DB) *gorm.DB {
+		return where(db, repo.cls).Delete(one)
+	})
+	return err
 }
 
 // Clauses adds clauses to the database and returns a new GormRepo
@@ -352,7 +487,7 @@ func (repo *GormRepo[MOD, CLS]) DeleteM(one *MOD, where func(db *gorm.DB, cls CL
 // 通过方法链支持 upsert 和其他基于子句的操作
 // 示例：repo.Clauses(clause.OnConflict{...}).Create(&record)
 func (repo *GormRepo[MOD, CLS]) Clauses(clauses ...clause.Expression) *GormRepo[MOD, CLS] {
-	return NewGormRepo[MOD, CLS](repo.db.Clauses(clauses...), (*MOD)(nil), repo.cls)
+	return repo.derive(repo.db.Clauses(clauses...))
 }
 
 // Clause adds a clause built from column definitions and returns a new GormRepo
@@ -102,6 +102,95 @@ func TestGormRepo_FirstE(t *testing.T) {
 	})
 }
 
+// TestGormRepo_TakeE tests the TakeE method with structured error handling
+// TestGormRepo_TakeE 测试带结构化错误处理的 TakeE 方法
+func TestGormRepo_TakeE(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Umc(db, &Account{}))
+
+	t.Run("case-1", func(t *testing.T) {
+		res, erb := repo.TakeE(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq("demo-1-username"))
+		})
+		require.Nil(t, erb)
+		require.Equal(t, "demo-1-nickname", res.Nickname)
+	})
+
+	t.Run("case-2", func(t *testing.T) {
+		res, erb := repo.TakeE(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq("demo-x-username"))
+		})
+		require.NotNil(t, erb)
+		require.True(t, erb.NotExist)
+		require.ErrorIs(t, erb, gorm.ErrRecordNotFound)
+		require.Nil(t, res)
+	})
+}
+
+// TestGormRepo_LastE tests the LastE method returning the record with the largest primary key
+// TestGormRepo_LastE 测试 LastE 方法返回主键最大的记录
+func TestGormRepo_LastE(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Umc(db, &Account{}))
+
+	t.Run("case-1", func(t *testing.T) {
+		res, erb := repo.LastE(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db
+		})
+		require.Nil(t, erb)
+		require.Equal(t, "demo-2-nickname", res.Nickname)
+	})
+
+	t.Run("case-2", func(t *testing.T) {
+		res, erb := repo.LastE(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq("demo-x-username"))
+		})
+		require.NotNil(t, erb)
+		require.True(t, erb.NotExist)
+		require.Nil(t, res)
+	})
+}
+
+// TestGormRepo_FindOneE tests that FindOneE expects exactly one matching record
+// TestGormRepo_FindOneE 测试 FindOneE 要求恰好匹配一条记录
+func TestGormRepo_FindOneE(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Umc(db, &Account{}))
+
+	t.Run("one", func(t *testing.T) {
+		res, erb := repo.FindOneE(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq("demo-1-username"))
+		})
+		require.Nil(t, erb)
+		require.Equal(t, "demo-1-nickname", res.Nickname)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		res, erb := repo.FindOneE(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq("demo-x-username"))
+		})
+		require.NotNil(t, erb)
+		require.True(t, erb.NotExist)
+		require.Nil(t, res)
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		res, erb := repo.FindOneE(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db
+		})
+		require.NotNil(t, erb)
+		require.False(t, erb.NotExist)
+		require.ErrorIs(t, erb, gormrepo.ErrMultipleRecords)
+		require.Nil(t, res)
+	})
+}
+
 // TestGormRepo_Where tests the Where method to apply custom conditions
 // TestGormRepo_Where 测试 Where 方法应用自定义条件
 func TestGormRepo_Where(t *testing.T) {
@@ -597,6 +686,28 @@ func TestGormRepo_DeleteW(t *testing.T) {
 	})
 }
 
+// TestGormRepo_DeleteWE tests that DeleteWE reports deletes matching nothing
+// TestGormRepo_DeleteWE 测试 DeleteWE 报告没有匹配任何行的删除
+func TestGormRepo_DeleteWE(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	username := uuid.New().String()
+	require.NoError(t, repo.Create(newAccount(username)))
+
+	where := func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq(username))
+	}
+	require.Nil(t, repo.DeleteWE(where))
+
+	erb := repo.DeleteWE(where)
+	require.NotNil(t, erb)
+	require.True(t, erb.NotExist)
+	require.ErrorIs(t, erb, gorm.ErrRecordNotFound)
+}
+
 func TestGormRepo_DeleteM(t *testing.T) {
 	tests.NewDBRun(t, func(db *gorm.DB) {
 		must.Done(db.AutoMigrate(&Account{}))
@@ -651,6 +762,28 @@ func TestGormRepo_UpdatesO(t *testing.T) {
 	})
 }
 
+// TestGormRepo_UpdatesOE tests that UpdatesOE reports updates matching nothing
+// TestGormRepo_UpdatesOE 测试 UpdatesOE 报告没有匹配任何行的更新
+func TestGormRepo_UpdatesOE(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	account := newAccount(uuid.New().String())
+	require.NoError(t, repo.Create(account))
+
+	newValues := func(cls *AccountColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Nickname.Kv(uuid.New().String()))
+	}
+	require.Nil(t, repo.UpdatesOE(account, newValues))
+
+	require.NoError(t, repo.Delete(account))
+	erb := repo.UpdatesOE(account, newValues)
+	require.NotNil(t, erb)
+	require.True(t, erb.NotExist)
+}
+
 // TestGormRepo_UpdatesC tests update using combined conditions: object primary key plus where clause
 // C = Combined, uses both object primary key and where conditions
 //
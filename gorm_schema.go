@@ -0,0 +1,40 @@
+package gormrepo
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ParseSchema parses the GORM schema of MOD using the cache store of the given db
+// Returns the schema, which describes fields, primary keys and the table name
+//
+// ParseSchema 使用给定 db 的缓存解析 MOD 的 GORM schema
+// 返回描述字段、主键和表名的 schema
+func ParseSchema[MOD any](db *gorm.DB) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(MOD)); err != nil {
+		return nil, errors.Wrap(err, "parse schema")
+	}
+	return stmt.Schema, nil
+}
+
+// lookupField finds the schema field matching the column name
+// Column names from CLS can be decorated with table prefix or quotes, these are stripped first
+//
+// lookupField 查找与列名匹配的 schema 字段
+// CLS 中的列名可能带有表名前缀或引号，会先去除这些修饰
+func lookupField(sch *schema.Schema, columnName string) (*schema.Field, error) {
+	name := columnName
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	name = strings.Trim(name, "`\"[] ")
+	field := sch.LookUpField(name)
+	if field == nil {
+		return nil, errors.Errorf("column %s not found in table %s", columnName, sch.Table)
+	}
+	return field, nil
+}
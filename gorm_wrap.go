@@ -39,6 +39,24 @@ func (wrap *GormWrap[MOD, CLS]) First(where func(db *gorm.DB, cls CLS) *gorm.DB,
 	return where(wrap.db, wrap.cls).First(dest)
 }
 
+// Take finds one record matching the where condition, without ordering
+// Returns *gorm.DB for checking errors via .Error field
+//
+// Take 查找符合 where 条件的一条记录，不排序
+// 返回 *gorm.DB 以便通过 .Error 字段检查错误
+func (wrap *GormWrap[MOD, CLS]) Take(where func(db *gorm.DB, cls CLS) *gorm.DB, dest *MOD) *gorm.DB {
+	return where(wrap.db, wrap.cls).Take(dest)
+}
+
+// Last finds the last record matching the where condition, ordered by primary key
+// Returns *gorm.DB for checking errors via .Error field
+//
+// Last 查找符合 where 条件的最后一条记录，按主键排序
+// 返回 *gorm.DB 以便通过 .Error 字段检查错误
+func (wrap *GormWrap[MOD, CLS]) Last(where func(db *gorm.DB, cls CLS) *gorm.DB, dest *MOD) *gorm.DB {
+	return where(wrap.db, wrap.cls).Last(dest)
+}
+
 // Where applies the where condition and returns the gorm.DB to enable chaining
 // Use when you need custom operations not provided by GormWrap
 //
@@ -88,20 +106,24 @@ func (wrap *GormWrap[MOD, CLS]) UpdatesM(where func(db *gorm.DB, cls CLS) *gorm.
 
 // UpdatesO updates object using primary key as condition, with ColumnValueMap for values
 // O = Object, the object must have valid primary key value, GORM uses it to locate the record
+// Versioned models: see VersionColumnFace
 //
 // UpdatesO 使用主键作为条件更新对象，使用 ColumnValueMap 指定更新值
 // O = Object，object 必须有有效的主键值，GORM 会用它来定位要更新的记录
+// 带版本的模型：见 VersionColumnFace
 func (wrap *GormWrap[MOD, CLS]) UpdatesO(object *MOD, newValues func(cls CLS) gormcnm.ColumnValueMap) *gorm.DB {
-	return wrap.db.Model(object).Updates(newValues(wrap.cls).AsMap())
+	return updatesVersioned(wrap.db, wrap.cls, object, newValues(wrap.cls).AsMap())
 }
 
 // UpdatesC updates object using combined conditions: primary key from object plus where clause
 // C = Combined, uses both object primary key and where conditions for precise targeting
+// Versioned models: see VersionColumnFace
 //
 // UpdatesC 使用组合条件更新对象：object 的主键加上 where 子句
 // C = Combined，同时使用 object 主键和 where 条件进行精确定位
+// 带版本的模型：见 VersionColumnFace
 func (wrap *GormWrap[MOD, CLS]) UpdatesC(object *MOD, where func(db *gorm.DB, cls CLS) *gorm.DB, newValues func(cls CLS) gormcnm.ColumnValueMap) *gorm.DB {
-	return where(wrap.db.Model(object), wrap.cls).Updates(newValues(wrap.cls).AsMap())
+	return updatesVersioned(where(wrap.db, wrap.cls), wrap.cls, object, newValues(wrap.cls).AsMap())
 }
 
 // Invoke executes a custom operation using the database connection and column definitions
@@ -142,20 +164,24 @@ func (wrap *GormWrap[MOD, CLS]) CreateInBatches(ones []*MOD, batchSize int) *gor
 
 // Save inserts or updates a record based on primary key
 // If primary key is zero value, creates new record; otherwise updates existing
+// Versioned models: see VersionColumnFace
 //
 // Save 根据主键插入或更新记录
 // 如果主键是零值，创建新记录；否则更新现有记录
+// 带版本的模型：见 VersionColumnFace
 func (wrap *GormWrap[MOD, CLS]) Save(one *MOD) *gorm.DB {
-	return wrap.db.Save(one)
+	return saveVersioned(wrap.db, wrap.cls, one)
 }
 
 // Saves inserts or updates multiple records based on primary keys
 // For each record: if primary key is zero value, creates new; otherwise updates existing
+// Versioned models: see VersionColumnFace
 //
 // Saves 根据主键批量插入或更新多条记录
 // 对于每条记录：如果主键是零值，创建新记录；否则更新现有记录
+// 带版本的模型：见 VersionColumnFace
 func (wrap *GormWrap[MOD, CLS]) Saves(ones []*MOD) *gorm.DB {
-	return wrap.db.Save(ones)
+	return savesVersioned(wrap.db, wrap.cls, ones)
 }
 
 // Delete deletes the given record using its primary key
@@ -39,6 +39,27 @@ func TestGormWrap_First(t *testing.T) {
 	})
 }
 
+// TestGormWrap_Take tests the Take and Last methods
+// TestGormWrap_Take 测试 Take 和 Last 方法
+func TestGormWrap_Take(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	repo := gormrepo.NewGormWrap(gormrepo.Umc(db, &Account{}))
+
+	var account Account
+	require.NoError(t, repo.Take(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	}, &account).Error)
+	require.Equal(t, "demo-1-nickname", account.Nickname)
+
+	var last Account
+	require.NoError(t, repo.Last(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	}, &last).Error)
+	require.Equal(t, "demo-2-nickname", last.Nickname)
+}
+
 func TestGormWrap_Where(t *testing.T) {
 	db := tests.NewMemDB(t)
 	defer rese.F0(rese.P1(db.DB()).Close)
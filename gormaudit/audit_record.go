@@ -0,0 +1,26 @@
+package gormaudit
+
+import "time"
+
+// Record is the default audit table row written by TableSink
+// Before and Changes hold JSON, the values before the write and the changed columns
+//
+// Record 是 TableSink 写入的默认审计表行
+// Before 和 Changes 保存 JSON，分别为写入前的值和变化的列
+type Record struct {
+	ID         uint64    `gorm:"primaryKey"`
+	Table      string    `gorm:"column:table_name;type:varchar(255);not null;index:idx_audit_records_row"`
+	PrimaryKey string    `gorm:"column:primary_key;type:varchar(255);not null;index:idx_audit_records_row"`
+	Operation  string    `gorm:"type:varchar(64);not null"`
+	Actor      string    `gorm:"type:varchar(255)"`
+	RequestID  string    `gorm:"type:varchar(255);index"`
+	Before     string    `gorm:"type:text"`
+	Changes    string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"not null"`
+}
+
+// TableName returns the audit table name
+// TableName 返回审计表名
+func (*Record) TableName() string {
+	return "audit_records"
+}
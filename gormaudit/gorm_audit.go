@@ -0,0 +1,422 @@
+// Package gormaudit records an audit trail of GormRepo writes
+// An Auditor is a gormrepo.Interceptor loading the rows before the write, diffing the CLS columns after it,
+// and handing the entries to a pluggable sink in the same transaction as the write
+//
+// gormaudit 记录 GormRepo 写入的审计轨迹
+// Auditor 是一个 gormrepo.Interceptor，在写入前加载行，写入后比较 CLS 中的列，
+// 并在与写入相同的事务中将审计条目交给可插拔的存储
+package gormaudit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormrepo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type actorKey struct{}
+
+type requestIDKey struct{}
+
+// WithActor returns a context carrying the actor recorded in audit entries
+// WithActor 返回携带审计条目中记录的操作者的上下文
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor carried by ctx, empty when none
+// ActorFrom 返回 ctx 携带的操作者，没有时为空
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// WithRequestID returns a context carrying the request ID recorded in audit entries
+// WithRequestID 返回携带审计条目中记录的请求 ID 的上下文
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFrom returns the request ID carried by ctx, empty when none
+// RequestIDFrom 返回 ctx 携带的请求 ID，没有时为空
+func RequestIDFrom(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Change is the change of one column, Before is nil on creates and After is nil on deletes
+// Change 是一列的变化，创建时 Before 为 nil，删除时 After 为 nil
+type Change struct {
+	Column string      `json:"column"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Entry is the audit of one row touched by a write
+// Entry 是一次写入所涉及的一行的审计
+type Entry struct {
+	Table      string                 // Table of the row // 行所在的表
+	PrimaryKey string                 // Primary key of the row, composite keys joined by comma // 行的主键，复合主键以逗号连接
+	Operation  gormrepo.Operation     // Write operation // 写入操作
+	Actor      string                 // Actor from the context // 来自上下文的操作者
+	RequestID  string                 // Request ID from the context // 来自上下文的请求 ID
+	Before     map[string]interface{} // Column values before the write, nil on creates // 写入前的列值，创建时为 nil
+	Changes    []Change               // Changed columns in CLS order // 按 CLS 顺序排列的变化列
+	At         time.Time              // Time of the write // 写入时间
+}
+
+// Sink stores audit entries, db is the transaction of the audited write
+// Sink 存储审计条目，db 是被审计写入所在的事务
+type Sink interface {
+	Write(ctx context.Context, db *gorm.DB, entries []*Entry) error
+}
+
+// SinkFunc adapts a function to the Sink interface
+// SinkFunc 将函数适配为 Sink 接口
+type SinkFunc func(ctx context.Context, db *gorm.DB, entries []*Entry) error
+
+// Write calls fn(ctx, db, entries)
+// Write 调用 fn(ctx, db, entries)
+func (fn SinkFunc) Write(ctx context.Context, db *gorm.DB, entries []*Entry) error {
+	return fn(ctx, db, entries)
+}
+
+// TableSink writes the entries as Record rows into the audit_records table
+// TableSink 将条目作为 Record 行写入 audit_records 表
+type TableSink struct{}
+
+// NewTableSink creates a TableSink, migrate Record before use
+// NewTableSink 创建 TableSink，使用前需迁移 Record
+func NewTableSink() *TableSink {
+	return &TableSink{}
+}
+
+// Write inserts one Record per entry
+// Write 为每个条目插入一条 Record
+func (sink *TableSink) Write(ctx context.Context, db *gorm.DB, entries []*Entry) error {
+	var records = make([]*Record, 0, len(entries))
+	for _, entry := range entries {
+		before, err := json.Marshal(entry.Before)
+		if err != nil {
+			return errors.WithMessage(err, "marshal before values")
+		}
+		changes, err := json.Marshal(entry.Changes)
+		if err != nil {
+			return errors.WithMessage(err, "marshal changes")
+		}
+		records = append(records, &Record{
+			Table:      entry.Table,
+			PrimaryKey: entry.PrimaryKey,
+			Operation:  string(entry.Operation),
+			Actor:      entry.Actor,
+			RequestID:  entry.RequestID,
+			Before:     string(before),
+			Changes:    string(changes),
+			CreatedAt:  entry.At,
+		})
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Create(records).Error
+}
+
+// Auditor audits the writes of the GormRepo of MOD, install it with WithInterceptors
+// Only the columns declared in CLS are recorded and diffed
+//
+// Auditor 审计 MOD 的 GormRepo 写入，通过 WithInterceptors 安装
+// 只记录和比较 CLS 中声明的列
+type Auditor[MOD any, CLS any] struct {
+	cls   CLS
+	sink  Sink
+	clock func() time.Time
+}
+
+// NewAuditor creates an Auditor writing into the default TableSink
+// The MOD param is used to deduce the type, its value is not used
+//
+// NewAuditor 创建写入默认 TableSink 的 Auditor
+// MOD 参数用于类型推断，其值不使用
+func NewAuditor[MOD any, CLS any](_ *MOD, cls CLS) *Auditor[MOD, CLS] {
+	return &Auditor[MOD, CLS]{
+		cls:   cls,
+		sink:  NewTableSink(),
+		clock: time.Now,
+	}
+}
+
+// WithSink sets the sink storing the entries
+// WithSink 设置存储条目的存储
+func (auditor *Auditor[MOD, CLS]) WithSink(sink Sink) *Auditor[MOD, CLS] {
+	auditor.sink = sink
+	return auditor
+}
+
+// WithClock sets the time source of Entry.At
+// WithClock 设置 Entry.At 的时间来源
+func (auditor *Auditor[MOD, CLS]) WithClock(clock func() time.Time) *Auditor[MOD, CLS] {
+	auditor.clock = clock
+	return auditor
+}
+
+// Interceptor returns the interceptor auditing the writes, reads pass through untouched
+// Each write runs in a transaction (a savepoint inside a repo transaction) with its audit, a failing sink rolls the write back
+//
+// Interceptor 返回审计写入的拦截器，读取操作直接通过
+// 每次写入与其审计在同一事务中运行（在仓储事务中时为保存点），存储失败会回滚写入
+func (auditor *Auditor[MOD, CLS]) Interceptor() gormrepo.Interceptor {
+	return func(inv *gormrepo.Invocation, next func() error) error {
+		if inv.Operation.IsRead() || inv.Operation == gormrepo.OpInvoke {
+			return next()
+		}
+		return gormrepo.Transaction(inv.Context, inv.DB, func(uow *gormrepo.UnitOfWork) error {
+			inv.DB = uow.DB()
+			return auditor.audit(inv, next)
+		})
+	}
+}
+
+func (auditor *Auditor[MOD, CLS]) audit(inv *gormrepo.Invocation, next func() error) error {
+	ctx := inv.DB.Statement.Context
+	sch, columns, err := auditor.columns(inv.DB)
+	if err != nil {
+		return err
+	}
+	before, err := auditor.loadBefore(inv, sch)
+	if err != nil {
+		return errors.WithMessage(err, "load rows before write")
+	}
+	if err := next(); err != nil {
+		return err
+	}
+	var after = map[string]*MOD{}
+	if !isDelete(inv.Operation) {
+		var targets = make([]*MOD, 0, len(before))
+		for _, one := range before {
+			targets = append(targets, one)
+		}
+		targets = append(targets, gormrepo.ObjectsOf[MOD](inv.Object)...)
+		if after, err = loadRows(inv.DB, sch, targets); err != nil {
+			return errors.WithMessage(err, "load rows after write")
+		}
+	}
+
+	var entries []*Entry
+	var push = func(key string, previous *MOD, current *MOD) {
+		entry := &Entry{
+			Table:      sch.Table,
+			PrimaryKey: key,
+			Operation:  inv.Operation,
+			Actor:      ActorFrom(ctx),
+			RequestID:  RequestIDFrom(ctx),
+			At:         auditor.clock(),
+		}
+		var previousValues, currentValues map[string]interface{}
+		if previous != nil {
+			previousValues = rowValues(ctx, columns, previous)
+			entry.Before = previousValues
+		}
+		if current != nil {
+			currentValues = rowValues(ctx, columns, current)
+		}
+		for _, column := range columns {
+			var oldValue, newValue interface{}
+			if previous != nil {
+				oldValue = previousValues[column.DBName]
+			}
+			if current != nil {
+				newValue = currentValues[column.DBName]
+			}
+			if previous != nil && current != nil && reflect.DeepEqual(oldValue, newValue) {
+				continue
+			}
+			entry.Changes = append(entry.Changes, Change{Column: column.DBName, Before: oldValue, After: newValue})
+		}
+		if len(entry.Changes) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	for _, key := range sortedKeys(before, after) {
+		push(key, before[key], after[key])
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return auditor.sink.Write(ctx, inv.DB, entries)
+}
+
+// loadBefore loads the rows the write is about to touch, keyed by primary key
+// loadBefore 加载写入即将涉及的行，以主键为键
+func (auditor *Auditor[MOD, CLS]) loadBefore(inv *gormrepo.Invocation, sch *schema.Schema) (map[string]*MOD, error) {
+	switch inv.Operation {
+	case gormrepo.OpUpdate, gormrepo.OpUpdates, gormrepo.OpDeleteW, gormrepo.OpUpdatesC, gormrepo.OpDeleteM:
+		where, ok := inv.Where.(func(db *gorm.DB, cls CLS) *gorm.DB)
+		if !ok {
+			return nil, errors.Errorf("where of %s is %T, not a where func of the audited CLS", inv.Operation, inv.Where)
+		}
+		db := inv.DB.Session(&gorm.Session{})
+		if inv.Object != nil {
+			// Combined with the primary key of the object
+			// 与对象的主键组合
+			db = whereKeys(db, sch, gormrepo.ObjectsOf[MOD](inv.Object)[0])
+		}
+		var rows []*MOD
+		if err := where(db, auditor.cls).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		return keyRows(inv.DB.Statement.Context, sch, rows), nil
+	default:
+		return loadRows(inv.DB, sch, gormrepo.ObjectsOf[MOD](inv.Object))
+	}
+}
+
+// columns returns the schema and the schema fields of the CLS columns, in CLS order
+// columns 返回 schema 以及 CLS 中各列的 schema 字段，按 CLS 顺序排列
+func (auditor *Auditor[MOD, CLS]) columns(db *gorm.DB) (*schema.Schema, []*schema.Field, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(MOD)); err != nil {
+		return nil, nil, errors.WithMessage(err, "parse audited schema")
+	}
+	sch := stmt.Schema
+	if len(sch.PrimaryFields) == 0 {
+		return nil, nil, errors.Errorf("audited table %s has no primary key", sch.Table)
+	}
+	var fields []*schema.Field
+	rv := reflect.Indirect(reflect.ValueOf(auditor.cls))
+	for idx := 0; idx < rv.NumField(); idx++ {
+		column, ok := rv.Field(idx).Interface().(gormrepo.ColumnNameFace)
+		if !ok {
+			continue
+		}
+		if field := sch.LookUpField(column.Name()); field != nil {
+			fields = append(fields, field)
+		}
+	}
+	return sch, fields, nil
+}
+
+// loadRows reloads the objects with non-zero primary keys from db in one query, keyed by primary key
+// loadRows 通过一次查询从 db 重新加载主键非零的对象，以主键为键
+func loadRows[MOD any](db *gorm.DB, sch *schema.Schema, objects []*MOD) (map[string]*MOD, error) {
+	ctx := db.Statement.Context
+	var seen = map[string]bool{}
+	var tuples = make([][]interface{}, 0, len(objects))
+	for _, object := range objects {
+		key, ok := primaryKey(ctx, sch, object)
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		rv := reflect.Indirect(reflect.ValueOf(object))
+		var tuple = make([]interface{}, 0, len(sch.PrimaryFields))
+		for _, field := range sch.PrimaryFields {
+			value, _ := field.ValueOf(ctx, rv)
+			tuple = append(tuple, value)
+		}
+		tuples = append(tuples, tuple)
+	}
+	if len(tuples) == 0 {
+		return map[string]*MOD{}, nil
+	}
+	var rows []*MOD
+	if err := whereKeysIn(db.Session(&gorm.Session{}), sch, tuples).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return keyRows(ctx, sch, rows), nil
+}
+
+// whereKeysIn narrows db to the primary keys, each tuple holding the values of the primary fields
+// whereKeysIn 将 db 限定到这些主键，每个元组包含各主键字段的值
+func whereKeysIn(db *gorm.DB, sch *schema.Schema, tuples [][]interface{}) *gorm.DB {
+	if len(sch.PrimaryFields) == 1 {
+		var values = make([]interface{}, 0, len(tuples))
+		for _, tuple := range tuples {
+			values = append(values, tuple[0])
+		}
+		return db.Where(clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: sch.PrimaryFields[0].DBName}, Values: values})
+	}
+	var columns = make([]string, 0, len(sch.PrimaryFields))
+	for _, field := range sch.PrimaryFields {
+		columns = append(columns, db.Statement.Quote(field.DBName))
+	}
+	return db.Where("("+strings.Join(columns, ", ")+") IN ?", tuples)
+}
+
+// whereKeys narrows db to the primary key of the object
+// whereKeys 将 db 限定到对象的主键
+func whereKeys[MOD any](db *gorm.DB, sch *schema.Schema, object *MOD) *gorm.DB {
+	rv := reflect.Indirect(reflect.ValueOf(object))
+	for _, field := range sch.PrimaryFields {
+		value, _ := field.ValueOf(db.Statement.Context, rv)
+		db = db.Where(map[string]interface{}{field.DBName: value})
+	}
+	return db
+}
+
+// primaryKey returns the primary key of the object as string, false when it is zero
+// primaryKey 以字符串返回对象的主键，主键为零值时返回 false
+func primaryKey[MOD any](ctx context.Context, sch *schema.Schema, object *MOD) (string, bool) {
+	rv := reflect.Indirect(reflect.ValueOf(object))
+	var parts = make([]string, 0, len(sch.PrimaryFields))
+	for _, field := range sch.PrimaryFields {
+		value, isZero := field.ValueOf(ctx, rv)
+		if isZero {
+			return "", false
+		}
+		parts = append(parts, fmt.Sprint(value))
+	}
+	return strings.Join(parts, ","), true
+}
+
+func keyRows[MOD any](ctx context.Context, sch *schema.Schema, rows []*MOD) map[string]*MOD {
+	var res = make(map[string]*MOD, len(rows))
+	for _, row := range rows {
+		if key, ok := primaryKey(ctx, sch, row); ok {
+			res[key] = row
+		}
+	}
+	return res
+}
+
+func rowValues[MOD any](ctx context.Context, columns []*schema.Field, row *MOD) map[string]interface{} {
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	var res = make(map[string]interface{}, len(columns))
+	for _, field := range columns {
+		res[field.DBName], _ = field.ValueOf(ctx, rv)
+	}
+	return res
+}
+
+// sortedKeys returns the keys of both maps, keeping the order stable across runs
+// sortedKeys 返回两个映射的所有键，保证多次运行顺序稳定
+func sortedKeys[MOD any](before map[string]*MOD, after map[string]*MOD) []string {
+	var keys = make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(a, b)
+	})
+	return keys
+}
+
+func isDelete(op gormrepo.Operation) bool {
+	return op == gormrepo.OpDelete || op == gormrepo.OpDeleteW || op == gormrepo.OpDeleteM
+}
@@ -0,0 +1,239 @@
+package gormaudit_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcngen"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormaudit"
+	"github.com/yyle88/gormrepo/gormclass"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"github.com/yyle88/osexistpath/osmustexist"
+	"github.com/yyle88/runpath"
+	"gorm.io/gorm"
+)
+
+type Customer struct {
+	ID      uint
+	Name    string
+	Email   string
+	Balance int64
+}
+
+func (*Customer) TableName() string {
+	return "customers"
+}
+
+// Tests the generation of columns for models.
+// 测试模型列的生成。
+func TestGenerateColumns(t *testing.T) {
+	absPath := runpath.Path() // Retrieve the absolute path of the source file based on the current test file's location
+	// 获取当前测试文件位置基础上的源文件绝对路径
+	t.Log(absPath)
+
+	// Check the existence of the target file. The file should be created beforehand to ensure it can be located via the code.
+	// 检查目标文件是否存在。文件应手动创建，确保代码能够找到它。
+	require.True(t, osmustexist.IsFile(absPath))
+
+	// List the models to have columns generated. Both instance and non-instance types are supported.
+	// 设置需要生成列的模型，这里支持指针类型和非指针类型。
+	objects := []any{&Customer{}}
+
+	options := gormcngen.NewOptions().
+		WithColumnClassExportable(true). // Generate exportable struct names (e.g., ExampleColumns) // 生成可导出的结构体名称（例如 ExampleColumns）
+		WithColumnsMethodRecvName("a").
+		WithColumnsCheckFieldType(true)
+
+	// Configure code generation settings
+	// 配置代码生成设置
+	cfg := gormcngen.NewConfigs(objects, options, absPath).
+		WithIsGenPreventEdit(false)
+	cfg.Gen() // Generate and write the code to the target location (e.g., "gormcnm.gen.go") // 生成并将代码写入目标位置（例如 "gormcnm.gen.go"）
+}
+
+func changesOf(t *testing.T, record *gormaudit.Record) []gormaudit.Change {
+	var changes []gormaudit.Change
+	require.NoError(t, json.Unmarshal([]byte(record.Changes), &changes))
+	return changes
+}
+
+func TestAuditor_Updates(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Customer{}, &gormaudit.Record{})
+	ctx := gormaudit.WithRequestID(gormaudit.WithActor(context.Background(), "alice"), "req-1")
+
+	base := gormrepo.NewBaseRepo(gormclass.Use(&Customer{}))
+	base = base.WithInterceptors(gormaudit.NewAuditor(gormclass.Use(&Customer{})).Interceptor())
+	repo := base.With(ctx, db)
+
+	customer := &Customer{Name: "a", Email: "a@example.com", Balance: 10}
+	require.NoError(t, repo.Create(customer))
+
+	require.NoError(t, repo.UpdatesM(func(db *gorm.DB, cls *CustomerColumns) *gorm.DB {
+		return db.Where(cls.ID.Eq(customer.ID))
+	}, func(cls *CustomerColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Balance.Kv(20)).Kw(cls.Email.Kv("a@example.com"))
+	}))
+
+	var records []*gormaudit.Record
+	require.NoError(t, db.Order("id").Find(&records).Error)
+	require.Len(t, records, 2)
+
+	created := records[0]
+	require.Equal(t, string(gormrepo.OpCreate), created.Operation)
+	require.Equal(t, "null", created.Before)
+	require.Len(t, changesOf(t, created), 4)
+
+	updated := records[1]
+	require.Equal(t, "customers", updated.Table)
+	require.Equal(t, "1", updated.PrimaryKey)
+	require.Equal(t, string(gormrepo.OpUpdates), updated.Operation)
+	require.Equal(t, "alice", updated.Actor)
+	require.Equal(t, "req-1", updated.RequestID)
+	require.JSONEq(t, `{"id":1,"name":"a","email":"a@example.com","balance":10}`, updated.Before)
+
+	// Only the changed column is recorded
+	// 只记录发生变化的列
+	changes := changesOf(t, updated)
+	require.Len(t, changes, 1)
+	require.Equal(t, "balance", changes[0].Column)
+	require.EqualValues(t, 10, changes[0].Before)
+	require.EqualValues(t, 20, changes[0].After)
+}
+
+func TestAuditor_SaveDelete(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Customer{}, &gormaudit.Record{})
+	ctx := gormaudit.WithActor(context.Background(), "bob")
+
+	var entries []*gormaudit.Entry
+	sink := gormaudit.SinkFunc(func(ctx context.Context, db *gorm.DB, items []*gormaudit.Entry) error {
+		entries = append(entries, items...)
+		return nil
+	})
+	repo := gormrepo.NewBaseRepo(gormclass.Use(&Customer{})).
+		WithInterceptors(gormaudit.NewAuditor(gormclass.Use(&Customer{})).WithSink(sink).Interceptor()).
+		With(ctx, db)
+
+	customer := &Customer{Name: "b", Balance: 5}
+	require.NoError(t, repo.Save(customer))
+	customer.Name = "c"
+	require.NoError(t, repo.Save(customer))
+	require.NoError(t, repo.DeleteW(func(db *gorm.DB, cls *CustomerColumns) *gorm.DB {
+		return db.Where(cls.Name.Eq("c"))
+	}))
+
+	require.Len(t, entries, 3)
+	require.Nil(t, entries[0].Before)
+	require.Equal(t, []gormaudit.Change{{Column: "name", Before: "b", After: "c"}}, entries[1].Changes)
+
+	deleted := entries[2]
+	require.Equal(t, gormrepo.OpDeleteW, deleted.Operation)
+	require.Equal(t, "bob", deleted.Actor)
+	require.Equal(t, "c", deleted.Before["name"])
+	for _, change := range deleted.Changes {
+		require.Nil(t, change.After)
+	}
+}
+
+func TestAuditor_BatchLoad(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Customer{}, &gormaudit.Record{})
+	var queries int
+	must.Done(db.Callback().Query().After("gorm:query").Register("test:count_queries", func(db *gorm.DB) {
+		if db.Statement.Table == "customers" {
+			queries++
+		}
+	}))
+
+	var entries []*gormaudit.Entry
+	sink := gormaudit.SinkFunc(func(ctx context.Context, db *gorm.DB, items []*gormaudit.Entry) error {
+		entries = append(entries, items...)
+		return nil
+	})
+	repo := gormrepo.NewBaseRepo(gormclass.Use(&Customer{})).
+		WithInterceptors(gormaudit.NewAuditor(gormclass.Use(&Customer{})).WithSink(sink).Interceptor()).
+		With(context.Background(), db)
+
+	// The written rows are reloaded in one query, whatever their count
+	// 无论写入多少行，都通过一次查询重新加载
+	require.NoError(t, repo.Creates([]*Customer{{Name: "a"}, {Name: "b"}, {Name: "c"}}))
+	require.Equal(t, 1, queries)
+	require.Len(t, entries, 3)
+
+	require.NoError(t, repo.UpdatesM(func(db *gorm.DB, cls *CustomerColumns) *gorm.DB {
+		return db.Where(cls.ID.Gt(0))
+	}, func(cls *CustomerColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Balance.Kv(7))
+	}))
+	require.Equal(t, 3, queries)
+	require.Len(t, entries, 6)
+	for idx, entry := range entries[3:] {
+		require.Equal(t, []string{"1", "2", "3"}[idx], entry.PrimaryKey)
+		require.Equal(t, []gormaudit.Change{{Column: "balance", Before: int64(0), After: int64(7)}}, entry.Changes)
+	}
+}
+
+func TestAuditor_SinkFailure(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Customer{}, &gormaudit.Record{})
+	ctx := context.Background()
+
+	errSink := errors.New("sink unavailable")
+	repo := gormrepo.NewBaseRepo(gormclass.Use(&Customer{})).
+		WithInterceptors(gormaudit.NewAuditor(gormclass.Use(&Customer{})).WithSink(gormaudit.SinkFunc(func(ctx context.Context, db *gorm.DB, entries []*gormaudit.Entry) error {
+			return errSink
+		})).Interceptor()).
+		With(ctx, db)
+
+	// The write rolls back together with its audit
+	// 写入与其审计一起回滚
+	require.ErrorIs(t, repo.Create(&Customer{Name: "d"}), errSink)
+
+	var count int64
+	require.NoError(t, db.Model(&Customer{}).Count(&count).Error)
+	require.Equal(t, int64(0), count)
+}
+
+func TestAuditor_Transaction(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Customer{}, &gormaudit.Record{})
+	ctx := context.Background()
+
+	base := gormrepo.NewBaseRepo(gormclass.Use(&Customer{})).
+		WithInterceptors(gormaudit.NewAuditor(gormclass.Use(&Customer{})).Interceptor())
+
+	// The audit records roll back with the enclosing transaction
+	// 审计记录随外层事务一起回滚
+	require.Error(t, base.Transaction(ctx, db, func(repo *gormrepo.GormRepo[Customer, *CustomerColumns]) error {
+		if err := repo.Create(&Customer{Name: "e"}); err != nil {
+			return err
+		}
+		return errors.New("business failure")
+	}))
+
+	var count int64
+	require.NoError(t, db.Model(&gormaudit.Record{}).Count(&count).Error)
+	require.Equal(t, int64(0), count)
+}
+
+func (a *Customer) Columns() *CustomerColumns {
+	return &CustomerColumns{
+		// Auto-generated: column names and types mapping. DO NOT EDIT. // 自动生成：列名和类型映射。请勿编辑。
+		ID:      gormcnm.Cnm(a.ID, "id"),
+		Name:    gormcnm.Cnm(a.Name, "name"),
+		Email:   gormcnm.Cnm(a.Email, "email"),
+		Balance: gormcnm.Cnm(a.Balance, "balance"),
+	}
+}
+
+type CustomerColumns struct {
+	// Auto-generated: embedding operation functions to make it simple to use. DO NOT EDIT. // 自动生成：嵌入操作函数便于使用。请勿编辑。
+	gormcnm.ColumnOperationClass
+	// Auto-generated: column names and types in database table. DO NOT EDIT. // 自动生成：数据库表的列名和类型。请勿编辑。
+	ID      gormcnm.ColumnName[uint]
+	Name    gormcnm.ColumnName[string]
+	Email   gormcnm.ColumnName[string]
+	Balance gormcnm.ColumnName[int64]
+}
@@ -0,0 +1,198 @@
+// Package gormoutbox provides a transactional outbox on top of GormRepo
+// Events are appended in the transaction of the business write and delivered later by a relay, at least once
+//
+// gormoutbox 在 GormRepo 之上提供事务性发件箱
+// 事件在业务写入的事务中追加，之后由中继至少投递一次
+package gormoutbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormrepo"
+	"gorm.io/gorm"
+)
+
+// ErrNotInTransaction is returned by Append when db is not in a transaction
+// An event appended outside the transaction of the business write could be kept while the write rolls back, or lost while it commits
+//
+// ErrNotInTransaction 在 db 不处于事务中时由 Append 返回
+// 在业务写入事务之外追加的事件可能在写入回滚时被保留，或在写入提交时丢失
+var ErrNotInTransaction = errors.New("gormoutbox: append outside a transaction")
+
+// Publisher delivers events to the message broker
+// Publish may be called more than once with the same event, consumers should dedupe by Event.ID
+//
+// Publisher 将事件投递到消息代理
+// 同一事件可能被多次调用 Publish，消费方应按 Event.ID 去重
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// PublisherFunc adapts a function to the Publisher interface
+// PublisherFunc 将函数适配为 Publisher 接口
+type PublisherFunc func(ctx context.Context, event *Event) error
+
+// Publish calls fn(ctx, event)
+// Publish 调用 fn(ctx, event)
+func (fn PublisherFunc) Publish(ctx context.Context, event *Event) error {
+	return fn(ctx, event)
+}
+
+// Append inserts the events into the outbox using the transaction of db
+// The events commit or roll back together with the business write done in the same transaction
+// Returns ErrNotInTransaction when db is not in a transaction
+//
+// Append 使用 db 所在的事务将事件插入发件箱
+// 事件与同一事务中的业务写入一起提交或回滚
+// 当 db 不处于事务中时返回 ErrNotInTransaction
+func Append(db *gorm.DB, events ...*Event) error {
+	if !gormrepo.InTransaction(db) {
+		return errors.WithStack(ErrNotInTransaction)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return repo.Repo(db).Creates(events)
+}
+
+// repo is the repo of the outbox table
+// repo 是发件箱表的仓储
+var repo = gormrepo.NewBaseRepo(&Event{}, (&Event{}).Columns())
+
+// Relay reads pending events in ID order, hands them to the publisher and marks them delivered
+// An event is marked only after Publish succeeds, a crash in between publishes it again on the next round
+//
+// Relay 按 ID 顺序读取待投递事件，交给发布者并标记为已投递
+// 事件仅在 Publish 成功后标记，二者之间的崩溃会使其在下一轮再次发布
+type Relay struct {
+	publisher Publisher
+	batchSize int
+	clock     func() time.Time
+	wake      chan struct{}
+}
+
+// NewRelay creates a Relay handing events to publisher in batches of 100
+// NewRelay 创建以每批 100 个事件交给 publisher 的 Relay
+func NewRelay(publisher Publisher) *Relay {
+	return &Relay{
+		publisher: publisher,
+		batchSize: 100,
+		clock:     time.Now,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// WithBatchSize sets the count of events read in one round
+// WithBatchSize 设置每轮读取的事件数量
+func (relay *Relay) WithBatchSize(batchSize int) *Relay {
+	relay.batchSize = batchSize
+	return relay
+}
+
+// WithClock sets the time source of DeliveredAt
+// WithClock 设置 DeliveredAt 的时间来源
+func (relay *Relay) WithClock(clock func() time.Time) *Relay {
+	relay.clock = clock
+	return relay
+}
+
+// RelayOnce publishes one batch of pending events in ID order and returns the count delivered
+// Stops at the first failing event to keep the order, recording its attempts and error, and returns the error
+// Marking is conditioned on the event still pending, so concurrent relays never fail on each other's marks
+//
+// RelayOnce 按 ID 顺序发布一批待投递事件，返回投递成功的数量
+// 在第一个失败的事件处停止以保持顺序，记录其尝试次数和错误，并返回该错误
+// 标记以事件仍待投递为条件，因此并发的中继不会因彼此的标记而失败
+func (relay *Relay) RelayOnce(ctx context.Context, db *gorm.DB) (int, error) {
+	outbox := repo.Repo(db.WithContext(ctx))
+	events, err := outbox.FindN(func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db.Where(cls.DeliveredAt.IsNULL()).Order(cls.ID.Ob("asc").Ox())
+	}, relay.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	for _, event := range events {
+		if err := relay.publisher.Publish(ctx, event); err != nil {
+			if erx := outbox.Updates(func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+				return db.Where(cls.ID.Eq(event.ID))
+			}, func(cls *EventColumns) map[string]interface{} {
+				return cls.Kw(cls.Attempts.KeAdd(1)).Kw(cls.LastError.Kv(err.Error())).AsMap()
+			}); erx != nil {
+				return count, erx
+			}
+			return count, errors.WithMessagef(err, "publish event %d", event.ID)
+		}
+		if err := relay.markDelivered(outbox, event); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// markDelivered sets DeliveredAt of the event when it is still pending
+// Marking an event already marked by another relay updates no row and is not an error
+//
+// markDelivered 在事件仍待投递时设置其 DeliveredAt
+// 标记已被其他中继标记的事件不会更新任何行，也不视为错误
+func (relay *Relay) markDelivered(outbox *gormrepo.GormRepo[Event, *EventColumns], event *Event) error {
+	deliveredAt := relay.clock()
+	if err := outbox.Updates(func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db.Where(cls.ID.Eq(event.ID)).Where(cls.DeliveredAt.IsNULL())
+	}, func(cls *EventColumns) map[string]interface{} {
+		return cls.Kw(cls.DeliveredAt.Kv(&deliveredAt)).Kw(cls.Attempts.KeAdd(1)).AsMap()
+	}); err != nil {
+		return errors.WithMessagef(err, "mark event %d delivered", event.ID)
+	}
+	event.DeliveredAt = &deliveredAt
+	return nil
+}
+
+// Wake asks a running Run loop to relay at once instead of waiting for the next tick
+// Register it with OnCommit to deliver the events right after the appending transaction commits
+//
+// Wake 请求正在运行的 Run 循环立即中继，而不是等待下一个周期
+// 将其注册到 OnCommit，可在追加事件的事务提交后立即投递
+func (relay *Relay) Wake() {
+	select {
+	case relay.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays pending events every interval, and on Wake, until ctx is done
+// A round drains the outbox batch by batch, a failing round is retried on the next tick
+// Returns the error of ctx once it is done
+//
+// Run 每隔 interval 以及在 Wake 时中继待投递事件，直到 ctx 结束
+// 每一轮逐批清空发件箱，失败的一轮在下一个周期重试
+// ctx 结束后返回 ctx 的错误
+func (relay *Relay) Run(ctx context.Context, db *gorm.DB, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			count, err := relay.RelayOnce(ctx, db)
+			if err != nil || count < relay.batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-relay.wake:
+		}
+	}
+}
+
+// Purge deletes the events delivered before the given time and returns the count deleted
+// Purge 删除在给定时间之前投递的事件，返回删除的数量
+func Purge(ctx context.Context, db *gorm.DB, before time.Time) (int64, error) {
+	return repo.Repo(db.WithContext(ctx)).DeleteWR(func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db.Where(cls.DeliveredAt.Lt(&before))
+	})
+}
@@ -0,0 +1,194 @@
+package gormoutbox_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormoutbox"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"gorm.io/gorm"
+)
+
+// recorder is a publisher remembering the payloads it was given
+// recorder 是记录收到的负载的发布者
+type recorder struct {
+	mutex    sync.Mutex
+	payloads []string
+	failOnce map[string]bool
+}
+
+func (r *recorder) Publish(ctx context.Context, event *gormoutbox.Event) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.failOnce[string(event.Payload)] {
+		delete(r.failOnce, string(event.Payload))
+		return errors.New("broker unavailable")
+	}
+	r.payloads = append(r.payloads, string(event.Payload))
+	return nil
+}
+
+func (r *recorder) Payloads() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.payloads...)
+}
+
+func appendEvents(t *testing.T, db *gorm.DB, payloads ...string) {
+	require.NoError(t, gormrepo.Transaction(context.Background(), db, func(uow *gormrepo.UnitOfWork) error {
+		for _, payload := range payloads {
+			if err := gormoutbox.Append(uow.DB(), &gormoutbox.Event{Topic: "orders", Key: "order-1", Payload: []byte(payload)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func TestAppend(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &gormoutbox.Event{})
+	ctx := context.Background()
+
+	require.ErrorIs(t, gormoutbox.Append(db, &gormoutbox.Event{Topic: "orders", Payload: []byte("a")}), gormoutbox.ErrNotInTransaction)
+
+	// The events roll back together with the transaction
+	// 事件与事务一起回滚
+	require.Error(t, gormrepo.Transaction(ctx, db, func(uow *gormrepo.UnitOfWork) error {
+		require.NoError(t, gormoutbox.Append(uow.DB(), &gormoutbox.Event{Topic: "orders", Payload: []byte("a")}))
+		return errors.New("business write failed")
+	}))
+	var count int64
+	require.NoError(t, db.Model(&gormoutbox.Event{}).Count(&count).Error)
+	require.Equal(t, int64(0), count)
+
+	appendEvents(t, db, "a", "b")
+	require.NoError(t, db.Model(&gormoutbox.Event{}).Count(&count).Error)
+	require.Equal(t, int64(2), count)
+}
+
+func TestRelay_RelayOnce(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &gormoutbox.Event{})
+	ctx := context.Background()
+
+	appendEvents(t, db, "a", "b", "c")
+
+	publisher := &recorder{failOnce: map[string]bool{"b": true}}
+	relay := gormoutbox.NewRelay(publisher)
+
+	// Stops at the failing event to keep the order
+	// 在失败的事件处停止以保持顺序
+	count, err := relay.RelayOnce(ctx, db)
+	require.Error(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, []string{"a"}, publisher.Payloads())
+
+	var failed gormoutbox.Event
+	require.NoError(t, db.Where("payload = ?", []byte("b")).First(&failed).Error)
+	require.Nil(t, failed.DeliveredAt)
+	require.Equal(t, 1, failed.Attempts)
+	require.Equal(t, "broker unavailable", failed.LastError)
+
+	count, err = relay.RelayOnce(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.Equal(t, []string{"a", "b", "c"}, publisher.Payloads())
+
+	count, err = relay.RelayOnce(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
+
+func TestRelay_ConcurrentMark(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &gormoutbox.Event{})
+	ctx := context.Background()
+
+	appendEvents(t, db, "a")
+
+	// Another relay delivers and marks the event while the first one is publishing it
+	// 在第一个中继发布事件期间，另一个中继投递并标记了该事件
+	other := &recorder{}
+	var once sync.Once
+	relay := gormoutbox.NewRelay(gormoutbox.PublisherFunc(func(ctx context.Context, event *gormoutbox.Event) error {
+		var err error
+		once.Do(func() {
+			_, err = gormoutbox.NewRelay(other).RelayOnce(ctx, db)
+		})
+		return err
+	}))
+
+	count, err := relay.RelayOnce(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, []string{"a"}, other.Payloads())
+
+	var event gormoutbox.Event
+	require.NoError(t, db.First(&event).Error)
+	require.NotNil(t, event.DeliveredAt)
+	require.Equal(t, 1, event.Attempts)
+}
+
+func TestRelay_Run(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &gormoutbox.Event{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publisher := &recorder{}
+	relay := gormoutbox.NewRelay(publisher).WithBatchSize(1)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- relay.Run(ctx, db, time.Hour)
+	}()
+
+	// Wake the relay once the appending transaction commits
+	// 在追加事件的事务提交后唤醒中继
+	require.NoError(t, gormrepo.Transaction(ctx, db, func(uow *gormrepo.UnitOfWork) error {
+		if err := gormoutbox.Append(uow.DB(),
+			&gormoutbox.Event{Topic: "orders", Payload: []byte("a")},
+			&gormoutbox.Event{Topic: "orders", Payload: []byte("b")},
+		); err != nil {
+			return err
+		}
+		return uow.OnCommit(relay.Wake)
+	}))
+
+	require.Eventually(t, func() bool {
+		return len(publisher.Payloads()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"a", "b"}, publisher.Payloads())
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestPurge(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &gormoutbox.Event{})
+	ctx := context.Background()
+
+	appendEvents(t, db, "a", "b")
+	appendEvents(t, db, "c")
+
+	deliveredAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	relay := gormoutbox.NewRelay(&recorder{}).WithBatchSize(2).WithClock(func() time.Time {
+		return deliveredAt
+	})
+	count, err := relay.RelayOnce(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	purged, err := gormoutbox.Purge(ctx, db, deliveredAt.Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), purged)
+
+	// The pending event stays
+	// 待投递的事件保留
+	var events []*gormoutbox.Event
+	require.NoError(t, db.Find(&events).Error)
+	require.Len(t, events, 1)
+	require.Equal(t, "c", string(events[0].Payload))
+}
@@ -0,0 +1,30 @@
+package gormoutbox_test
+
+import (
+	"testing"
+
+	"github.com/yyle88/gormcngen"
+	"github.com/yyle88/gormrepo/gormoutbox"
+	"github.com/yyle88/osexistpath/osmustexist"
+	"github.com/yyle88/runpath/runtestpath"
+)
+
+//go:generate go test -v -run TestGenerateColumns
+func TestGenerateColumns(t *testing.T) {
+	// Retrieve the absolute path of the source file based on current test file location
+	// 根据当前测试文件位置获取源文件的绝对路径
+	absPath := osmustexist.FILE(runtestpath.SrcPath(t))
+	t.Log(absPath)
+
+	objects := []any{
+		&gormoutbox.Event{},
+	}
+
+	options := gormcngen.NewOptions().
+		WithColumnClassExportable(true). // Generate exportable column class names like EventColumns // 生成可导出的列类名称如 EventColumns
+		WithColumnsMethodRecvName("c").  // Set receiver name for column methods // 设置列方法的接收器名称
+		WithColumnsCheckFieldType(true)  // Enable field type checking for type safe // 启用字段类型检查以获得更好的类型安全
+
+	cfg := gormcngen.NewConfigs(objects, options, absPath)
+	cfg.Gen() // Generate code to "gormcnm.gen.go" file // 生成代码到 "gormcnm.gen.go" 文件
+}
@@ -0,0 +1,25 @@
+package gormoutbox
+
+import "time"
+
+// Event is a domain event row in the outbox table
+// The auto-increment ID gives the delivery order, DeliveredAt stays nil until the relay publishes the event
+//
+// Event 是发件箱表中的领域事件行
+// 自增 ID 决定投递顺序，DeliveredAt 在中继发布事件之前保持为 nil
+type Event struct {
+	ID          uint64     `gorm:"primaryKey"`
+	Topic       string     `gorm:"type:varchar(255);not null"`
+	Key         string     `gorm:"column:event_key;type:varchar(255)"` // "key" is reserved in MySQL // "key" 是 MySQL 保留字
+	Payload     []byte     `gorm:"not null"`
+	CreatedAt   time.Time  `gorm:"not null"`
+	DeliveredAt *time.Time `gorm:"index"`
+	Attempts    int        `gorm:"not null;default:0"`
+	LastError   string     `gorm:"type:text"`
+}
+
+// TableName returns the outbox table name
+// TableName 返回发件箱表名
+func (*Event) TableName() string {
+	return "outbox_events"
+}
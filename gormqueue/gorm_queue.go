@@ -0,0 +1,390 @@
+// Package gormqueue provides a database-backed job queue on top of GormRepo
+// Jobs are rows of any model whose status, run_at, attempts and lease columns are mapped through its CLS
+//
+// gormqueue 在 GormRepo 之上提供基于数据库的任务队列
+// 任务是任意模型的行，其状态、运行时间、尝试次数和租约列通过 CLS 映射
+package gormqueue
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormrepo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Job statuses stored in the status column
+// 存储在状态列中的任务状态
+const (
+	StatusPending = "pending" // Waiting until run_at // 等待到达 run_at
+	StatusRunning = "running" // Claimed and leased by a worker // 已被工作者认领并持有租约
+	StatusDone    = "done"    // Acked // 已确认完成
+	StatusDead    = "dead"    // Dead-lettered after max attempts // 达到最大尝试次数后进入死信
+)
+
+// ErrLeaseLost is returned by Ack and Nack when the job is no longer held by the caller
+// The lease expired and the job was recovered, or another worker claimed it meanwhile
+//
+// ErrLeaseLost 在任务不再由调用方持有时由 Ack 和 Nack 返回
+// 租约已过期且任务被回收，或者期间被其他工作者认领
+var ErrLeaseLost = errors.New("gormqueue: lease lost")
+
+// Columns names the queue columns of the model
+// Status is a string column, RunAt and LeaseUntil are time columns, Attempts is an integer column
+//
+// Columns 指定模型的队列列
+// Status 为字符串列，RunAt 和 LeaseUntil 为时间列，Attempts 为整数列
+type Columns struct {
+	Status     gormrepo.ColumnNameFace // Job status // 任务状态
+	RunAt      gormrepo.ColumnNameFace // Earliest time to run // 最早运行时间
+	Attempts   gormrepo.ColumnNameFace // Count of claims // 认领次数
+	LeaseUntil gormrepo.ColumnNameFace // Lease expiry of the running job // 运行中任务的租约到期时间
+}
+
+// QueueColumnsFace is implemented by the column struct (CLS) of a job model
+// QueueColumnsFace 由任务模型的列结构体（CLS）实现
+type QueueColumnsFace interface {
+	QueueColumns() *Columns
+}
+
+// Queue claims and settles jobs stored as rows of MOD
+// Queue 认领和处理存储为 MOD 行的任务
+type Queue[MOD any, CLS QueueColumnsFace] struct {
+	repo          *gormrepo.BaseRepo[MOD, CLS]
+	columns       *Columns
+	maxAttempts   int
+	leaseDuration time.Duration
+	backoff       func(attempts int) time.Duration
+	clock         func() time.Time
+}
+
+// NewQueue creates a Queue with 5 max attempts, 5 minutes lease and exponential backoff from 1s to 1h
+// The MOD param is used to deduce the type, its value is not used
+//
+// NewQueue 创建最大尝试 5 次、租约 5 分钟、退避时间从 1s 指数增长到 1h 的 Queue
+// MOD 参数用于类型推断，其值不使用
+func NewQueue[MOD any, CLS QueueColumnsFace](_ *MOD, cls CLS) *Queue[MOD, CLS] {
+	return &Queue[MOD, CLS]{
+		repo:          gormrepo.NewBaseRepo((*MOD)(nil), cls),
+		columns:       cls.QueueColumns(),
+		maxAttempts:   5,
+		leaseDuration: 5 * time.Minute,
+		backoff:       ExponentialBackoff(time.Second, time.Hour),
+		clock:         time.Now,
+	}
+}
+
+// WithMaxAttempts sets the attempts after which a failing job is dead-lettered
+// WithMaxAttempts 设置失败任务进入死信前的尝试次数
+func (queue *Queue[MOD, CLS]) WithMaxAttempts(maxAttempts int) *Queue[MOD, CLS] {
+	queue.maxAttempts = maxAttempts
+	return queue
+}
+
+// WithLeaseDuration sets how long a claimed job stays leased before it can be recovered
+// WithLeaseDuration 设置认领的任务在可被回收前持有租约的时长
+func (queue *Queue[MOD, CLS]) WithLeaseDuration(leaseDuration time.Duration) *Queue[MOD, CLS] {
+	queue.leaseDuration = leaseDuration
+	return queue
+}
+
+// WithBackoff sets the delay before retrying a nacked job, given the attempts so far
+// WithBackoff 设置被 Nack 的任务重试前的等待时间，参数为目前的尝试次数
+func (queue *Queue[MOD, CLS]) WithBackoff(backoff func(attempts int) time.Duration) *Queue[MOD, CLS] {
+	queue.backoff = backoff
+	return queue
+}
+
+// WithClock sets the time source, enabling tests to move time forward
+// WithClock 设置时间来源，使测试可以推进时间
+func (queue *Queue[MOD, CLS]) WithClock(clock func() time.Time) *Queue[MOD, CLS] {
+	queue.clock = clock
+	return queue
+}
+
+// ExponentialBackoff returns a backoff doubling from base on each attempt, capped at limit
+// ExponentialBackoff 返回从 base 开始每次尝试翻倍、以 limit 为上限的退避函数
+func ExponentialBackoff(base time.Duration, limit time.Duration) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		delay := base
+		for idx := 1; idx < attempts && delay < limit; idx++ {
+			delay *= 2
+		}
+		return min(delay, limit)
+	}
+}
+
+// Enqueue inserts the job as pending, to run at its run_at, or now when run_at is zero
+// Enqueue 将任务作为待处理插入，在其 run_at 运行，run_at 为零值时立即运行
+func (queue *Queue[MOD, CLS]) Enqueue(ctx context.Context, db *gorm.DB, job *MOD) error {
+	db = db.WithContext(ctx)
+	fields, err := queue.fields(db)
+	if err != nil {
+		return err
+	}
+	rv := reflect.Indirect(reflect.ValueOf(job))
+	if err := fields.status.Set(ctx, rv, StatusPending); err != nil {
+		return errors.WithMessage(err, "set status")
+	}
+	if err := fields.attempts.Set(ctx, rv, 0); err != nil {
+		return errors.WithMessage(err, "set attempts")
+	}
+	if _, isZero := fields.runAt.ValueOf(ctx, rv); isZero {
+		if err := fields.runAt.Set(ctx, rv, queue.clock()); err != nil {
+			return errors.WithMessage(err, "set run_at")
+		}
+	}
+	return queue.repo.Repo(db).Create(job)
+}
+
+// Claim leases up to n runnable jobs to the caller, ordered by run_at
+// Expired leases are recovered first: back to pending, or dead when out of attempts
+// Uses SELECT ... FOR UPDATE SKIP LOCKED on Postgres and MySQL, and an atomic compare-and-set update per job on SQLite
+// Each claim increments attempts and sets lease_until, the returned jobs carry the new values
+//
+// Claim 按 run_at 顺序为调用方租借最多 n 个可运行的任务
+// 首先回收过期的租约：回到待处理，或尝试次数用尽时进入死信
+// 在 Postgres 和 MySQL 上使用 SELECT ... FOR UPDATE SKIP LOCKED，在 SQLite 上对每个任务使用原子的比较并设置更新
+// 每次认领都会递增 attempts 并设置 lease_until，返回的任务携带新值
+func (queue *Queue[MOD, CLS]) Claim(ctx context.Context, db *gorm.DB, n int) ([]*MOD, error) {
+	db = db.WithContext(ctx)
+	if _, err := queue.Recover(ctx, db); err != nil {
+		return nil, err
+	}
+	fields, err := queue.fields(db)
+	if err != nil {
+		return nil, err
+	}
+	now := queue.clock()
+	runnable := func(db *gorm.DB) *gorm.DB {
+		return db.Where(queue.columns.Status.Name()+" = ?", StatusPending).
+			Where(queue.columns.RunAt.Name()+" <= ?", now)
+	}
+	claim := map[string]interface{}{
+		fields.status.DBName:     StatusRunning,
+		fields.attempts.DBName:   gorm.Expr(queue.columns.Attempts.Name()+" + ?", 1),
+		fields.leaseUntil.DBName: now.Add(queue.leaseDuration),
+	}
+
+	var ids []interface{}
+	if supportsSkipLocked(db) {
+		err = queue.repo.Repo(db).Transaction(func(repo *gormrepo.GormRepo[MOD, CLS]) error {
+			jobs, err := repo.ForUpdate().SkipLocked().FindN(func(db *gorm.DB, cls CLS) *gorm.DB {
+				return runnable(db).Order(queue.columns.RunAt.Name())
+			}, n)
+			if err != nil {
+				return err
+			}
+			if ids = primaryKeys(ctx, fields.primary, jobs); len(ids) == 0 {
+				return nil
+			}
+			return repo.Updates(func(db *gorm.DB, cls CLS) *gorm.DB {
+				return db.Where(map[string]interface{}{fields.primary.DBName: ids})
+			}, func(cls CLS) map[string]interface{} {
+				return claim
+			})
+		})
+	} else {
+		ids, err = queue.claimEach(db, fields, runnable, claim, n)
+	}
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return queue.repo.Repo(db).Find(func(db *gorm.DB, cls CLS) *gorm.DB {
+		return db.Where(map[string]interface{}{fields.primary.DBName: ids}).Order(queue.columns.RunAt.Name())
+	})
+}
+
+// claimEach claims the candidates one by one with an update conditioned on the job still being runnable
+// A candidate taken by a concurrent worker updates no row and is skipped
+//
+// claimEach 逐个认领候选任务，更新条件为任务仍可运行
+// 被并发工作者取走的候选任务不会更新任何行并被跳过
+func (queue *Queue[MOD, CLS]) claimEach(db *gorm.DB, fields *queueFields, runnable func(db *gorm.DB) *gorm.DB, claim map[string]interface{}, n int) ([]interface{}, error) {
+	repo := queue.repo.Repo(db)
+	jobs, err := repo.FindN(func(db *gorm.DB, cls CLS) *gorm.DB {
+		return runnable(db).Order(queue.columns.RunAt.Name())
+	}, n)
+	if err != nil {
+		return nil, err
+	}
+	var ids []interface{}
+	for _, id := range primaryKeys(db.Statement.Context, fields.primary, jobs) {
+		count, err := repo.UpdatesR(func(db *gorm.DB, cls CLS) *gorm.DB {
+			return runnable(db.Where(fields.primary.DBName+" = ?", id))
+		}, func(cls CLS) map[string]interface{} {
+			return claim
+		})
+		if err != nil {
+			return nil, err
+		}
+		if count == 1 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Ack marks the claimed job as done
+// Returns ErrLeaseLost when the job is no longer held by the caller
+//
+// Ack 将认领的任务标记为完成
+// 当任务不再由调用方持有时返回 ErrLeaseLost
+func (queue *Queue[MOD, CLS]) Ack(ctx context.Context, db *gorm.DB, job *MOD) error {
+	return queue.settle(db.WithContext(ctx), job, func(fields *queueFields, attempts int64) map[string]interface{} {
+		return map[string]interface{}{fields.status.DBName: StatusDone}
+	})
+}
+
+// Nack releases the claimed job to run again after the backoff, or dead-letters it when out of attempts
+// Returns ErrLeaseLost when the job is no longer held by the caller
+//
+// Nack 释放认领的任务，使其在退避时间后再次运行，尝试次数用尽时将其转入死信
+// 当任务不再由调用方持有时返回 ErrLeaseLost
+func (queue *Queue[MOD, CLS]) Nack(ctx context.Context, db *gorm.DB, job *MOD) error {
+	return queue.settle(db.WithContext(ctx), job, func(fields *queueFields, attempts int64) map[string]interface{} {
+		if attempts >= int64(queue.maxAttempts) {
+			return map[string]interface{}{fields.status.DBName: StatusDead}
+		}
+		return map[string]interface{}{
+			fields.status.DBName: StatusPending,
+			fields.runAt.DBName:  queue.clock().Add(queue.backoff(int(attempts))),
+		}
+	})
+}
+
+// settle updates the job held by the caller, matching status running and the attempts of the claim
+// A recovered and re-claimed job has more attempts, so the stale holder gets ErrLeaseLost
+//
+// settle 更新调用方持有的任务，匹配运行中状态以及认领时的尝试次数
+// 被回收并重新认领的任务尝试次数更多，因此过期的持有者会得到 ErrLeaseLost
+func (queue *Queue[MOD, CLS]) settle(db *gorm.DB, job *MOD, values func(fields *queueFields, attempts int64) map[string]interface{}) error {
+	fields, err := queue.fields(db)
+	if err != nil {
+		return err
+	}
+	rv := reflect.Indirect(reflect.ValueOf(job))
+	id, _ := fields.primary.ValueOf(db.Statement.Context, rv)
+	attempts, _ := fields.attempts.ValueOf(db.Statement.Context, rv)
+	count, err := queue.repo.Repo(db).UpdatesR(func(db *gorm.DB, cls CLS) *gorm.DB {
+		return db.Where(fields.primary.DBName+" = ?", id).
+			Where(queue.columns.Status.Name()+" = ?", StatusRunning).
+			Where(queue.columns.Attempts.Name()+" = ?", attempts)
+	}, func(cls CLS) map[string]interface{} {
+		return values(fields, reflect.ValueOf(attempts).Convert(reflect.TypeOf(int64(0))).Int())
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.WithStack(ErrLeaseLost)
+	}
+	return nil
+}
+
+// Recover releases running jobs whose lease expired, back to pending, or dead when out of attempts
+// Claim calls it first, call it directly to recover without claiming
+//
+// Recover 释放租约已过期的运行中任务，回到待处理，或尝试次数用尽时进入死信
+// Claim 会首先调用它，也可以直接调用以在不认领的情况下回收
+func (queue *Queue[MOD, CLS]) Recover(ctx context.Context, db *gorm.DB) (int64, error) {
+	db = db.WithContext(ctx)
+	fields, err := queue.fields(db)
+	if err != nil {
+		return 0, err
+	}
+	repo := queue.repo.Repo(db)
+	now := queue.clock()
+	expired := func(db *gorm.DB) *gorm.DB {
+		return db.Where(queue.columns.Status.Name()+" = ?", StatusRunning).
+			Where(queue.columns.LeaseUntil.Name()+" < ?", now)
+	}
+	dead, err := repo.UpdatesR(func(db *gorm.DB, cls CLS) *gorm.DB {
+		return expired(db).Where(queue.columns.Attempts.Name()+" >= ?", queue.maxAttempts)
+	}, func(cls CLS) map[string]interface{} {
+		return map[string]interface{}{fields.status.DBName: StatusDead}
+	})
+	if err != nil {
+		return 0, err
+	}
+	pending, err := repo.UpdatesR(func(db *gorm.DB, cls CLS) *gorm.DB {
+		return expired(db)
+	}, func(cls CLS) map[string]interface{} {
+		return map[string]interface{}{fields.status.DBName: StatusPending}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return dead + pending, nil
+}
+
+// queueFields holds the schema fields of the queue columns
+// queueFields 保存队列列的模式字段
+type queueFields struct {
+	primary    *schema.Field
+	status     *schema.Field
+	runAt      *schema.Field
+	attempts   *schema.Field
+	leaseUntil *schema.Field
+}
+
+func (queue *Queue[MOD, CLS]) fields(db *gorm.DB) (*queueFields, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(MOD)); err != nil {
+		return nil, errors.WithMessage(err, "parse job schema")
+	}
+	sch := stmt.Schema
+	if sch.PrioritizedPrimaryField == nil {
+		return nil, errors.Errorf("job table %s has no primary key", sch.Table)
+	}
+	res := &queueFields{primary: sch.PrioritizedPrimaryField}
+	for _, item := range []struct {
+		column gormrepo.ColumnNameFace
+		target **schema.Field
+	}{
+		{queue.columns.Status, &res.status},
+		{queue.columns.RunAt, &res.runAt},
+		{queue.columns.Attempts, &res.attempts},
+		{queue.columns.LeaseUntil, &res.leaseUntil},
+	} {
+		field := sch.LookUpField(item.column.Name())
+		if field == nil {
+			return nil, errors.Errorf("column %s not found in job table %s", item.column.Name(), sch.Table)
+		}
+		*item.target = field
+	}
+	// Attempts is compared and converted as an integer when settling the jobs
+	// 处理任务时 Attempts 作为整数比较和转换
+	switch res.attempts.FieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return nil, errors.Errorf("attempts column %s of job table %s is %s, not an integer", res.attempts.DBName, sch.Table, res.attempts.FieldType)
+	}
+	return res, nil
+}
+
+// primaryKeys returns the primary key values of the jobs
+// primaryKeys 返回任务的主键值
+func primaryKeys[MOD any](ctx context.Context, field *schema.Field, jobs []*MOD) []interface{} {
+	var ids = make([]interface{}, 0, len(jobs))
+	for _, job := range jobs {
+		id, _ := field.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(job)))
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// supportsSkipLocked reports whether the dialect supports SELECT ... FOR UPDATE SKIP LOCKED
+// supportsSkipLocked 判断方言是否支持 SELECT ... FOR UPDATE SKIP LOCKED
+func supportsSkipLocked(db *gorm.DB) bool {
+	switch db.Dialector.Name() {
+	case "postgres", "mysql":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,278 @@
+package gormqueue_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcngen"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo/gormclass"
+	"github.com/yyle88/gormrepo/gormqueue"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"github.com/yyle88/osexistpath/osmustexist"
+	"github.com/yyle88/runpath"
+)
+
+type Job struct {
+	ID         uint
+	Payload    string
+	Status     string `gorm:"index"`
+	RunAt      time.Time
+	Attempts   int
+	LeaseUntil time.Time
+}
+
+func (*Job) TableName() string {
+	return "jobs"
+}
+
+// QueueColumns maps the queue columns of Job
+// QueueColumns 映射 Job 的队列列
+func (c *JobColumns) QueueColumns() *gormqueue.Columns {
+	return &gormqueue.Columns{
+		Status:     c.Status,
+		RunAt:      c.RunAt,
+		Attempts:   c.Attempts,
+		LeaseUntil: c.LeaseUntil,
+	}
+}
+
+// Tests the generation of columns for models.
+// 测试模型列的生成。
+func TestGenerateColumns(t *testing.T) {
+	absPath := runpath.Path() // Retrieve the absolute path of the source file based on the current test file's location
+	// 获取当前测试文件位置基础上的源文件绝对路径
+	t.Log(absPath)
+
+	// Check the existence of the target file. The file should be created beforehand to ensure it can be located via the code.
+	// 检查目标文件是否存在。文件应手动创建，确保代码能够找到它。
+	require.True(t, osmustexist.IsFile(absPath))
+
+	// List the models to have columns generated. Both instance and non-instance types are supported.
+	// 设置需要生成列的模型，这里支持指针类型和非指针类型。
+	objects := []any{&Job{}}
+
+	options := gormcngen.NewOptions().
+		WithColumnClassExportable(true). // Generate exportable struct names (e.g., ExampleColumns) // 生成可导出的结构体名称（例如 ExampleColumns）
+		WithColumnsMethodRecvName("a").
+		WithColumnsCheckFieldType(true)
+
+	// Configure code generation settings
+	// 配置代码生成设置
+	cfg := gormcngen.NewConfigs(objects, options, absPath).
+		WithIsGenPreventEdit(false)
+	cfg.Gen() // Generate and write the code to the target location (e.g., "gormcnm.gen.go") // 生成并将代码写入目标位置（例如 "gormcnm.gen.go"）
+}
+
+// testClock is a movable time source
+// testClock 是可以推进的时间来源
+type testClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestQueue_ClaimAck(t *testing.T) {
+	db := tests.NewConcurrentMemDB(t, &Job{})
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	queue := gormqueue.NewQueue(gormclass.Use(&Job{})).WithClock(clock.Now)
+
+	for idx := 0; idx < 3; idx++ {
+		require.NoError(t, queue.Enqueue(ctx, db, &Job{Payload: fmt.Sprintf("job-%d", idx)}))
+	}
+	// Scheduled in the future, not claimable yet
+	// 计划在未来运行，暂时不可认领
+	require.NoError(t, queue.Enqueue(ctx, db, &Job{Payload: "later", RunAt: clock.Now().Add(time.Hour)}))
+
+	jobs, err := queue.Claim(ctx, db, 2)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	for _, job := range jobs {
+		require.Equal(t, gormqueue.StatusRunning, job.Status)
+		require.Equal(t, 1, job.Attempts)
+		require.NoError(t, queue.Ack(ctx, db, job))
+	}
+
+	jobs, err = queue.Claim(ctx, db, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.NoError(t, queue.Ack(ctx, db, jobs[0]))
+
+	jobs, err = queue.Claim(ctx, db, 10)
+	require.NoError(t, err)
+	require.Empty(t, jobs)
+
+	clock.Add(time.Hour)
+	jobs, err = queue.Claim(ctx, db, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "later", jobs[0].Payload)
+}
+
+func TestQueue_NackDeadLetter(t *testing.T) {
+	db := tests.NewConcurrentMemDB(t, &Job{})
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	queue := gormqueue.NewQueue(gormclass.Use(&Job{})).
+		WithClock(clock.Now).
+		WithMaxAttempts(2).
+		WithBackoff(gormqueue.ExponentialBackoff(time.Minute, time.Hour))
+
+	require.NoError(t, queue.Enqueue(ctx, db, &Job{Payload: "flaky"}))
+
+	jobs, err := queue.Claim(ctx, db, 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.NoError(t, queue.Nack(ctx, db, jobs[0]))
+
+	// Backing off, not claimable until the delay passes
+	// 正在退避，等待时间过去之前不可认领
+	jobs, err = queue.Claim(ctx, db, 1)
+	require.NoError(t, err)
+	require.Empty(t, jobs)
+
+	clock.Add(time.Minute)
+	jobs, err = queue.Claim(ctx, db, 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, 2, jobs[0].Attempts)
+	require.NoError(t, queue.Nack(ctx, db, jobs[0]))
+
+	var job Job
+	require.NoError(t, db.First(&job, jobs[0].ID).Error)
+	require.Equal(t, gormqueue.StatusDead, job.Status)
+}
+
+func TestQueue_LeaseExpiry(t *testing.T) {
+	db := tests.NewConcurrentMemDB(t, &Job{})
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	queue := gormqueue.NewQueue(gormclass.Use(&Job{})).
+		WithClock(clock.Now).
+		WithLeaseDuration(time.Minute)
+
+	require.NoError(t, queue.Enqueue(ctx, db, &Job{Payload: "slow"}))
+
+	stale, err := queue.Claim(ctx, db, 1)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+
+	// The worker dies, the lease expires and another worker takes the job
+	// 工作者崩溃，租约过期后由另一个工作者接手任务
+	clock.Add(2 * time.Minute)
+	jobs, err := queue.Claim(ctx, db, 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, 2, jobs[0].Attempts)
+
+	require.ErrorIs(t, queue.Ack(ctx, db, stale[0]), gormqueue.ErrLeaseLost)
+	require.NoError(t, queue.Ack(ctx, db, jobs[0]))
+}
+
+func TestQueue_ClaimConcurrent(t *testing.T) {
+	db := tests.NewConcurrentMemDB(t, &Job{})
+	ctx := context.Background()
+	queue := gormqueue.NewQueue(gormclass.Use(&Job{}))
+
+	for idx := 0; idx < 20; idx++ {
+		require.NoError(t, queue.Enqueue(ctx, db, &Job{Payload: fmt.Sprintf("job-%d", idx)}))
+	}
+
+	var mutex sync.Mutex
+	var claimed = map[uint]int{}
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				jobs, err := queue.Claim(ctx, db, 3)
+				must.Done(err)
+				if len(jobs) == 0 {
+					return
+				}
+				mutex.Lock()
+				for _, job := range jobs {
+					claimed[job.ID]++
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, claimed, 20)
+	for _, count := range claimed {
+		require.Equal(t, 1, count)
+	}
+}
+
+// textAttemptsColumns maps the attempts of the queue to the text column Payload
+// textAttemptsColumns 将队列的尝试次数映射到文本列 Payload
+type textAttemptsColumns struct {
+	*JobColumns
+}
+
+func (c *textAttemptsColumns) QueueColumns() *gormqueue.Columns {
+	columns := c.JobColumns.QueueColumns()
+	columns.Attempts = c.Payload
+	return columns
+}
+
+func TestQueue_AttemptsKind(t *testing.T) {
+	db := tests.NewConcurrentMemDB(t, &Job{})
+	ctx := context.Background()
+	queue := gormqueue.NewQueue(&Job{}, &textAttemptsColumns{JobColumns: (&Job{}).Columns()})
+
+	// A non-integer attempts column is rejected before any job is written
+	// 非整数的尝试次数列在写入任何任务之前被拒绝
+	require.ErrorContains(t, queue.Enqueue(ctx, db, &Job{Payload: "job"}), "not an integer")
+	_, err := queue.Claim(ctx, db, 1)
+	require.ErrorContains(t, err, "not an integer")
+	require.ErrorContains(t, queue.Ack(ctx, db, &Job{ID: 1, Payload: "job"}), "not an integer")
+
+	var count int64
+	require.NoError(t, db.Model(&Job{}).Count(&count).Error)
+	require.Zero(t, count)
+}
+
+func (a *Job) Columns() *JobColumns {
+	return &JobColumns{
+		// Auto-generated: column names and types mapping. DO NOT EDIT. // 自动生成：列名和类型映射。请勿编辑。
+		ID:         gormcnm.Cnm(a.ID, "id"),
+		Payload:    gormcnm.Cnm(a.Payload, "payload"),
+		Status:     gormcnm.Cnm(a.Status, "status"),
+		RunAt:      gormcnm.Cnm(a.RunAt, "run_at"),
+		Attempts:   gormcnm.Cnm(a.Attempts, "attempts"),
+		LeaseUntil: gormcnm.Cnm(a.LeaseUntil, "lease_until"),
+	}
+}
+
+type JobColumns struct {
+	// Auto-generated: embedding operation functions to make it simple to use. DO NOT EDIT. // 自动生成：嵌入操作函数便于使用。请勿编辑。
+	gormcnm.ColumnOperationClass
+	// Auto-generated: column names and types in database table. DO NOT EDIT. // 自动生成：数据库表的列名和类型。请勿编辑。
+	ID         gormcnm.ColumnName[uint]
+	Payload    gormcnm.ColumnName[string]
+	Status     gormcnm.ColumnName[string]
+	RunAt      gormcnm.ColumnName[time.Time]
+	Attempts   gormcnm.ColumnName[int]
+	LeaseUntil gormcnm.ColumnName[time.Time]
+}
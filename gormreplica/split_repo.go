@@ -0,0 +1,176 @@
+// Package gormreplica splits the reads and writes of a repo across a primary and its read replicas
+// Reads go to a replica picked by a Policy, writes and transactions go to the primary
+//
+// gormreplica 将仓储的读取和写入拆分到主库及其只读副本上
+// 读取发往由 Policy 选择的副本，写入和事务发往主库
+package gormreplica
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"sync/atomic"
+
+	"github.com/yyle88/gormrepo"
+	"gorm.io/gorm"
+)
+
+// Policy picks the replica serving a read, returning an index in [0, n)
+// Policy 选择处理读取的副本，返回 [0, n) 范围内的下标
+type Policy interface {
+	Pick(ctx context.Context, n int) int
+}
+
+// PolicyFunc adapts a function to Policy
+// PolicyFunc 将函数适配为 Policy
+type PolicyFunc func(ctx context.Context, n int) int
+
+// Pick calls the function
+// Pick 调用该函数
+func (fn PolicyFunc) Pick(ctx context.Context, n int) int {
+	return fn(ctx, n)
+}
+
+// RoundRobin returns the Policy cycling through the replicas in turn
+// RoundRobin 返回依次轮流使用各副本的 Policy
+func RoundRobin() Policy {
+	var next atomic.Uint64
+	return PolicyFunc(func(ctx context.Context, n int) int {
+		return int((next.Add(1) - 1) % uint64(n))
+	})
+}
+
+// Random returns the Policy picking a replica at random
+// Random 返回随机选择副本的 Policy
+func Random() Policy {
+	return PolicyFunc(func(ctx context.Context, n int) int {
+		return rand.IntN(n)
+	})
+}
+
+type primaryKey struct{}
+
+// WithPrimary returns a context whose reads go to the primary
+// WithPrimary 返回读取发往主库的上下文
+func WithPrimary(ctx context.Context) context.Context {
+	var forced = new(atomic.Bool)
+	forced.Store(true)
+	return context.WithValue(ctx, primaryKey{}, forced)
+}
+
+// WithReadYourWrites returns a context whose reads go to the primary once a write through a SplitRepo succeeded in it
+// Covers the replication lag, so a request reads back what it wrote; use one such context per request
+//
+// WithReadYourWrites 返回的上下文中，一旦通过 SplitRepo 的写入成功，之后的读取就发往主库
+// 用于规避复制延迟，使请求能读回自己写入的内容；每个请求使用一个这样的上下文
+func WithReadYourWrites(ctx context.Context) context.Context {
+	if readsPrimary(ctx) {
+		return ctx
+	}
+	return context.WithValue(ctx, primaryKey{}, new(atomic.Bool))
+}
+
+// readsPrimary reports whether the context sends the reads to the primary
+// readsPrimary 判断上下文是否将读取发往主库
+func readsPrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	written, ok := ctx.Value(primaryKey{}).(*atomic.Bool)
+	if !ok {
+		return false
+	}
+	return written.Load()
+}
+
+// SplitRepo is a BaseRepo variant bound to a primary and its read replicas
+// The reads of its repos (First, Find, Count, Exist and the others) run on a replica, the writes on the primary
+// Reads run on the primary inside transactions, with WithPrimary, and after a write with WithReadYourWrites
+// The replicas must share the dialect of the primary, only their connection pools are used
+//
+// SplitRepo 是绑定主库及其只读副本的 BaseRepo 变体
+// 其仓储的读取（First、Find、Count、Exist 等）在副本上运行，写入在主库上运行
+// 在事务中、使用 WithPrimary 时、以及使用 WithReadYourWrites 且发生写入后，读取在主库上运行
+// 副本必须与主库使用相同的方言，只会使用其连接池
+type SplitRepo[MOD any, CLS any] struct {
+	base     *gormrepo.BaseRepo[MOD, CLS]
+	primary  *gorm.DB
+	replicas []*gorm.DB
+	policy   Policy
+}
+
+// NewSplitRepo creates a SplitRepo running the repos of base on the primary and the replicas, balanced by RoundRobin
+// Reads run on the primary when there are no replicas
+//
+// NewSplitRepo 创建在主库和副本上运行 base 仓储的 SplitRepo，使用 RoundRobin 均衡负载
+// 没有副本时读取在主库上运行
+func NewSplitRepo[MOD any, CLS any](base *gormrepo.BaseRepo[MOD, CLS], primary *gorm.DB, replicas ...*gorm.DB) *SplitRepo[MOD, CLS] {
+	split := &SplitRepo[MOD, CLS]{
+		primary:  primary,
+		replicas: replicas,
+		policy:   RoundRobin(),
+	}
+	split.base = base.WithInterceptors(split.route)
+	return split
+}
+
+// WithPolicy sets the policy picking the replica of each read
+// WithPolicy 设置为每次读取选择副本的策略
+func (split *SplitRepo[MOD, CLS]) WithPolicy(policy Policy) *SplitRepo[MOD, CLS] {
+	split.policy = policy
+	return split
+}
+
+// With creates a GormRepo with the context, writing to the primary and reading from a replica
+// With 创建带有上下文的 GormRepo，写入主库并从副本读取
+func (split *SplitRepo[MOD, CLS]) With(ctx context.Context) *gormrepo.GormRepo[MOD, CLS] {
+	return split.base.With(ctx, split.primary)
+}
+
+// Transaction runs the function in a transaction on the primary, the reads in it run on the primary too
+// Transaction 在主库的事务中运行函数，其中的读取也在主库上运行
+func (split *SplitRepo[MOD, CLS]) Transaction(ctx context.Context, run func(repo *gormrepo.GormRepo[MOD, CLS]) error, opts ...*sql.TxOptions) error {
+	return split.base.Transaction(ctx, split.primary, run, opts...)
+}
+
+// Primary returns the primary database
+// Primary 返回主库
+func (split *SplitRepo[MOD, CLS]) Primary() *gorm.DB {
+	return split.primary
+}
+
+// Replica returns the database serving reads with the context, picked like the reads of the repos
+// Replica 返回在该上下文中处理读取的数据库，选择方式与仓储的读取相同
+func (split *SplitRepo[MOD, CLS]) Replica(ctx context.Context) *gorm.DB {
+	if len(split.replicas) == 0 || readsPrimary(ctx) {
+		return split.primary.WithContext(ctx)
+	}
+	return split.pick(ctx).WithContext(ctx)
+}
+
+func (split *SplitRepo[MOD, CLS]) pick(ctx context.Context) *gorm.DB {
+	return split.replicas[split.policy.Pick(ctx, len(split.replicas))]
+}
+
+// route moves reads to a replica, keeping the statement built so far, and records the writes for WithReadYourWrites
+// route 将读取转移到副本并保留已构建的语句，同时为 WithReadYourWrites 记录写入
+func (split *SplitRepo[MOD, CLS]) route(inv *gormrepo.Invocation, next func() error) error {
+	if !inv.Operation.IsRead() {
+		if err := next(); err != nil {
+			return err
+		}
+		if written, ok := inv.Context.Value(primaryKey{}).(*atomic.Bool); ok {
+			written.Store(true)
+		}
+		return nil
+	}
+	if len(split.replicas) == 0 || readsPrimary(inv.Context) || gormrepo.InTransaction(inv.DB) {
+		return next()
+	}
+	// Setting the context clones the statement, so swapping its pool leaves the primary intact
+	// 设置上下文会克隆语句，因此替换其连接池不影响主库
+	db := inv.DB.Session(&gorm.Session{Context: inv.Context})
+	db.Statement.ConnPool = split.pick(inv.Context).Statement.ConnPool
+	inv.DB = db
+	return next()
+}
@@ -0,0 +1,178 @@
+package gormreplica_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcngen"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormclass"
+	"github.com/yyle88/gormrepo/gormreplica"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/osexistpath/osmustexist"
+	"github.com/yyle88/runpath"
+	"gorm.io/gorm"
+)
+
+type Article struct {
+	ID     uint
+	Title  string
+	Source string
+}
+
+func (*Article) TableName() string {
+	return "articles"
+}
+
+// Tests the generation of columns for models.
+// 测试模型列的生成。
+func TestGenerateColumns(t *testing.T) {
+	absPath := runpath.Path() // Retrieve the absolute path of the source file based on the current test file's location
+	// 获取当前测试文件位置基础上的源文件绝对路径
+	t.Log(absPath)
+
+	// Check the existence of the target file. The file should be created beforehand to ensure it can be located via the code.
+	// 检查目标文件是否存在。文件应手动创建，确保代码能够找到它。
+	require.True(t, osmustexist.IsFile(absPath))
+
+	// List the models to have columns generated. Both instance and non-instance types are supported.
+	// 设置需要生成列的模型，这里支持指针类型和非指针类型。
+	objects := []any{&Article{}}
+
+	options := gormcngen.NewOptions().
+		WithColumnClassExportable(true). // Generate exportable struct names (e.g., ExampleColumns) // 生成可导出的结构体名称（例如 ExampleColumns）
+		WithColumnsMethodRecvName("a").
+		WithColumnsCheckFieldType(true)
+
+	// Configure code generation settings
+	// 配置代码生成设置
+	cfg := gormcngen.NewConfigs(objects, options, absPath).
+		WithIsGenPreventEdit(false)
+	cfg.Gen() // Generate and write the code to the target location (e.g., "gormcnm.gen.go") // 生成并将代码写入目标位置（例如 "gormcnm.gen.go"）
+}
+
+// setupArticle seeds one article whose source names the database
+// Replication is not simulated, so each read shows the database serving it
+//
+// setupArticle 写入一篇以来源标明所在数据库的文章
+// 不模拟复制，因此每次读取都能显示处理它的数据库
+func setupArticle(t *testing.T, db *gorm.DB, source string) {
+	require.NoError(t, db.Create(&Article{ID: 1, Title: "hello", Source: source}).Error)
+}
+
+func sourceOf(t *testing.T, repo *gormrepo.GormRepo[Article, *ArticleColumns]) string {
+	article, err := repo.First(func(db *gorm.DB, cls *ArticleColumns) *gorm.DB {
+		return db.Where(cls.ID.Eq(1))
+	})
+	require.NoError(t, err)
+	return article.Source
+}
+
+func TestSplitRepo_Reads(t *testing.T) {
+	primary := tests.NewMemDBWithTables(t, &Article{})
+	replica1 := tests.NewMemDBWithTables(t, &Article{})
+	replica2 := tests.NewMemDBWithTables(t, &Article{})
+	setupArticle(t, primary, "primary")
+	setupArticle(t, replica1, "replica-1")
+	setupArticle(t, replica2, "replica-2")
+	split := gormreplica.NewSplitRepo(gormrepo.NewBaseRepo(gormclass.Use(&Article{})), primary, replica1, replica2)
+	ctx := context.Background()
+
+	// Reads take the replicas in turn
+	// 读取依次使用各副本
+	require.Equal(t, "replica-1", sourceOf(t, split.With(ctx)))
+	require.Equal(t, "replica-2", sourceOf(t, split.With(ctx)))
+	require.Equal(t, "replica-1", sourceOf(t, split.With(ctx)))
+
+	count, err := split.With(ctx).Count(func(db *gorm.DB, cls *ArticleColumns) *gorm.DB {
+		return db.Where(cls.Source.Eq("replica-2"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	split.WithPolicy(gormreplica.PolicyFunc(func(ctx context.Context, n int) int {
+		return n - 1
+	}))
+	require.Equal(t, "replica-2", sourceOf(t, split.With(ctx)))
+	var article Article
+	require.NoError(t, split.Replica(ctx).Take(&article).Error)
+	require.Equal(t, "replica-2", article.Source)
+
+	require.Equal(t, "primary", sourceOf(t, split.With(gormreplica.WithPrimary(ctx))))
+}
+
+func TestSplitRepo_Writes(t *testing.T) {
+	primary := tests.NewMemDBWithTables(t, &Article{})
+	replica1 := tests.NewMemDBWithTables(t, &Article{})
+	replica2 := tests.NewMemDBWithTables(t, &Article{})
+	setupArticle(t, primary, "primary")
+	setupArticle(t, replica1, "replica-1")
+	setupArticle(t, replica2, "replica-2")
+	split := gormreplica.NewSplitRepo(gormrepo.NewBaseRepo(gormclass.Use(&Article{})), primary, replica1, replica2)
+	ctx := gormreplica.WithReadYourWrites(context.Background())
+
+	require.Equal(t, "replica-1", sourceOf(t, split.With(ctx)))
+
+	// Writes go to the primary, then the context reads from the primary
+	// 写入发往主库，之后该上下文从主库读取
+	require.NoError(t, split.With(ctx).Create(&Article{ID: 2, Title: "new", Source: "primary"}))
+	var count int64
+	require.NoError(t, split.Primary().Model(&Article{}).Count(&count).Error)
+	require.Equal(t, int64(2), count)
+
+	require.Equal(t, "primary", sourceOf(t, split.With(ctx)))
+	article, err := split.With(ctx).First(func(db *gorm.DB, cls *ArticleColumns) *gorm.DB {
+		return db.Where(cls.ID.Eq(2))
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new", article.Title)
+
+	// Other contexts keep reading from the replicas
+	// 其他上下文继续从副本读取
+	require.Equal(t, "replica-2", sourceOf(t, split.With(context.Background())))
+}
+
+func TestSplitRepo_Transaction(t *testing.T) {
+	primary := tests.NewMemDBWithTables(t, &Article{})
+	replica1 := tests.NewMemDBWithTables(t, &Article{})
+	replica2 := tests.NewMemDBWithTables(t, &Article{})
+	setupArticle(t, primary, "primary")
+	setupArticle(t, replica1, "replica-1")
+	setupArticle(t, replica2, "replica-2")
+	split := gormreplica.NewSplitRepo(gormrepo.NewBaseRepo(gormclass.Use(&Article{})), primary, replica1, replica2)
+	ctx := context.Background()
+
+	require.NoError(t, split.Transaction(ctx, func(repo *gormrepo.GormRepo[Article, *ArticleColumns]) error {
+		require.Equal(t, "primary", sourceOf(t, repo))
+		return repo.UpdatesM(func(db *gorm.DB, cls *ArticleColumns) *gorm.DB {
+			return db.Where(cls.ID.Eq(1))
+		}, func(cls *ArticleColumns) gormcnm.ColumnValueMap {
+			return cls.Kw(cls.Title.Kv("updated"))
+		})
+	}))
+
+	var article Article
+	require.NoError(t, split.Primary().First(&article, 1).Error)
+	require.Equal(t, "updated", article.Title)
+	require.Equal(t, "replica-1", sourceOf(t, split.With(ctx)))
+}
+
+func (a *Article) Columns() *ArticleColumns {
+	return &ArticleColumns{
+		// Auto-generated: column names and types mapping. DO NOT EDIT. // 自动生成：列名和类型映射。请勿编辑。
+		ID:     gormcnm.Cnm(a.ID, "id"),
+		Title:  gormcnm.Cnm(a.Title, "title"),
+		Source: gormcnm.Cnm(a.Source, "source"),
+	}
+}
+
+type ArticleColumns struct {
+	// Auto-generated: embedding operation functions to make it simple to use. DO NOT EDIT. // 自动生成：嵌入操作函数便于使用。请勿编辑。
+	gormcnm.ColumnOperationClass
+	// Auto-generated: column names and types in database table. DO NOT EDIT. // 自动生成：数据库表的列名和类型。请勿编辑。
+	ID     gormcnm.ColumnName[uint]
+	Title  gormcnm.ColumnName[string]
+	Source gormcnm.ColumnName[string]
+}
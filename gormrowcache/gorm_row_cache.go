@@ -0,0 +1,246 @@
+// Package gormrowcache provides a read-through cache of rows looked up by primary key
+// Writes through GormRepo invalidate the cached rows via an interceptor, after commit when in a transaction
+//
+// gormrowcache 提供按主键查找的行的读穿透缓存
+// 通过 GormRepo 的写入经由拦截器使缓存的行失效，在事务中时于提交后失效
+package gormrowcache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormrepo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Cache stores rows by key, keys are "<table>:<primary key>"
+// Implementations must be safe for concurrent use, and should rely on a TTL when Delete or Purge can fail
+//
+// Cache 按键存储行，键的格式为 "<表名>:<主键>"
+// 实现必须支持并发使用，当 Delete 或 Purge 可能失败时应依靠 TTL
+type Cache interface {
+	Get(ctx context.Context, key string) (interface{}, bool)
+	Set(ctx context.Context, key string, value interface{})
+	Delete(ctx context.Context, keys ...string)
+	Purge(ctx context.Context, prefix string) // Removes the keys starting with prefix // 删除以 prefix 开头的键
+}
+
+// Cached serves primary key lookups of MOD from the cache, loading misses from the database
+// Install Interceptor on every repo writing MOD, otherwise those writes leave stale rows until the TTL
+//
+// Cached 从缓存提供 MOD 的主键查找，未命中时从数据库加载
+// 需在每个写入 MOD 的仓储上安装 Interceptor，否则这些写入会留下陈旧的行直到 TTL 过期
+type Cached[MOD any, CLS any] struct {
+	repo  *gormrepo.BaseRepo[MOD, CLS]
+	cache Cache
+	mutex sync.Mutex // Makes the epoch check and the store one step against invalidations // 使纪元检查与存储相对于失效成为一个步骤
+	epoch uint64     // Bumped on each invalidation, a load that spans one is not stored // 每次失效时递增，跨越失效的加载不会被存储
+}
+
+// NewCached creates a Cached using an LRU of 1024 rows
+// The MOD param is used to deduce the type, its value is not used
+//
+// NewCached 创建使用 1024 行 LRU 的 Cached
+// MOD 参数用于类型推断，其值不使用
+func NewCached[MOD any, CLS any](_ *MOD, cls CLS) *Cached[MOD, CLS] {
+	return &Cached[MOD, CLS]{
+		repo:  gormrepo.NewBaseRepo((*MOD)(nil), cls),
+		cache: NewLRU(1024),
+	}
+}
+
+// WithCache sets the cache storing the rows
+// WithCache 设置存储行的缓存
+func (cached *Cached[MOD, CLS]) WithCache(cache Cache) *Cached[MOD, CLS] {
+	cached.cache = cache
+	return cached
+}
+
+// First returns the row with the primary key id, from the cache when present
+// Each call returns its own copy, so callers may modify it freely (nested pointers are shared)
+// Inside a transaction the cache is bypassed, since the transaction may see rows not yet committed
+// Returns gorm.ErrRecordNotFound when no row matches, misses are not cached
+//
+// First 返回主键为 id 的行，缓存中存在时从缓存返回
+// 每次调用返回独立的副本，调用方可以随意修改（嵌套的指针是共享的）
+// 在事务中会绕过缓存，因为事务可能看到尚未提交的行
+// 没有行匹配时返回 gorm.ErrRecordNotFound，未命中的结果不会被缓存
+func (cached *Cached[MOD, CLS]) First(ctx context.Context, db *gorm.DB, id interface{}) (*MOD, error) {
+	db = db.WithContext(ctx)
+	sch, err := parseSchema[MOD](db)
+	if err != nil {
+		return nil, err
+	}
+	if gormrepo.InTransaction(db) {
+		return cached.load(db, sch, id)
+	}
+	key := cacheKey(sch.Table, id)
+	if value, ok := cached.cache.Get(ctx, key); ok {
+		if one, ok := value.(MOD); ok {
+			return &one, nil
+		}
+	}
+	epoch := cached.currentEpoch()
+	one, err := cached.load(db, sch, id)
+	if err != nil {
+		return nil, err
+	}
+	cached.store(ctx, key, epoch, *one)
+	return one, nil
+}
+
+func (cached *Cached[MOD, CLS]) currentEpoch() uint64 {
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+	return cached.epoch
+}
+
+// store sets the row unless an invalidation ran since epoch, checking and setting under the lock of invalidate
+// store 在 epoch 之后没有发生失效时设置该行，检查和设置都在 invalidate 的锁内进行
+func (cached *Cached[MOD, CLS]) store(ctx context.Context, key string, epoch uint64, one MOD) {
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+	if cached.epoch == epoch {
+		cached.cache.Set(ctx, key, one)
+	}
+}
+
+// invalidate bumps the epoch and removes the rows in one step, so no load started before it stores afterwards
+// invalidate 在一个步骤中递增纪元并删除行，因此在它之前开始的加载不会在之后存储
+func (cached *Cached[MOD, CLS]) invalidate(remove func()) {
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+	cached.epoch++
+	remove()
+}
+
+func (cached *Cached[MOD, CLS]) load(db *gorm.DB, sch *schema.Schema, id interface{}) (*MOD, error) {
+	return cached.repo.Repo(db).First(func(db *gorm.DB, cls CLS) *gorm.DB {
+		return db.Where(map[string]interface{}{sch.PrioritizedPrimaryField.DBName: id})
+	})
+}
+
+// Invalidate removes the rows with the primary keys from the cache
+// Invalidate 从缓存中删除这些主键的行
+func (cached *Cached[MOD, CLS]) Invalidate(ctx context.Context, db *gorm.DB, ids ...interface{}) error {
+	sch, err := parseSchema[MOD](db)
+	if err != nil {
+		return err
+	}
+	var keys = make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, cacheKey(sch.Table, id))
+	}
+	cached.invalidate(func() {
+		cached.cache.Delete(ctx, keys...)
+	})
+	return nil
+}
+
+// InvalidateAll removes all the rows of the table from the cache
+// InvalidateAll 从缓存中删除该表的所有行
+func (cached *Cached[MOD, CLS]) InvalidateAll(ctx context.Context, db *gorm.DB) error {
+	sch, err := parseSchema[MOD](db)
+	if err != nil {
+		return err
+	}
+	cached.invalidate(func() {
+		cached.cache.Purge(ctx, sch.Table+":")
+	})
+	return nil
+}
+
+// Interceptor returns the interceptor invalidating the rows written through the repo
+// Save, UpdatesO, UpdatesC, Delete and DeleteM invalidate the rows of their objects
+// Where-based writes (Update, Updates, DeleteW) and Invoke cannot know the rows, so they invalidate the whole table
+// Inside a repo transaction the rows are invalidated again when it commits, dropping rows cached meanwhile by other readers
+// Writes inside other transactions (e.g. a plain db.Transaction) fail with ErrNotInTransaction, as they cannot invalidate on commit
+//
+// Interceptor 返回使通过仓储写入的行失效的拦截器
+// Save、UpdatesO、UpdatesC、Delete 和 DeleteM 使其对象的行失效
+// 基于 where 的写入（Update、Updates、DeleteW）和 Invoke 无法得知涉及的行，因此使整个表失效
+// 在仓储事务中，提交时会再次失效，以丢弃期间被其他读取方缓存的行
+// 在其他事务中（例如普通的 db.Transaction）的写入以 ErrNotInTransaction 失败，因为它们无法在提交时失效
+func (cached *Cached[MOD, CLS]) Interceptor() gormrepo.Interceptor {
+	return func(inv *gormrepo.Invocation, next func() error) error {
+		if inv.Operation.IsRead() {
+			return next()
+		}
+		invalidate, err := cached.invalidation(inv)
+		if err != nil {
+			return err
+		}
+		if invalidate == nil {
+			return next()
+		}
+		// Rows cached by other readers before the commit would outlive it, so a transaction must be able to invalidate on commit
+		// 其他读取方在提交前缓存的行会在提交后继续存在，因此事务必须能够在提交时失效
+		if gormrepo.InTransaction(inv.DB) {
+			if err := gormrepo.OnCommit(inv.DB, invalidate); err != nil {
+				return errors.WithMessage(err, "row cache writes in a transaction must run in a repo transaction")
+			}
+		}
+		err = next()
+		invalidate()
+		return err
+	}
+}
+
+// invalidation returns the invalidation of the write, nil when it cannot touch cached rows
+// Keys are taken before the write, since Delete may clear the primary key of the object
+//
+// invalidation 返回该写入对应的失效操作，写入不会涉及缓存的行时返回 nil
+// 在写入之前获取键，因为 Delete 可能会清除对象的主键
+func (cached *Cached[MOD, CLS]) invalidation(inv *gormrepo.Invocation) (func(), error) {
+	ctx := inv.DB.Statement.Context
+	sch, err := parseSchema[MOD](inv.DB)
+	if err != nil {
+		return nil, err
+	}
+	switch inv.Operation {
+	case gormrepo.OpCreate, gormrepo.OpCreates, gormrepo.OpCreateInBatches:
+		// Misses are not cached, so new rows have nothing to invalidate
+		// 未命中的结果不会被缓存，因此新行没有需要失效的内容
+		return nil, nil
+	case gormrepo.OpSave, gormrepo.OpSaves, gormrepo.OpUpdatesO, gormrepo.OpUpdatesC, gormrepo.OpDelete, gormrepo.OpDeleteM:
+		var keys []string
+		for _, object := range gormrepo.ObjectsOf[MOD](inv.Object) {
+			id, isZero := sch.PrioritizedPrimaryField.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(object)))
+			if !isZero {
+				keys = append(keys, cacheKey(sch.Table, id))
+			}
+		}
+		return func() {
+			cached.invalidate(func() {
+				cached.cache.Delete(context.WithoutCancel(ctx), keys...)
+			})
+		}, nil
+	default:
+		return func() {
+			cached.invalidate(func() {
+				cached.cache.Purge(context.WithoutCancel(ctx), sch.Table+":")
+			})
+		}, nil
+	}
+}
+
+func cacheKey(table string, id interface{}) string {
+	return fmt.Sprintf("%s:%v", table, id)
+}
+
+// parseSchema parses the schema of MOD, which must have a single primary key
+// parseSchema 解析 MOD 的 schema，MOD 必须只有一个主键
+func parseSchema[MOD any](db *gorm.DB) (*schema.Schema, error) {
+	sch, err := gormrepo.ParseSchema[MOD](db)
+	if err != nil {
+		return nil, err
+	}
+	if len(sch.PrimaryFields) != 1 {
+		return nil, errors.Errorf("cached table %s must have a single primary key", sch.Table)
+	}
+	return sch, nil
+}
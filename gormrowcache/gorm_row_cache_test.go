@@ -0,0 +1,287 @@
+package gormrowcache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcngen"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormclass"
+	"github.com/yyle88/gormrepo/gormrowcache"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"github.com/yyle88/osexistpath/osmustexist"
+	"github.com/yyle88/runpath"
+	"gorm.io/gorm"
+)
+
+type Product struct {
+	ID    uint
+	Name  string
+	Price int64
+}
+
+func (*Product) TableName() string {
+	return "products"
+}
+
+// Tests the generation of columns for models.
+// 测试模型列的生成。
+func TestGenerateColumns(t *testing.T) {
+	absPath := runpath.Path() // Retrieve the absolute path of the source file based on the current test file's location
+	// 获取当前测试文件位置基础上的源文件绝对路径
+	t.Log(absPath)
+
+	// Check the existence of the target file. The file should be created beforehand to ensure it can be located via the code.
+	// 检查目标文件是否存在。文件应手动创建，确保代码能够找到它。
+	require.True(t, osmustexist.IsFile(absPath))
+
+	// List the models to have columns generated. Both instance and non-instance types are supported.
+	// 设置需要生成列的模型，这里支持指针类型和非指针类型。
+	objects := []any{&Product{}}
+
+	options := gormcngen.NewOptions().
+		WithColumnClassExportable(true). // Generate exportable struct names (e.g., ExampleColumns) // 生成可导出的结构体名称（例如 ExampleColumns）
+		WithColumnsMethodRecvName("a").
+		WithColumnsCheckFieldType(true)
+
+	// Configure code generation settings
+	// 配置代码生成设置
+	cfg := gormcngen.NewConfigs(objects, options, absPath).
+		WithIsGenPreventEdit(false)
+	cfg.Gen() // Generate and write the code to the target location (e.g., "gormcnm.gen.go") // 生成并将代码写入目标位置（例如 "gormcnm.gen.go"）
+}
+
+// setupProducts seeds two products
+// setupProducts 写入两个商品
+func setupProducts(t *testing.T, db *gorm.DB) {
+	require.NoError(t, db.Create([]*Product{{ID: 1, Name: "apple", Price: 10}, {ID: 2, Name: "pear", Price: 20}}).Error)
+}
+
+// countQueries counts the queries run on the products table
+// countQueries 统计在 products 表上执行的查询次数
+func countQueries(t *testing.T, db *gorm.DB) *int {
+	var count int
+	must.Done(db.Callback().Query().After("gorm:query").Register("test:count_queries", func(db *gorm.DB) {
+		if db.Statement.Table == "products" {
+			count++
+		}
+	}))
+	return &count
+}
+
+func TestCached_First(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Product{})
+	setupProducts(t, db)
+	queries := countQueries(t, db)
+	ctx := context.Background()
+
+	cached := gormrowcache.NewCached(gormclass.Use(&Product{}))
+
+	one, err := cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	require.Equal(t, "apple", one.Name)
+	require.Equal(t, 1, *queries)
+
+	// Hits return their own copy, without querying
+	// 命中时返回独立的副本，不会查询
+	one.Name = "modified"
+	two, err := cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	require.Equal(t, "apple", two.Name)
+	require.Equal(t, 1, *queries)
+
+	// Misses are not cached
+	// 未命中的结果不会被缓存
+	_, err = cached.First(ctx, db, 3)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = cached.First(ctx, db, 3)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	require.Equal(t, 3, *queries)
+
+	require.NoError(t, cached.Invalidate(ctx, db, 1))
+	_, err = cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	require.Equal(t, 4, *queries)
+}
+
+func TestCached_InvalidateDuringLoad(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Product{})
+	setupProducts(t, db)
+	ctx := context.Background()
+	cached := gormrowcache.NewCached(gormclass.Use(&Product{}))
+
+	// A load spanning an invalidation does not store its row
+	// 跨越失效的加载不会存储其行
+	var invalidated bool
+	must.Done(db.Callback().Query().After("gorm:query").Register("test:invalidate", func(tx *gorm.DB) {
+		if !invalidated && tx.Statement.Table == "products" {
+			invalidated = true
+			must.Done(cached.Invalidate(ctx, db, 1))
+		}
+	}))
+	queries := countQueries(t, db)
+	_, err := cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	_, err = cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	require.Equal(t, 2, *queries)
+	_, err = cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	require.Equal(t, 2, *queries)
+}
+
+func TestCached_Interceptor(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Product{})
+	setupProducts(t, db)
+	ctx := context.Background()
+
+	cached := gormrowcache.NewCached(gormclass.Use(&Product{}))
+	repo := gormrepo.NewBaseRepo(gormclass.Use(&Product{})).WithInterceptors(cached.Interceptor()).With(ctx, db)
+
+	one, err := cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	_, err = cached.First(ctx, db, 2)
+	require.NoError(t, err)
+
+	// Writes of objects invalidate their own rows
+	// 对象的写入使其自身的行失效
+	require.NoError(t, repo.UpdatesO(one, func(cls *ProductColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Price.Kv(11))
+	}))
+	one, err = cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), one.Price)
+
+	one.Name = "green-apple"
+	require.NoError(t, repo.Save(one))
+	one, err = cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	require.Equal(t, "green-apple", one.Name)
+
+	// Where-based writes invalidate the whole table
+	// 基于 where 的写入使整个表失效
+	require.NoError(t, repo.UpdatesM(func(db *gorm.DB, cls *ProductColumns) *gorm.DB {
+		return db.Where(cls.Price.Gt(0))
+	}, func(cls *ProductColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Price.KeAdd(100))
+	}))
+	two, err := cached.First(ctx, db, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(120), two.Price)
+
+	require.NoError(t, repo.Delete(two))
+	_, err = cached.First(ctx, db, 2)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestCached_Transaction(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Product{})
+	setupProducts(t, db)
+	ctx := context.Background()
+
+	lru := gormrowcache.NewLRU(16)
+	cached := gormrowcache.NewCached(gormclass.Use(&Product{})).WithCache(lru)
+	base := gormrepo.NewBaseRepo(gormclass.Use(&Product{})).WithInterceptors(cached.Interceptor())
+
+	_, err := cached.First(ctx, db, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, gormrepo.Transaction(ctx, db, func(uow *gormrepo.UnitOfWork) error {
+		repo := base.Repo(uow.DB())
+		if err := repo.UpdatesM(func(db *gorm.DB, cls *ProductColumns) *gorm.DB {
+			return db.Where(cls.ID.Eq(1))
+		}, func(cls *ProductColumns) gormcnm.ColumnValueMap {
+			return cls.Kw(cls.Name.Kv("red-apple"))
+		}); err != nil {
+			return err
+		}
+		// Inside the transaction the cache is bypassed and the uncommitted row is seen
+		// 在事务中绕过缓存，可以看到未提交的行
+		one, err := cached.First(ctx, uow.DB(), 1)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, "red-apple", one.Name)
+
+		// Another reader refills the cache with the committed row before the commit
+		// 另一个读取方在提交前使用已提交的行重新填充缓存
+		lru.Set(ctx, "products:1", Product{ID: 1, Name: "apple", Price: 10})
+		return nil
+	}))
+
+	// The commit drops the rows cached meanwhile
+	// 提交会丢弃期间缓存的行
+	one, err := cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	require.Equal(t, "red-apple", one.Name)
+
+	// A rollback leaves the cache consistent with the database
+	// 回滚后缓存与数据库保持一致
+	require.Error(t, gormrepo.Transaction(ctx, db, func(uow *gormrepo.UnitOfWork) error {
+		if err := base.Repo(uow.DB()).Delete(one); err != nil {
+			return err
+		}
+		return errors.New("business failure")
+	}))
+	one, err = cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	require.Equal(t, "red-apple", one.Name)
+
+	// Other transactions cannot invalidate on commit, so their writes are rejected
+	// 其他事务无法在提交时失效，因此其写入会被拒绝
+	require.ErrorIs(t, db.Transaction(func(tx *gorm.DB) error {
+		return base.Repo(tx).Delete(one)
+	}), gormrepo.ErrNotInTransaction)
+	one, err = cached.First(ctx, db, 1)
+	require.NoError(t, err)
+	require.Equal(t, "red-apple", one.Name)
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	lru := gormrowcache.NewLRU(2).WithTTL(time.Minute).WithClock(func() time.Time {
+		return now
+	})
+
+	lru.Set(ctx, "a:1", 1)
+	lru.Set(ctx, "a:2", 2)
+	_, ok := lru.Get(ctx, "a:1") // Marks a:1 as recently used // 将 a:1 标记为最近使用
+	require.True(t, ok)
+	lru.Set(ctx, "b:1", 3)
+	_, ok = lru.Get(ctx, "a:2")
+	require.False(t, ok)
+	require.Equal(t, 2, lru.Len())
+
+	lru.Purge(ctx, "a:")
+	_, ok = lru.Get(ctx, "a:1")
+	require.False(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = lru.Get(ctx, "b:1")
+	require.False(t, ok)
+	require.Equal(t, 0, lru.Len())
+}
+
+func (a *Product) Columns() *ProductColumns {
+	return &ProductColumns{
+		// Auto-generated: column names and types mapping. DO NOT EDIT. // 自动生成：列名和类型映射。请勿编辑。
+		ID:    gormcnm.Cnm(a.ID, "id"),
+		Name:  gormcnm.Cnm(a.Name, "name"),
+		Price: gormcnm.Cnm(a.Price, "price"),
+	}
+}
+
+type ProductColumns struct {
+	// Auto-generated: embedding operation functions to make it simple to use. DO NOT EDIT. // 自动生成：嵌入操作函数便于使用。请勿编辑。
+	gormcnm.ColumnOperationClass
+	// Auto-generated: column names and types in database table. DO NOT EDIT. // 自动生成：数据库表的列名和类型。请勿编辑。
+	ID    gormcnm.ColumnName[uint]
+	Name  gormcnm.ColumnName[string]
+	Price gormcnm.ColumnName[int64]
+}
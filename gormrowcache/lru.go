@@ -0,0 +1,132 @@
+package gormrowcache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LRU is an in-process Cache evicting the least recently used entry beyond capacity
+// Entries also expire after the TTL when one is set
+//
+// LRU 是进程内的 Cache，超出容量时淘汰最近最少使用的条目
+// 设置 TTL 时条目还会在 TTL 后过期
+type LRU struct {
+	mutex    sync.Mutex
+	capacity int
+	ttl      time.Duration
+	clock    func() time.Time
+	items    map[string]*list.Element
+	order    *list.List // Front is the most recently used // 队首为最近使用
+}
+
+type lruItem struct {
+	key      string
+	value    interface{}
+	expireAt time.Time
+}
+
+// NewLRU creates an LRU holding up to capacity entries, without TTL
+// NewLRU 创建最多保存 capacity 个条目且没有 TTL 的 LRU
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: max(capacity, 1),
+		clock:    time.Now,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// WithTTL sets how long an entry stays valid, zero means forever
+// Bounds the staleness when other processes write the table without invalidating this cache
+//
+// WithTTL 设置条目保持有效的时长，零表示永久
+// 当其他进程写入该表而不使此缓存失效时，用于限制数据陈旧的时间
+func (lru *LRU) WithTTL(ttl time.Duration) *LRU {
+	lru.ttl = ttl
+	return lru
+}
+
+// WithClock sets the time source of the TTL
+// WithClock 设置 TTL 的时间来源
+func (lru *LRU) WithClock(clock func() time.Time) *LRU {
+	lru.clock = clock
+	return lru
+}
+
+// Get returns the value of the key and marks it as recently used
+// Get 返回键的值并将其标记为最近使用
+func (lru *LRU) Get(ctx context.Context, key string) (interface{}, bool) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+	element, ok := lru.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := element.Value.(*lruItem)
+	if !item.expireAt.IsZero() && !lru.clock().Before(item.expireAt) {
+		lru.remove(element)
+		return nil, false
+	}
+	lru.order.MoveToFront(element)
+	return item.value, true
+}
+
+// Set stores the value of the key, evicting the least recently used entry when full
+// Set 保存键的值，满时淘汰最近最少使用的条目
+func (lru *LRU) Set(ctx context.Context, key string, value interface{}) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+	var expireAt time.Time
+	if lru.ttl > 0 {
+		expireAt = lru.clock().Add(lru.ttl)
+	}
+	if element, ok := lru.items[key]; ok {
+		element.Value = &lruItem{key: key, value: value, expireAt: expireAt}
+		lru.order.MoveToFront(element)
+		return
+	}
+	lru.items[key] = lru.order.PushFront(&lruItem{key: key, value: value, expireAt: expireAt})
+	for lru.order.Len() > lru.capacity {
+		lru.remove(lru.order.Back())
+	}
+}
+
+// Delete removes the keys
+// Delete 删除这些键
+func (lru *LRU) Delete(ctx context.Context, keys ...string) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+	for _, key := range keys {
+		if element, ok := lru.items[key]; ok {
+			lru.remove(element)
+		}
+	}
+}
+
+// Purge removes the keys starting with prefix
+// Purge 删除以 prefix 开头的键
+func (lru *LRU) Purge(ctx context.Context, prefix string) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+	for key, element := range lru.items {
+		if strings.HasPrefix(key, prefix) {
+			lru.remove(element)
+		}
+	}
+}
+
+// Len returns the count of entries, expired ones included until they are touched
+// Len 返回条目数量，已过期但尚未被访问的条目也计算在内
+func (lru *LRU) Len() int {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+	return lru.order.Len()
+}
+
+func (lru *LRU) remove(element *list.Element) {
+	lru.order.Remove(element)
+	delete(lru.items, element.Value.(*lruItem).key)
+}
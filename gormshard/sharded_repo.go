@@ -0,0 +1,485 @@
+// Package gormshard routes the operations of a repo across horizontally sharded databases
+// Each call picks its shard from a shard key column, read from the model or from the where condition
+// Reads without a shard key fan out across all the shards and merge their results
+//
+// gormshard 将仓储的操作路由到水平分片的多个数据库
+// 每次调用从分片键列选择分片，分片键从模型或 where 条件中读取
+// 没有分片键的读取会分发到所有分片并合并结果
+package gormshard
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrCrossShard is returned when a write would touch more than one shard
+// ErrCrossShard 在写入会涉及多个分片时返回
+var ErrCrossShard = errors.New("gormshard: write spans shards")
+
+// ErrShardKeyRequired is returned when an operation needs one shard, but the shard key does not pin it
+// ErrShardKeyRequired 在操作需要单个分片、而分片键无法确定该分片时返回
+var ErrShardKeyRequired = errors.New("gormshard: shard key required")
+
+// ShardedRepo runs the repos of MOD on the shard owning each row, chosen by the shard key column of type K
+// The where conditions pin a shard with shard_key = value or shard_key IN (values) at the top level, e.g. cls.UserID.Eq(id)
+// Where conditions are inspected without running them, so conditions added through db.Scopes are not seen
+// Where-based writes (Update, Updates, UpdatesM, DeleteW) never fan out, they fail with ErrCrossShard unless pinned to one shard
+//
+// ShardedRepo 在拥有各行的分片上运行 MOD 的仓储，分片由类型为 K 的分片键列选择
+// where 条件通过顶层的 shard_key = value 或 shard_key IN (values) 限定分片，例如 cls.UserID.Eq(id)
+// where 条件不经运行即被检查，因此通过 db.Scopes 添加的条件不可见
+// 基于 where 的写入（Update、Updates、UpdatesM、DeleteW）从不分发，除非限定到一个分片，否则以 ErrCrossShard 失败
+type ShardedRepo[MOD any, CLS any, K any] struct {
+	base        *gormrepo.BaseRepo[MOD, CLS]
+	cls         CLS
+	keyColumn   string
+	shards      []*gorm.DB
+	locate      func(key K, n int) int
+	concurrency int
+}
+
+// NewShardedRepo creates a ShardedRepo over the shards, locating the shard of a key by hashing it
+// The MOD param is used to deduce the type, its value is not used
+//
+// NewShardedRepo 创建基于这些分片的 ShardedRepo，通过对键取哈希定位其分片
+// MOD 参数用于类型推断，其值不使用
+func NewShardedRepo[MOD any, CLS any, K any](_ *MOD, cls CLS, keyColumn func(cls CLS) gormcnm.ColumnName[K], shards ...*gorm.DB) *ShardedRepo[MOD, CLS, K] {
+	return &ShardedRepo[MOD, CLS, K]{
+		base:        gormrepo.NewBaseRepo((*MOD)(nil), cls),
+		cls:         cls,
+		keyColumn:   columnOf(keyColumn(cls).Name()),
+		shards:      shards,
+		locate:      HashLocate[K],
+		concurrency: 8,
+	}
+}
+
+// WithLocate sets the function returning the shard index in [0, n) of a key
+// WithLocate 设置返回键所在分片下标（范围 [0, n)）的函数
+func (repo *ShardedRepo[MOD, CLS, K]) WithLocate(locate func(key K, n int) int) *ShardedRepo[MOD, CLS, K] {
+	repo.locate = locate
+	return repo
+}
+
+// WithConcurrency sets how many shards a fan-out query runs on at the same time
+// WithConcurrency 设置分发查询同时在多少个分片上运行
+func (repo *ShardedRepo[MOD, CLS, K]) WithConcurrency(concurrency int) *ShardedRepo[MOD, CLS, K] {
+	repo.concurrency = max(concurrency, 1)
+	return repo
+}
+
+// WithInterceptors sets interceptors on the repos of every shard, appended after the existing ones
+// WithInterceptors 为每个分片的仓储设置拦截器，追加在已有拦截器之后
+func (repo *ShardedRepo[MOD, CLS, K]) WithInterceptors(interceptors ...gormrepo.Interceptor) *ShardedRepo[MOD, CLS, K] {
+	repo.base = repo.base.WithInterceptors(interceptors...)
+	return repo
+}
+
+// HashLocate locates integer keys by modulo and other keys by their FNV-1a hash
+// HashLocate 对整数键取模定位，其他键使用其 FNV-1a 哈希定位
+func HashLocate[K any](key K, n int) int {
+	value := reflect.ValueOf(key)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(uint64(value.Int()) % uint64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(value.Uint() % uint64(n))
+	default:
+		hash := fnv.New64a()
+		_, _ = fmt.Fprint(hash, key)
+		return int(hash.Sum64() % uint64(n))
+	}
+}
+
+// Shard returns the repo of the shard owning the key
+// Shard 返回拥有该键的分片的仓储
+func (repo *ShardedRepo[MOD, CLS, K]) Shard(ctx context.Context, key K) *gormrepo.GormRepo[MOD, CLS] {
+	return repo.base.With(ctx, repo.shards[repo.locate(key, len(repo.shards))])
+}
+
+// Transaction runs the function in a transaction on the shard owning the key
+// Transaction 在拥有该键的分片上的事务中运行函数
+func (repo *ShardedRepo[MOD, CLS, K]) Transaction(ctx context.Context, key K, run func(repo *gormrepo.GormRepo[MOD, CLS]) error, opts ...*sql.TxOptions) error {
+	return repo.base.Transaction(ctx, repo.shards[repo.locate(key, len(repo.shards))], run, opts...)
+}
+
+// First finds the first record matching the where condition, which must pin one shard
+// First 查找符合 where 条件的第一条记录，where 条件必须限定到一个分片
+func (repo *ShardedRepo[MOD, CLS, K]) First(ctx context.Context, where func(db *gorm.DB, cls CLS) *gorm.DB) (*MOD, error) {
+	shard, err := repo.whereShard(where)
+	if err != nil {
+		return nil, err
+	}
+	return repo.base.With(ctx, repo.shards[shard]).First(where)
+}
+
+// Find retrieves the records matching the where condition from the shards it pins, or from all the shards
+// An Order and a Limit/Offset in the where condition apply to the merged records, as on one database
+// Each shard then returns its records from the start, up to offset+limit of them, merged by the ordering of plain columns
+//
+// Find 从 where 条件限定的分片（或所有分片）检索符合条件的记录
+// where 条件中的 Order 和 Limit/Offset 作用于合并后的记录，与单个数据库上一致
+// 此时每个分片从头返回其记录，最多 offset+limit 条，并按普通列的排序合并
+func (repo *ShardedRepo[MOD, CLS, K]) Find(ctx context.Context, where func(db *gorm.DB, cls CLS) *gorm.DB) ([]*MOD, error) {
+	stmt := repo.probe(where)
+	sch, err := repo.schema()
+	if err != nil {
+		return nil, err
+	}
+	orders, err := whereOrder(stmt, sch)
+	if err != nil {
+		return nil, err
+	}
+	limit, offset := whereLimit(stmt)
+	shards, err := repo.readShards(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	var paged = limit != nil || offset > 0
+	var shardWhere = where
+	if paged && len(shards) > 1 {
+		shardWhere = func(db *gorm.DB, cls CLS) *gorm.DB {
+			// A negative offset resets the offset of the where condition to zero, the offset applies after the merge
+			// 负数的 offset 会将 where 条件的 offset 重置为零，offset 在合并后生效
+			db = where(db, cls).Offset(-1)
+			if limit != nil {
+				db = db.Limit(offset + *limit)
+			}
+			return db
+		}
+	}
+	var parts = make([][]*MOD, len(shards))
+	if err := repo.fanOut(shards, func(idx int, shard *gorm.DB) error {
+		results, err := repo.base.With(ctx, shard).Find(shardWhere)
+		parts[idx] = results
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if len(shards) == 1 {
+		return parts[0], nil
+	}
+
+	results := slices.Concat(parts...)
+	if len(orders) > 0 {
+		slices.SortStableFunc(results, func(a, b *MOD) int {
+			return compareRows(ctx, orders, a, b)
+		})
+	}
+	if paged {
+		results = results[min(offset, len(results)):]
+	}
+	if limit != nil {
+		results = results[:min(*limit, len(results))]
+	}
+	return results, nil
+}
+
+// FindPage retrieves a page of the records ordered across the shards
+// FindPage 检索跨分片排序后的一页记录
+func (repo *ShardedRepo[MOD, CLS, K]) FindPage(ctx context.Context, where func(db *gorm.DB, cls CLS) *gorm.DB, ordering func(cls CLS) gormcnm.OrderByBottle, page *gormrepo.Pagination) ([]*MOD, error) {
+	return repo.Find(ctx, func(db *gorm.DB, cls CLS) *gorm.DB {
+		return where(db, cls).Order(string(ordering(cls))).Limit(page.Limit).Offset(page.Offset)
+	})
+}
+
+// Count returns the number of records matching the where condition, summed across the shards
+// Count 返回符合 where 条件的记录数量，跨分片求和
+func (repo *ShardedRepo[MOD, CLS, K]) Count(ctx context.Context, where func(db *gorm.DB, cls CLS) *gorm.DB) (int64, error) {
+	shards, err := repo.readShards(repo.probe(where))
+	if err != nil {
+		return 0, err
+	}
+	var counts = make([]int64, len(shards))
+	if err := repo.fanOut(shards, func(idx int, shard *gorm.DB) error {
+		count, err := repo.base.With(ctx, shard).Count(where)
+		counts[idx] = count
+		return err
+	}); err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	return total, nil
+}
+
+// Create inserts the record into the shard of its key
+// Create 将记录插入其键所在的分片
+func (repo *ShardedRepo[MOD, CLS, K]) Create(ctx context.Context, one *MOD) error {
+	shard, err := repo.objectShard(ctx, one)
+	if err != nil {
+		return err
+	}
+	return repo.base.With(ctx, repo.shards[shard]).Create(one)
+}
+
+// Creates inserts the records, which must all belong to one shard
+// Creates 插入这些记录，它们必须都属于同一个分片
+func (repo *ShardedRepo[MOD, CLS, K]) Creates(ctx context.Context, ones []*MOD) error {
+	shard, err := repo.objectShard(ctx, ones...)
+	if err != nil {
+		return err
+	}
+	return repo.base.With(ctx, repo.shards[shard]).Creates(ones)
+}
+
+// Save updates the record, or inserts it when new, in the shard of its key
+// Save 在其键所在的分片中更新记录，新记录时插入
+func (repo *ShardedRepo[MOD, CLS, K]) Save(ctx context.Context, one *MOD) error {
+	shard, err := repo.objectShard(ctx, one)
+	if err != nil {
+		return err
+	}
+	return repo.base.With(ctx, repo.shards[shard]).Save(one)
+}
+
+// Delete deletes the record from the shard of its key
+// Delete 从其键所在的分片删除记录
+func (repo *ShardedRepo[MOD, CLS, K]) Delete(ctx context.Context, one *MOD) error {
+	shard, err := repo.objectShard(ctx, one)
+	if err != nil {
+		return err
+	}
+	return repo.base.With(ctx, repo.shards[shard]).Delete(one)
+}
+
+// Update updates one column of the records matching the where condition, which must pin one shard
+// Update 更新符合 where 条件的记录的单个列，where 条件必须限定到一个分片
+func (repo *ShardedRepo[MOD, CLS, K]) Update(ctx context.Context, where func(db *gorm.DB, cls CLS) *gorm.DB, valueFunc func(cls CLS) (string, interface{})) error {
+	shard, err := repo.writeShard(where)
+	if err != nil {
+		return err
+	}
+	return repo.base.With(ctx, repo.shards[shard]).Update(where, valueFunc)
+}
+
+// Updates updates the columns of the records matching the where condition, which must pin one shard
+// Updates 更新符合 where 条件的记录的多个列，where 条件必须限定到一个分片
+func (repo *ShardedRepo[MOD, CLS, K]) Updates(ctx context.Context, where func(db *gorm.DB, cls CLS) *gorm.DB, mapValues func(cls CLS) map[string]interface{}) error {
+	shard, err := repo.writeShard(where)
+	if err != nil {
+		return err
+	}
+	return repo.base.With(ctx, repo.shards[shard]).Updates(where, mapValues)
+}
+
+// UpdatesO updates the record by its primary key in the shard of its key, so the shard key of the object must be set
+// UpdatesO 在记录键所在的分片中按主键更新记录，因此对象的分片键必须已设置
+func (repo *ShardedRepo[MOD, CLS, K]) UpdatesO(ctx context.Context, object *MOD, newValues func(cls CLS) gormcnm.ColumnValueMap) error {
+	shard, err := repo.objectShard(ctx, object)
+	if err != nil {
+		return err
+	}
+	return repo.base.With(ctx, repo.shards[shard]).UpdatesO(object, newValues)
+}
+
+// UpdatesM updates the records matching the where condition, which must pin one shard
+// UpdatesM 更新符合 where 条件的记录，where 条件必须限定到一个分片
+func (repo *ShardedRepo[MOD, CLS, K]) UpdatesM(ctx context.Context, where func(db *gorm.DB, cls CLS) *gorm.DB, newValues func(cls CLS) gormcnm.ColumnValueMap) error {
+	shard, err := repo.writeShard(where)
+	if err != nil {
+		return err
+	}
+	return repo.base.With(ctx, repo.shards[shard]).UpdatesM(where, newValues)
+}
+
+// DeleteW deletes the records matching the where condition, which must pin one shard
+// DeleteW 删除符合 where 条件的记录，where 条件必须限定到一个分片
+func (repo *ShardedRepo[MOD, CLS, K]) DeleteW(ctx context.Context, where func(db *gorm.DB, cls CLS) *gorm.DB) error {
+	shard, err := repo.writeShard(where)
+	if err != nil {
+		return err
+	}
+	return repo.base.With(ctx, repo.shards[shard]).DeleteW(where)
+}
+
+// probe builds the statement of the where condition without running it
+// probe 构建 where 条件的语句而不运行它
+func (repo *ShardedRepo[MOD, CLS, K]) probe(where func(db *gorm.DB, cls CLS) *gorm.DB) *gorm.Statement {
+	return where(repo.shards[0].Session(&gorm.Session{NewDB: true}).Model((*MOD)(nil)), repo.cls).Statement
+}
+
+func (repo *ShardedRepo[MOD, CLS, K]) schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: repo.shards[0]}
+	if err := stmt.Parse(new(MOD)); err != nil {
+		return nil, errors.WithMessage(err, "parse sharded schema")
+	}
+	return stmt.Schema, nil
+}
+
+// readShards returns the shards pinned by the where statement, all the shards when it pins none
+// readShards 返回 where 语句限定的分片，未限定时返回所有分片
+func (repo *ShardedRepo[MOD, CLS, K]) readShards(stmt *gorm.Statement) ([]*gorm.DB, error) {
+	keys, ok := whereKeys(stmt, repo.keyColumn)
+	if !ok {
+		return repo.shards, nil
+	}
+	indexes, err := repo.locateKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	var res = make([]*gorm.DB, 0, len(indexes))
+	for _, idx := range indexes {
+		res = append(res, repo.shards[idx])
+	}
+	return res, nil
+}
+
+// whereShard returns the one shard pinned by the where condition
+// whereShard 返回 where 条件限定的唯一分片
+func (repo *ShardedRepo[MOD, CLS, K]) whereShard(where func(db *gorm.DB, cls CLS) *gorm.DB) (int, error) {
+	keys, ok := whereKeys(repo.probe(where), repo.keyColumn)
+	if !ok {
+		return 0, errors.WithMessagef(ErrShardKeyRequired, "where condition does not pin %s", repo.keyColumn)
+	}
+	indexes, err := repo.locateKeys(keys)
+	if err != nil {
+		return 0, err
+	}
+	if len(indexes) != 1 {
+		return 0, errors.WithMessagef(ErrShardKeyRequired, "where condition pins %d shards", len(indexes))
+	}
+	return indexes[0], nil
+}
+
+// writeShard returns the one shard written by the where condition, ErrCrossShard when it may write more
+// writeShard 返回 where 条件写入的唯一分片，可能写入多个分片时返回 ErrCrossShard
+func (repo *ShardedRepo[MOD, CLS, K]) writeShard(where func(db *gorm.DB, cls CLS) *gorm.DB) (int, error) {
+	shard, err := repo.whereShard(where)
+	if errors.Is(err, ErrShardKeyRequired) {
+		return 0, errors.WithMessage(ErrCrossShard, err.Error())
+	}
+	return shard, err
+}
+
+// objectShard returns the one shard of the objects, ErrCrossShard when they belong to several
+// Objects whose shard key is the zero value are rejected with ErrShardKeyRequired, instead of being routed as key 0
+//
+// objectShard 返回这些对象所属的唯一分片，属于多个分片时返回 ErrCrossShard
+// 分片键为零值的对象以 ErrShardKeyRequired 拒绝，而不是当作键 0 路由
+func (repo *ShardedRepo[MOD, CLS, K]) objectShard(ctx context.Context, ones ...*MOD) (int, error) {
+	sch, err := repo.schema()
+	if err != nil {
+		return 0, err
+	}
+	field := sch.LookUpField(repo.keyColumn)
+	if field == nil {
+		return 0, errors.Errorf("gormshard: shard key column %s is not a field of %s", repo.keyColumn, sch.Name)
+	}
+	var keys = make([]interface{}, 0, len(ones))
+	for _, one := range ones {
+		value, isZero := field.ValueOf(ctx, reflect.ValueOf(one).Elem())
+		if isZero {
+			return 0, errors.WithMessagef(ErrShardKeyRequired, "shard key %s is not set", repo.keyColumn)
+		}
+		keys = append(keys, value)
+	}
+	indexes, err := repo.locateKeys(keys)
+	if err != nil {
+		return 0, err
+	}
+	switch len(indexes) {
+	case 0:
+		return 0, errors.WithMessage(ErrShardKeyRequired, "no records")
+	case 1:
+		return indexes[0], nil
+	default:
+		return 0, errors.WithMessagef(ErrCrossShard, "records belong to %d shards", len(indexes))
+	}
+}
+
+// locateKeys returns the sorted distinct shards of the keys
+// locateKeys 返回这些键所在的分片，已排序且去重
+func (repo *ShardedRepo[MOD, CLS, K]) locateKeys(keys []interface{}) ([]int, error) {
+	var indexes []int
+	for _, value := range keys {
+		key, err := keyOf[K](value)
+		if err != nil {
+			return nil, err
+		}
+		if idx := repo.locate(key, len(repo.shards)); !slices.Contains(indexes, idx) {
+			indexes = append(indexes, idx)
+		}
+	}
+	slices.Sort(indexes)
+	return indexes, nil
+}
+
+// fanOut runs the function on each shard, at most concurrency at the same time
+// fanOut 在每个分片上运行函数，同时最多运行 concurrency 个
+func (repo *ShardedRepo[MOD, CLS, K]) fanOut(shards []*gorm.DB, run func(idx int, shard *gorm.DB) error) error {
+	if len(shards) == 1 {
+		return run(0, shards[0])
+	}
+	var eg errgroup.Group
+	eg.SetLimit(repo.concurrency)
+	for idx, shard := range shards {
+		eg.Go(func() error {
+			return run(idx, shard)
+		})
+	}
+	return eg.Wait()
+}
+
+// keyOf converts the value to the key type, e.g. the int of a where condition to the uint64 of the column
+// keyOf 将值转换为键类型，例如将 where 条件中的 int 转换为列的 uint64
+func keyOf[K any](value interface{}) (K, error) {
+	if key, ok := value.(K); ok {
+		return key, nil
+	}
+	var key K
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	typ := reflect.TypeOf(&key).Elem()
+	if !rv.IsValid() || kindOf(rv.Kind()) == "" || kindOf(rv.Kind()) != kindOf(typ.Kind()) {
+		return key, errors.Errorf("gormshard: shard key %v is not a %s", value, typ)
+	}
+	reflect.ValueOf(&key).Elem().Set(rv.Convert(typ))
+	return key, nil
+}
+
+// kindOf groups the kinds converting to each other without changing the meaning of the key
+// kindOf 将可相互转换且不改变键含义的类型种类归为一组
+func kindOf(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.String:
+		return "string"
+	default:
+		return ""
+	}
+}
+
+// compareRows compares the rows by the ordering columns
+// compareRows 按排序列比较行
+func compareRows[MOD any](ctx context.Context, orders []orderKey, a, b *MOD) int {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for _, order := range orders {
+		res := order.compare(order.field.ReflectValueOf(ctx, va), order.field.ReflectValueOf(ctx, vb))
+		if order.desc {
+			res = -res
+		}
+		if res != 0 {
+			return res
+		}
+	}
+	return 0
+}
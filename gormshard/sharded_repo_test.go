@@ -0,0 +1,307 @@
+package gormshard_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcngen"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormshard"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/osexistpath/osmustexist"
+	"github.com/yyle88/runpath"
+	"gorm.io/gorm"
+)
+
+type Order struct {
+	ID       uint
+	UserID   uint64
+	Amount   int64
+	Priority *int32
+}
+
+func (*Order) TableName() string {
+	return "orders"
+}
+
+// Tests the generation of columns for models.
+// 测试模型列的生成。
+func TestGenerateColumns(t *testing.T) {
+	absPath := runpath.Path() // Retrieve the absolute path of the source file based on the current test file's location
+	// 获取当前测试文件位置基础上的源文件绝对路径
+	t.Log(absPath)
+
+	// Check the existence of the target file. The file should be created beforehand to ensure it can be located via the code.
+	// 检查目标文件是否存在。文件应手动创建，确保代码能够找到它。
+	require.True(t, osmustexist.IsFile(absPath))
+
+	// List the models to have columns generated. Both instance and non-instance types are supported.
+	// 设置需要生成列的模型，这里支持指针类型和非指针类型。
+	objects := []any{&Order{}}
+
+	options := gormcngen.NewOptions().
+		WithColumnClassExportable(true). // Generate exportable struct names (e.g., ExampleColumns) // 生成可导出的结构体名称（例如 ExampleColumns）
+		WithColumnsMethodRecvName("a").
+		WithColumnsCheckFieldType(true)
+
+	// Configure code generation settings
+	// 配置代码生成设置
+	cfg := gormcngen.NewConfigs(objects, options, absPath).
+		WithIsGenPreventEdit(false)
+	cfg.Gen() // Generate and write the code to the target location (e.g., "gormcnm.gen.go") // 生成并将代码写入目标位置（例如 "gormcnm.gen.go"）
+}
+
+// userShardKey shards the orders by user, users are located by user_id % 3, so shard 0 holds users 3 and 6
+// userShardKey 按用户对订单分片，用户按 user_id % 3 定位，因此分片 0 保存用户 3 和 6
+func userShardKey(cls *OrderColumns) gormcnm.ColumnName[uint64] {
+	return cls.UserID
+}
+
+// setupOrders seeds the orders of users 1 to 6 with amounts 10*ID
+// setupOrders 写入用户 1 到 6 的订单，金额为 10*ID
+func setupOrders(t *testing.T, repo *gormshard.ShardedRepo[Order, *OrderColumns, uint64]) {
+	ctx := context.Background()
+	for id := uint(1); id <= 6; id++ {
+		require.NoError(t, repo.Create(ctx, &Order{ID: id, UserID: uint64(id), Amount: int64(id) * 10}))
+	}
+}
+
+func countOf(t *testing.T, db *gorm.DB) int64 {
+	var count int64
+	require.NoError(t, db.Model(&Order{}).Count(&count).Error)
+	return count
+}
+
+func TestShardedRepo_Create(t *testing.T) {
+	shards := []*gorm.DB{tests.NewMemDBWithTables(t, &Order{}), tests.NewMemDBWithTables(t, &Order{}), tests.NewMemDBWithTables(t, &Order{})}
+	repo := gormshard.NewShardedRepo(&Order{}, (&Order{}).Columns(), userShardKey, shards...)
+	setupOrders(t, repo)
+	ctx := context.Background()
+
+	for _, shard := range shards {
+		require.Equal(t, int64(2), countOf(t, shard))
+	}
+	var order Order
+	require.NoError(t, shards[0].Where("user_id = ?", 3).First(&order).Error)
+
+	// Records of several shards cannot be written together
+	// 多个分片的记录不能一起写入
+	err := repo.Creates(ctx, []*Order{{ID: 7, UserID: 7}, {ID: 8, UserID: 8}})
+	require.ErrorIs(t, err, gormshard.ErrCrossShard)
+	require.NoError(t, repo.Creates(ctx, []*Order{{ID: 7, UserID: 7}, {ID: 10, UserID: 10}}))
+	require.Equal(t, int64(4), countOf(t, shards[1]))
+
+	// Records without a shard key are rejected, rather than stored on the shard of key 0
+	// 没有分片键的记录被拒绝，而不是存入键 0 所在的分片
+	err = repo.Create(ctx, &Order{ID: 11, Amount: 110})
+	require.ErrorIs(t, err, gormshard.ErrShardKeyRequired)
+	err = repo.Creates(ctx, []*Order{{ID: 12, UserID: 3}, {ID: 13}})
+	require.ErrorIs(t, err, gormshard.ErrShardKeyRequired)
+	err = repo.UpdatesO(ctx, &Order{ID: 3}, func(cls *OrderColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Amount.Kv(0))
+	})
+	require.ErrorIs(t, err, gormshard.ErrShardKeyRequired)
+	err = repo.Delete(ctx, &Order{ID: 3})
+	require.ErrorIs(t, err, gormshard.ErrShardKeyRequired)
+	require.Equal(t, int64(2), countOf(t, shards[0]))
+}
+
+func TestShardedRepo_Reads(t *testing.T) {
+	shards := []*gorm.DB{tests.NewMemDBWithTables(t, &Order{}), tests.NewMemDBWithTables(t, &Order{}), tests.NewMemDBWithTables(t, &Order{})}
+	repo := gormshard.NewShardedRepo(&Order{}, (&Order{}).Columns(), userShardKey, shards...)
+	setupOrders(t, repo)
+	ctx := context.Background()
+
+	order, err := repo.First(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.UserID.Eq(5))
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(50), order.Amount)
+
+	_, err = repo.First(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.Amount.Eq(50))
+	})
+	require.ErrorIs(t, err, gormshard.ErrShardKeyRequired)
+
+	orders, err := repo.Find(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.UserID.In([]uint64{1, 4, 5}))
+	})
+	require.NoError(t, err)
+	require.Len(t, orders, 3)
+
+	count, err := repo.Count(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.Amount.Gte(20))
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), count)
+}
+
+func TestShardedRepo_FindPage(t *testing.T) {
+	shards := []*gorm.DB{tests.NewMemDBWithTables(t, &Order{}), tests.NewMemDBWithTables(t, &Order{}), tests.NewMemDBWithTables(t, &Order{})}
+	repo := gormshard.NewShardedRepo(&Order{}, (&Order{}).Columns(), userShardKey, shards...)
+	setupOrders(t, repo)
+	ctx := context.Background()
+	repo.WithConcurrency(2)
+
+	orders, err := repo.FindPage(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.Amount.Gt(10))
+	}, func(cls *OrderColumns) gormcnm.OrderByBottle {
+		return cls.Amount.Ob("desc")
+	}, &gormrepo.Pagination{Limit: 3, Offset: 1})
+	require.NoError(t, err)
+	var amounts []int64
+	for _, order := range orders {
+		amounts = append(amounts, order.Amount)
+	}
+	require.Equal(t, []int64{50, 40, 30}, amounts)
+
+	// The ordering and the limit of the where condition apply to the merged records
+	// where 条件中的排序和 limit 作用于合并后的记录
+	orders, err = repo.Find(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Order(cls.Amount.Ob("asc").Ox()).Limit(2)
+	})
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	require.Equal(t, int64(10), orders[0].Amount)
+	require.Equal(t, int64(20), orders[1].Amount)
+
+	// An offset without a limit skips the merged records once, not once per shard
+	// 没有 limit 的 offset 只跳过一次合并后的记录，而不是每个分片各跳过一次
+	orders, err = repo.Find(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Order(cls.Amount.Ob("asc").Ox()).Offset(2)
+	})
+	require.NoError(t, err)
+	require.Len(t, orders, 4)
+	require.Equal(t, int64(30), orders[0].Amount)
+}
+
+// postgresDialector reports the name of postgres, so the merge follows the NULL ordering of postgres
+// postgresDialector 报告 postgres 的名称，使合并遵循 postgres 的 NULL 排序
+type postgresDialector struct {
+	gorm.Dialector
+}
+
+func (postgresDialector) Name() string {
+	return "postgres"
+}
+
+func TestShardedRepo_FindNullOrdering(t *testing.T) {
+	shards := []*gorm.DB{tests.NewMemDBWithTables(t, &Order{}), tests.NewMemDBWithTables(t, &Order{}), tests.NewMemDBWithTables(t, &Order{})}
+	repo := gormshard.NewShardedRepo(&Order{}, (&Order{}).Columns(), userShardKey, shards...)
+	setupOrders(t, repo)
+	ctx := context.Background()
+
+	for _, id := range []uint{2, 5} {
+		priority := int32(id)
+		require.NoError(t, repo.UpdatesO(ctx, &Order{ID: id, UserID: uint64(id)}, func(cls *OrderColumns) gormcnm.ColumnValueMap {
+			return cls.Kw(cls.Priority.Kv(&priority))
+		}))
+	}
+	orderIDs := func() []uint {
+		orders, err := repo.Find(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+			return db.Order(cls.Priority.Ob("asc").Ox()).Order(cls.ID.Ob("asc").Ox())
+		})
+		require.NoError(t, err)
+		var ids []uint
+		for _, order := range orders {
+			ids = append(ids, order.ID)
+		}
+		return ids
+	}
+
+	// SQLite sorts NULL before the values, so does the merge
+	// SQLite 将 NULL 排在值之前，合并也如此
+	require.Equal(t, []uint{1, 3, 4, 6, 2, 5}, orderIDs())
+
+	// Postgres sorts NULL after the values, so does the merge
+	// Postgres 将 NULL 排在值之后，合并也如此
+	shards[0].Dialector = postgresDialector{Dialector: shards[0].Dialector}
+	require.Equal(t, []uint{2, 5, 1, 3, 4, 6}, orderIDs())
+}
+
+func TestShardedRepo_Writes(t *testing.T) {
+	shards := []*gorm.DB{tests.NewMemDBWithTables(t, &Order{}), tests.NewMemDBWithTables(t, &Order{}), tests.NewMemDBWithTables(t, &Order{})}
+	repo := gormshard.NewShardedRepo(&Order{}, (&Order{}).Columns(), userShardKey, shards...)
+	setupOrders(t, repo)
+	ctx := context.Background()
+
+	require.NoError(t, repo.UpdatesM(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.UserID.Eq(2))
+	}, func(cls *OrderColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Amount.Kv(200))
+	}))
+	var order Order
+	require.NoError(t, shards[2].Where("user_id = ?", 2).First(&order).Error)
+	require.Equal(t, int64(200), order.Amount)
+
+	require.NoError(t, repo.Update(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.UserID.Eq(2))
+	}, func(cls *OrderColumns) (string, interface{}) {
+		return cls.Amount.Kv(201)
+	}))
+	order.Amount = 0
+	require.NoError(t, repo.UpdatesO(ctx, &order, func(cls *OrderColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Amount.Kv(202))
+	}))
+	require.NoError(t, shards[2].Where("user_id = ?", 2).First(&order).Error)
+	require.Equal(t, int64(202), order.Amount)
+
+	// Writes not pinned to one shard are rejected
+	// 未限定到单个分片的写入被拒绝
+	err := repo.DeleteW(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.Amount.Gt(0))
+	})
+	require.ErrorIs(t, err, gormshard.ErrCrossShard)
+	err = repo.Update(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.Amount.Gt(0))
+	}, func(cls *OrderColumns) (string, interface{}) {
+		return cls.Amount.Kv(0)
+	})
+	require.ErrorIs(t, err, gormshard.ErrCrossShard)
+	err = repo.Updates(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.UserID.In([]uint64{1, 2}))
+	}, func(cls *OrderColumns) map[string]interface{} {
+		return cls.Kw(cls.Amount.Kv(0)).AsMap()
+	})
+	require.ErrorIs(t, err, gormshard.ErrCrossShard)
+	err = repo.DeleteW(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.UserID.In([]uint64{1, 2}))
+	})
+	require.ErrorIs(t, err, gormshard.ErrCrossShard)
+	err = repo.DeleteW(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.UserID.Eq(1)).Or(cls.UserID.Eq(2))
+	})
+	require.ErrorIs(t, err, gormshard.ErrCrossShard)
+
+	require.NoError(t, repo.DeleteW(ctx, func(db *gorm.DB, cls *OrderColumns) *gorm.DB {
+		return db.Where(cls.UserID.In([]uint64{1, 4}))
+	}))
+	require.Equal(t, int64(0), countOf(t, shards[1]))
+
+	require.NoError(t, repo.Transaction(ctx, 3, func(repo *gormrepo.GormRepo[Order, *OrderColumns]) error {
+		return repo.Create(&Order{ID: 9, UserID: 9})
+	}))
+	require.Equal(t, int64(3), countOf(t, shards[0]))
+}
+
+func (a *Order) Columns() *OrderColumns {
+	return &OrderColumns{
+		// Auto-generated: column names and types mapping. DO NOT EDIT. // 自动生成：列名和类型映射。请勿编辑。
+		ID:       gormcnm.Cnm(a.ID, "id"),
+		UserID:   gormcnm.Cnm(a.UserID, "user_id"),
+		Amount:   gormcnm.Cnm(a.Amount, "amount"),
+		Priority: gormcnm.Cnm(a.Priority, "priority"),
+	}
+}
+
+type OrderColumns struct {
+	// Auto-generated: embedding operation functions to make it simple to use. DO NOT EDIT. // 自动生成：嵌入操作函数便于使用。请勿编辑。
+	gormcnm.ColumnOperationClass
+	// Auto-generated: column names and types in database table. DO NOT EDIT. // 自动生成：数据库表的列名和类型。请勿编辑。
+	ID       gormcnm.ColumnName[uint]
+	UserID   gormcnm.ColumnName[uint64]
+	Amount   gormcnm.ColumnName[int64]
+	Priority gormcnm.ColumnName[*int32]
+}
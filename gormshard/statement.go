@@ -0,0 +1,252 @@
+package gormshard
+
+import (
+	"cmp"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// whereKeys returns the values the where clauses pin the column to, false when they do not pin it
+// Only top-level AND conditions count: column = value, column IN (values), their map and struct forms
+// Any top-level OR leaves the column unpinned, since the rows may then come from any shard
+//
+// whereKeys 返回 where 子句将该列限定到的值，未限定时返回 false
+// 只考虑顶层的 AND 条件：column = value、column IN (values)，以及它们的 map 和结构体形式
+// 顶层存在 OR 时视为未限定，因为此时行可能来自任意分片
+func whereKeys(stmt *gorm.Statement, column string) ([]interface{}, bool) {
+	where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return nil, false
+	}
+	for _, expr := range where.Exprs {
+		if _, ok := expr.(clause.OrConditions); ok {
+			return nil, false
+		}
+	}
+	return exprKeys(where.Exprs, column)
+}
+
+func exprKeys(exprs []clause.Expression, column string) ([]interface{}, bool) {
+	for _, expr := range exprs {
+		switch value := expr.(type) {
+		case clause.AndConditions:
+			if keys, ok := exprKeys(value.Exprs, column); ok {
+				return keys, true
+			}
+		case clause.Eq:
+			if sameColumn(value.Column, column) {
+				return []interface{}{value.Value}, true
+			}
+		case clause.IN:
+			if sameColumn(value.Column, column) {
+				return value.Values, true
+			}
+		case clause.Expr:
+			sql := normalizeName(strings.ReplaceAll(value.SQL, " ", ""))
+			switch {
+			case len(value.Vars) != 1:
+			case sql == column+"=?" || strings.HasSuffix(sql, "."+column+"=?"):
+				return value.Vars, true
+			case sql == column+"in(?)" || strings.HasSuffix(sql, "."+column+"in(?)"):
+				return spread(value.Vars[0]), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func sameColumn(column interface{}, name string) bool {
+	switch value := column.(type) {
+	case string:
+		return columnOf(value) == name
+	case clause.Column:
+		return !value.Raw && columnOf(value.Name) == name
+	default:
+		return false
+	}
+}
+
+// columnOf returns the unquoted column name without its table
+// columnOf 返回去掉引号和表名的列名
+func columnOf(name string) string {
+	name = normalizeName(name)
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.NewReplacer("`", "", `"`, "", "[", "", "]", "").Replace(name))
+}
+
+// spread returns the elements of a slice value, or the value itself
+// spread 返回切片值的元素，或值本身
+func spread(value interface{}) []interface{} {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []interface{}{value}
+	}
+	var res = make([]interface{}, 0, rv.Len())
+	for idx := 0; idx < rv.Len(); idx++ {
+		res = append(res, rv.Index(idx).Interface())
+	}
+	return res
+}
+
+// whereLimit returns the limit and offset of the statement, a nil limit when there is none
+// whereLimit 返回语句的 limit 和 offset，没有 limit 时返回 nil
+func whereLimit(stmt *gorm.Statement) (*int, int) {
+	limit, ok := stmt.Clauses["LIMIT"].Expression.(clause.Limit)
+	if !ok {
+		return nil, 0
+	}
+	return limit.Limit, limit.Offset
+}
+
+// orderKey is one column of the ordering merging the rows of the shards
+// orderKey 是合并各分片行时排序的一个列
+type orderKey struct {
+	field   *schema.Field
+	desc    bool
+	compare func(a, b reflect.Value) int
+}
+
+// whereOrder parses the ORDER BY of the statement into fields of the schema
+// Fails when it orders by something other than plain columns, which cannot be compared in memory
+//
+// whereOrder 将语句的 ORDER BY 解析为 schema 的字段
+// 当排序依据不是普通列时失败，因为无法在内存中比较
+func whereOrder(stmt *gorm.Statement, sch *schema.Schema) ([]orderKey, error) {
+	orderBy, ok := stmt.Clauses["ORDER BY"].Expression.(clause.OrderBy)
+	if !ok {
+		return nil, nil
+	}
+	if orderBy.Expression != nil {
+		return nil, errors.New("gormshard: cannot merge an ordering expression across shards")
+	}
+	var nullsLast = nullsLastOf(stmt.DB)
+	var res []orderKey
+	for _, column := range orderBy.Columns {
+		if !column.Column.Raw {
+			key, err := newOrderKey(sch, column.Column.Name, column.Desc, nullsLast)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, key)
+			continue
+		}
+		for _, part := range strings.Split(column.Column.Name, ",") {
+			words := strings.Fields(part)
+			if len(words) == 0 || len(words) > 2 {
+				return nil, errors.Errorf("gormshard: cannot merge the ordering %q across shards", part)
+			}
+			desc := column.Desc
+			if len(words) == 2 {
+				switch strings.ToLower(words[1]) {
+				case "asc":
+				case "desc":
+					desc = true
+				default:
+					return nil, errors.Errorf("gormshard: cannot merge the ordering %q across shards", part)
+				}
+			}
+			key, err := newOrderKey(sch, words[0], desc, nullsLast)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, key)
+		}
+	}
+	return res, nil
+}
+
+func newOrderKey(sch *schema.Schema, name string, desc bool, nullsLast bool) (orderKey, error) {
+	field := sch.LookUpField(columnOf(name))
+	if field == nil {
+		return orderKey{}, errors.Errorf("gormshard: cannot merge the ordering by %q across shards", name)
+	}
+	compare := compareOf(field.FieldType, nullsLast)
+	if compare == nil {
+		return orderKey{}, errors.Errorf("gormshard: cannot merge the ordering by %q across shards", name)
+	}
+	return orderKey{field: field, desc: desc, compare: compare}, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// nullsLastOf reports whether the dialect sorts NULL after the values in ascending order, as Postgres and Oracle do
+// SQLite, MySQL and SQL Server sort NULL before the values
+//
+// nullsLastOf 判断该方言在升序中是否将 NULL 排在值之后，Postgres 和 Oracle 如此
+// SQLite、MySQL 和 SQL Server 将 NULL 排在值之前
+func nullsLastOf(db *gorm.DB) bool {
+	if db == nil || db.Dialector == nil {
+		return false
+	}
+	switch db.Dialector.Name() {
+	case "postgres", "oracle":
+		return true
+	default:
+		return false
+	}
+}
+
+// compareOf returns the comparison of values of the type, nil when the type cannot be compared
+// Nil pointers compare as NULL does on the dialect: the largest value when nullsLast, otherwise the smallest
+//
+// compareOf 返回该类型值的比较函数，类型无法比较时返回 nil
+// nil 指针按方言中 NULL 的方式比较：nullsLast 时为最大值，否则为最小值
+func compareOf(typ reflect.Type, nullsLast bool) func(a, b reflect.Value) int {
+	if typ.Kind() == reflect.Ptr {
+		compare := compareOf(typ.Elem(), nullsLast)
+		if compare == nil {
+			return nil
+		}
+		var nilFirst = -1
+		if nullsLast {
+			nilFirst = 1
+		}
+		return func(a, b reflect.Value) int {
+			switch {
+			case a.IsNil() && b.IsNil():
+				return 0
+			case a.IsNil():
+				return nilFirst
+			case b.IsNil():
+				return -nilFirst
+			default:
+				return compare(a.Elem(), b.Elem())
+			}
+		}
+	}
+	if typ == timeType {
+		return func(a, b reflect.Value) int {
+			return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+		}
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(a, b reflect.Value) int { return cmp.Compare(a.Int(), b.Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(a, b reflect.Value) int { return cmp.Compare(a.Uint(), b.Uint()) }
+	case reflect.Float32, reflect.Float64:
+		return func(a, b reflect.Value) int { return cmp.Compare(a.Float(), b.Float()) }
+	case reflect.String:
+		return func(a, b reflect.Value) int { return cmp.Compare(a.String(), b.String()) }
+	case reflect.Bool:
+		return func(a, b reflect.Value) int { return cmp.Compare(boolInt(a.Bool()), boolInt(b.Bool())) }
+	default:
+		return nil
+	}
+}
+
+func boolInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
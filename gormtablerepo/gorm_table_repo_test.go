@@ -3,6 +3,7 @@ package gormtablerepo_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -31,6 +32,18 @@ func (*Student) TableName() string {
 	return "students"
 }
 
+// Event is stored in monthly tables like events_202610
+// Event 存储在 events_202610 这样的按月表中
+type Event struct {
+	ID        uint
+	Kind      string `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (*Event) TableName() string {
+	return "events"
+}
+
 func (a *Student) Columns() *StudentColumns {
 	return a.TableColumns(gormcnm.NewPlainDecoration())
 }
@@ -66,7 +79,7 @@ func TestGenerateColumns(t *testing.T) {
 
 	// List the models to have columns generated. Both instance and non-instance types are supported.
 	// 设置需要生成列的模型，这里支持指针类型和非指针类型。
-	objects := []any{&Student{}}
+	objects := []any{&Student{}, &Event{}}
 
 	options := gormcngen.NewOptions().
 		WithColumnClassExportable(true). // Generate exportable struct names (e.g., ExampleColumns) // 生成可导出的结构体名称（例如 ExampleColumns）
@@ -158,3 +171,25 @@ func TestTableRepo_BuildColumns(t *testing.T) {
 	require.Equal(t, "B", result.Name)
 	require.Equal(t, 85, result.Score)
 }
+
+func (a *Event) Columns() *EventColumns {
+	return a.TableColumns(gormcnm.NewPlainDecoration())
+}
+
+func (a *Event) TableColumns(decoration gormcnm.ColumnNameDecoration) *EventColumns {
+	return &EventColumns{
+		// Auto-generated: column mapping in table operations. DO NOT EDIT. // 自动生成：表操作的列映射。请勿编辑。
+		ID:        gormcnm.Cmn(a.ID, "id", decoration),
+		Kind:      gormcnm.Cmn(a.Kind, "kind", decoration),
+		CreatedAt: gormcnm.Cmn(a.CreatedAt, "created_at", decoration),
+	}
+}
+
+type EventColumns struct {
+	// Auto-generated: embedding operation functions to make it simple to use. DO NOT EDIT. // 自动生成：嵌入操作函数便于使用。请勿编辑。
+	gormcnm.ColumnOperationClass
+	// Auto-generated: column names and types in database table. DO NOT EDIT. // 自动生成：数据库表的列名和类型。请勿编辑。
+	ID        gormcnm.ColumnName[uint]
+	Kind      gormcnm.ColumnName[string]
+	CreatedAt gormcnm.ColumnName[time.Time]
+}
@@ -0,0 +1,224 @@
+package gormtablerepo
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"gorm.io/gorm"
+)
+
+// ErrNoPartitions is returned when a union is asked to read no partitions
+// ErrNoPartitions 在联合查询没有要读取的分区时返回
+var ErrNoPartitions = errors.New("gormtablerepo: no partitions to union")
+
+// unionAlias is the name of the derived table holding the rows of the unioned partitions
+// unionAlias 是保存联合分区行的派生表名称
+const unionAlias = "partitions"
+
+// PartitionRepo is a TableRepo variant whose table is resolved from a key, e.g. monthly tables like events_202610
+// The partitions share MOD and CLS, so CLS should hold plain column names without a table prefix
+//
+// PartitionRepo 是根据键解析表名的 TableRepo 变体，例如 events_202610 这样的按月分表
+// 各分区共用 MOD 和 CLS，因此 CLS 应使用不带表名前缀的普通列名
+type PartitionRepo[MOD any, CLS any, K any] struct {
+	base      *gormrepo.BaseRepo[MOD, CLS]
+	cls       CLS
+	tableName func(key K) string
+}
+
+// NewPartitionRepo creates a PartitionRepo resolving the table of a key with tableName
+// The MOD param is used to deduce the type, its value is not used
+//
+// NewPartitionRepo 创建使用 tableName 解析键所在表的 PartitionRepo
+// MOD 参数用于类型推断，其值不使用
+func NewPartitionRepo[MOD any, CLS any, K any](_ *MOD, cls CLS, tableName func(key K) string) *PartitionRepo[MOD, CLS, K] {
+	return &PartitionRepo[MOD, CLS, K]{
+		base:      gormrepo.NewBaseRepo((*MOD)(nil), cls),
+		cls:       cls,
+		tableName: tableName,
+	}
+}
+
+// WithInterceptors returns a new PartitionRepo whose repos run the interceptors
+// WithInterceptors 返回新的 PartitionRepo，其仓储会运行这些拦截器
+func (repo *PartitionRepo[MOD, CLS, K]) WithInterceptors(interceptors ...gormrepo.Interceptor) *PartitionRepo[MOD, CLS, K] {
+	return &PartitionRepo[MOD, CLS, K]{
+		base:      repo.base.WithInterceptors(interceptors...),
+		cls:       repo.cls,
+		tableName: repo.tableName,
+	}
+}
+
+// GetTableName returns the table of the key
+// GetTableName 返回键所在的表
+func (repo *PartitionRepo[MOD, CLS, K]) GetTableName(key K) string {
+	return repo.tableName(key)
+}
+
+// TableNames returns the tables of the keys, without duplicates, in the order of the keys
+// TableNames 按键的顺序返回键所在的表，不含重复
+func (repo *PartitionRepo[MOD, CLS, K]) TableNames(keys ...K) []string {
+	var res []string
+	var seen = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if name := repo.tableName(key); !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// TableColumns returns the column definitions shared by the partitions
+// TableColumns 返回各分区共用的列定义
+func (repo *PartitionRepo[MOD, CLS, K]) TableColumns() CLS {
+	return repo.cls
+}
+
+// Repo creates a GormRepo bound to the table of the key
+// Repo 创建绑定到键所在表的 GormRepo
+func (repo *PartitionRepo[MOD, CLS, K]) Repo(db *gorm.DB, key K) *gormrepo.GormRepo[MOD, CLS] {
+	return repo.base.Repo(repo.bind(db, key))
+}
+
+// Gorm creates a GormWrap bound to the table of the key
+// Gorm 创建绑定到键所在表的 GormWrap
+func (repo *PartitionRepo[MOD, CLS, K]) Gorm(db *gorm.DB, key K) *gormrepo.GormWrap[MOD, CLS] {
+	return repo.base.Gorm(repo.bind(db, key))
+}
+
+// bind returns a session of db on the table of the key, whose operations do not add to each other
+// bind 返回键所在表上的 db 会话，其上的各个操作之间不会互相叠加
+func (repo *PartitionRepo[MOD, CLS, K]) bind(db *gorm.DB, key K) *gorm.DB {
+	return db.Table(repo.tableName(key)).Session(&gorm.Session{})
+}
+
+// Union returns a db reading the rows of the partitions of the keys, as one derived table joined by UNION ALL
+// The where condition filters each partition, ordering and paging are set on the returned db
+//
+// Union 返回读取各键所在分区行的 db，各分区以 UNION ALL 合并为一个派生表
+// where 条件过滤每个分区，排序和分页设置在返回的 db 上
+func (repo *PartitionRepo[MOD, CLS, K]) Union(db *gorm.DB, keys []K, where func(db *gorm.DB, cls CLS) *gorm.DB) *gorm.DB {
+	names := repo.TableNames(keys...)
+	if len(names) == 0 {
+		tx := db.Session(&gorm.Session{})
+		_ = tx.AddError(ErrNoPartitions)
+		return tx
+	}
+	var parts = make([]interface{}, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, where(db.Session(&gorm.Session{NewDB: true}).Table(name).Model(new(MOD)), repo.cls))
+	}
+	expr := strings.TrimSuffix(strings.Repeat("? UNION ALL ", len(parts)), " UNION ALL ")
+	return db.Table("("+expr+") AS "+unionAlias, parts...)
+}
+
+// UnionFind finds the records of the partitions of the keys matching the where condition
+// UnionFind 查找各键所在分区中符合 where 条件的记录
+func (repo *PartitionRepo[MOD, CLS, K]) UnionFind(db *gorm.DB, keys []K, where func(db *gorm.DB, cls CLS) *gorm.DB) ([]*MOD, error) {
+	var results []*MOD
+	if err := repo.Union(db, keys, where).Find(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// UnionFindPage finds one page of the records of the partitions of the keys, ordered across the partitions
+// UnionFindPage 查找各键所在分区记录中的一页，排序跨越所有分区
+func (repo *PartitionRepo[MOD, CLS, K]) UnionFindPage(db *gorm.DB, keys []K, where func(db *gorm.DB, cls CLS) *gorm.DB, ordering func(cls CLS) gormcnm.OrderByBottle, page *gormrepo.Pagination) ([]*MOD, error) {
+	var results []*MOD
+	if err := repo.Union(db, keys, where).Order(string(ordering(repo.cls))).Scopes(page.Scope()).Find(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// UnionCount counts the records of the partitions of the keys matching the where condition
+// UnionCount 统计各键所在分区中符合 where 条件的记录数
+func (repo *PartitionRepo[MOD, CLS, K]) UnionCount(db *gorm.DB, keys []K, where func(db *gorm.DB, cls CLS) *gorm.DB) (int64, error) {
+	var count int64
+	if err := repo.Union(db, keys, where).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// EnsureTables creates the missing partitions of the keys from the schema of MOD
+// Indexes are named after each partition, so that the partitions do not clash on them
+//
+// EnsureTables 根据 MOD 的结构创建各键缺失的分区
+// 索引按各分区命名，因此分区之间不会冲突
+func (repo *PartitionRepo[MOD, CLS, K]) EnsureTables(db *gorm.DB, keys ...K) error {
+	for _, name := range repo.TableNames(keys...) {
+		migrator := db.Table(name).Migrator()
+		if migrator.HasTable(name) {
+			continue
+		}
+		if err := migrator.CreateTable((*MOD)(nil)); err != nil {
+			return errors.WithMessagef(err, "create partition %s", name)
+		}
+	}
+	return nil
+}
+
+// MonthlyTable returns a table name func putting times into monthly tables, e.g. events_202610
+// Times are taken in their own location, use one location (e.g. UTC) for the keys
+//
+// MonthlyTable 返回将时间分到按月表中的表名函数，例如 events_202610
+// 时间按其自身时区计算，键应使用同一时区（例如 UTC）
+func MonthlyTable(prefix string) func(key time.Time) string {
+	return func(key time.Time) string {
+		return prefix + "_" + key.Format("200601")
+	}
+}
+
+// DailyTable returns a table name func putting times into daily tables, e.g. events_20261018
+// DailyTable 返回将时间分到按天表中的表名函数，例如 events_20261018
+func DailyTable(prefix string) func(key time.Time) string {
+	return func(key time.Time) string {
+		return prefix + "_" + key.Format("20060102")
+	}
+}
+
+// SuffixTable returns a table name func putting each key into its own table, e.g. events_42 for tenant 42
+// SuffixTable 返回将每个键分到各自表中的表名函数，例如租户 42 对应 events_42
+func SuffixTable[K any](prefix string) func(key K) string {
+	return func(key K) string {
+		return prefix + "_" + fmt.Sprint(key)
+	}
+}
+
+// BucketTable returns a table name func hashing keys into the buckets, e.g. events_0 to events_15 for 16 buckets
+// BucketTable 返回将键哈希到各个桶中的表名函数，例如 16 个桶对应 events_0 到 events_15
+func BucketTable[K any](prefix string, buckets int) func(key K) string {
+	return func(key K) string {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(fmt.Sprint(key)))
+		return fmt.Sprintf("%s_%d", prefix, hash.Sum32()%uint32(buckets))
+	}
+}
+
+// Months returns the first moments of the months from the month of from to the month of to, in the location of from
+// Months 返回从 from 所在月份到 to 所在月份每个月的起始时刻，使用 from 的时区
+func Months(from, to time.Time) []time.Time {
+	var res []time.Time
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()); !month.After(to); month = month.AddDate(0, 1, 0) {
+		res = append(res, month)
+	}
+	return res
+}
+
+// Days returns the first moments of the days from the day of from to the day of to, in the location of from
+// Days 返回从 from 所在日期到 to 所在日期每天的起始时刻，使用 from 的时区
+func Days(from, to time.Time) []time.Time {
+	var res []time.Time
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location()); !day.After(to); day = day.AddDate(0, 0, 1) {
+		res = append(res, day)
+	}
+	return res
+}
@@ -0,0 +1,124 @@
+package gormtablerepo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormtablerepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"gorm.io/gorm"
+)
+
+// setupEvents creates monthly partitions from 2026-08 to 2026-10, each holding one "login" and one "logout" event
+// setupEvents 创建 2026-08 到 2026-10 的按月分区，每个分区包含一个 "login" 和一个 "logout" 事件
+func setupEvents(t *testing.T, repo *gormtablerepo.PartitionRepo[Event, *EventColumns, time.Time], db *gorm.DB) {
+	months := gormtablerepo.Months(time.Date(2026, 8, 15, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	require.Len(t, months, 3)
+	require.NoError(t, repo.EnsureTables(db, months...))
+	for idx, month := range months {
+		require.NoError(t, repo.Repo(db, month).Creates([]*Event{
+			{ID: uint(idx*2 + 1), Kind: "login", CreatedAt: month.Add(time.Hour)},
+			{ID: uint(idx*2 + 2), Kind: "logout", CreatedAt: month.Add(2 * time.Hour)},
+		}))
+	}
+}
+
+func TestPartitionRepo_Repo(t *testing.T) {
+	db := tests.NewMemDB(t)
+	repo := gormtablerepo.NewPartitionRepo(&Event{}, (&Event{}).Columns(), gormtablerepo.MonthlyTable("events"))
+	setupEvents(t, repo, db)
+	october := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, "events_202610", repo.GetTableName(october))
+
+	tableRepo := repo.Repo(db, october)
+	event, err := tableRepo.First(func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db.Where(cls.Kind.Eq("logout"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint(6), event.ID)
+
+	// The operations of a repo do not add their conditions to each other
+	// 同一仓储的各个操作之间不会互相叠加条件
+	count, err := tableRepo.Count(func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	var events []*Event
+	require.NoError(t, repo.Gorm(db, october).Find(func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db.Where(cls.Kind.Eq("login"))
+	}, &events).Error)
+	require.Len(t, events, 1)
+	require.Equal(t, uint(5), events[0].ID)
+}
+
+func TestPartitionRepo_Union(t *testing.T) {
+	db := tests.NewMemDB(t)
+	repo := gormtablerepo.NewPartitionRepo(&Event{}, (&Event{}).Columns(), gormtablerepo.MonthlyTable("events"))
+	setupEvents(t, repo, db)
+	months := gormtablerepo.Months(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC))
+
+	events, err := repo.UnionFind(db, months, func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db.Where(cls.Kind.Eq("login"))
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	count, err := repo.UnionCount(db, gormtablerepo.Days(time.Date(2026, 8, 30, 0, 0, 0, 0, time.UTC), time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC)), func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), count)
+
+	events, err = repo.UnionFindPage(db, months, func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db
+	}, func(cls *EventColumns) gormcnm.OrderByBottle {
+		return cls.CreatedAt.Ob("desc")
+	}, &gormrepo.Pagination{Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, uint(5), events[0].ID)
+	require.Equal(t, uint(4), events[1].ID)
+
+	_, err = repo.UnionFind(db, nil, func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db
+	})
+	require.ErrorIs(t, err, gormtablerepo.ErrNoPartitions)
+}
+
+func TestPartitionRepo_EnsureTables(t *testing.T) {
+	db := tests.NewMemDB(t)
+	repo := gormtablerepo.NewPartitionRepo(&Event{}, (&Event{}).Columns(), gormtablerepo.MonthlyTable("events"))
+	setupEvents(t, repo, db)
+	november := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	require.False(t, db.Migrator().HasTable("events_202611"))
+
+	require.NoError(t, repo.EnsureTables(db, november, november.AddDate(0, -1, 0)))
+	require.True(t, db.Migrator().HasTable("events_202611"))
+	require.True(t, db.Migrator().HasIndex("events_202611", "idx_events_202611_kind"))
+	require.NoError(t, repo.Repo(db, november).Create(&Event{Kind: "login", CreatedAt: november}))
+
+	// Existing partitions keep their rows
+	// 已存在的分区保留其中的行
+	count, err := repo.Repo(db, november.AddDate(0, -1, 0)).Count(func(db *gorm.DB, cls *EventColumns) *gorm.DB {
+		return db
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+}
+
+func TestBucketTable(t *testing.T) {
+	tableName := gormtablerepo.BucketTable[uint64]("events", 4)
+	require.Equal(t, tableName(42), tableName(42))
+
+	var names = map[string]bool{}
+	for key := uint64(0); key < 100; key++ {
+		names[tableName(key)] = true
+	}
+	require.Len(t, names, 4)
+	require.Equal(t, "events_42", gormtablerepo.SuffixTable[int]("events")(42))
+}
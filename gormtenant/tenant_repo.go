@@ -0,0 +1,299 @@
+// Package gormtenant scopes repos to the tenant taken from the context
+// Reads, updates and deletes only see the rows of the tenant, creates and saves stamp the tenant on the records
+// Without a tenant in the context every operation fails, unless the context is marked cross-tenant
+//
+// gormtenant 将仓储限定在从上下文获取的租户范围内
+// 读取、更新和删除只能看到该租户的行，创建和保存会在记录上写入租户
+// 上下文中没有租户时所有操作都会失败，除非上下文被标记为跨租户
+package gormtenant
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormrepo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrNoTenant is returned when the context has no tenant and is not marked cross-tenant
+// ErrNoTenant 在上下文没有租户且未被标记为跨租户时返回
+var ErrNoTenant = errors.New("gormtenant: no tenant in context")
+
+// ErrTenantMismatch is returned when a write would touch or move a record of another tenant
+// ErrTenantMismatch 在写入会涉及或转移其他租户的记录时返回
+var ErrTenantMismatch = errors.New("gormtenant: record of another tenant")
+
+// ErrUpsertNotScoped is returned by Upsert in a tenant context, since its conflict update may hit the row of another tenant
+// ErrUpsertNotScoped 在租户上下文中由 Upsert 返回，因为其冲突更新可能命中其他租户的行
+var ErrUpsertNotScoped = errors.New("gormtenant: upsert cannot be scoped to the tenant")
+
+// TenantColumnFace is implemented by column structs (CLS) declaring the tenant column
+// TenantColumnFace 由声明租户列的列结构体（CLS）实现
+type TenantColumnFace interface {
+	TenantColumn() gormrepo.ColumnNameFace
+}
+
+type tenantKey struct{}
+
+type crossTenantKey struct{}
+
+// WithTenant returns a context scoped to the tenant
+// WithTenant 返回限定在该租户范围内的上下文
+func WithTenant(ctx context.Context, tenantID interface{}) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFrom returns the tenant of the context
+// Zero values (e.g. 0 or "") are not tenants, so that an unset ID cannot scope the operations to them
+//
+// TenantFrom 返回上下文的租户
+// 零值（例如 0 或 ""）不是租户，避免未设置的 ID 将操作限定到这些值上
+func TenantFrom(ctx context.Context) (interface{}, bool) {
+	tenantID := ctx.Value(tenantKey{})
+	if tenantID == nil || reflect.ValueOf(tenantID).IsZero() {
+		return nil, false
+	}
+	return tenantID, true
+}
+
+// CrossTenant returns a context whose operations see the rows of all tenants, e.g. for admin jobs and migrations
+// Its creates and saves keep the tenant set on the records
+//
+// CrossTenant 返回的上下文中操作可以看到所有租户的行，例如用于管理任务和数据迁移
+// 其创建和保存保留记录上已设置的租户
+func CrossTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, crossTenantKey{}, true)
+}
+
+// IsCrossTenant reports whether the context is marked cross-tenant
+// IsCrossTenant 判断上下文是否被标记为跨租户
+func IsCrossTenant(ctx context.Context) bool {
+	cross, _ := ctx.Value(crossTenantKey{}).(bool)
+	return cross
+}
+
+// TenantRepo is a BaseRepo variant scoping its repos to the tenant of the context
+// The tenant predicate is set on the connection, so it also covers the where funcs of the extension helpers (aggregates, pluck, keyset)
+// FirstOrCreate inserts as OpCreate, so its record gets the tenant; Upsert fails with ErrUpsertNotScoped unless the context is cross-tenant
+//
+// TenantRepo 是将其仓储限定在上下文租户范围内的 BaseRepo 变体
+// 租户条件设置在连接上，因此也覆盖扩展辅助方法（聚合、pluck、键集分页）的 where 函数
+// FirstOrCreate 以 OpCreate 插入，因此其记录会写入租户；Upsert 以 ErrUpsertNotScoped 失败，除非上下文是跨租户的
+type TenantRepo[MOD any, CLS TenantColumnFace] struct {
+	base *gormrepo.BaseRepo[MOD, CLS]
+	cls  CLS
+}
+
+// NewTenantRepo creates a TenantRepo using the tenant column declared by CLS
+// The MOD param is used to deduce the type, its value is not used
+//
+// NewTenantRepo 使用 CLS 声明的租户列创建 TenantRepo
+// MOD 参数用于类型推断，其值不使用
+func NewTenantRepo[MOD any, CLS TenantColumnFace](_ *MOD, cls CLS) *TenantRepo[MOD, CLS] {
+	repo := &TenantRepo[MOD, CLS]{cls: cls}
+	repo.base = gormrepo.NewBaseRepo((*MOD)(nil), cls).WithInterceptors(repo.Interceptor())
+	return repo
+}
+
+// WithInterceptors returns a new TenantRepo whose repos also run the interceptors, inside the tenant interceptor
+// WithInterceptors 返回新的 TenantRepo，其仓储还会运行这些拦截器，位于租户拦截器之内
+func (repo *TenantRepo[MOD, CLS]) WithInterceptors(interceptors ...gormrepo.Interceptor) *TenantRepo[MOD, CLS] {
+	return &TenantRepo[MOD, CLS]{
+		base: repo.base.WithInterceptors(interceptors...),
+		cls:  repo.cls,
+	}
+}
+
+// With creates a GormRepo scoped to the tenant of the context
+// Every operation of the repo fails with ErrNoTenant when the context has no tenant and is not cross-tenant
+//
+// With 创建限定在上下文租户范围内的 GormRepo
+// 上下文没有租户且不是跨租户时，仓储的所有操作都以 ErrNoTenant 失败
+func (repo *TenantRepo[MOD, CLS]) With(ctx context.Context, db *gorm.DB) *gormrepo.GormRepo[MOD, CLS] {
+	return repo.base.Repo(repo.scope(db.WithContext(ctx)))
+}
+
+// Bind creates a GormRepo on the unit of work, scoped to the tenant of its context
+// Bind 在工作单元上创建 GormRepo，限定在其上下文的租户范围内
+func (repo *TenantRepo[MOD, CLS]) Bind(uow *gormrepo.UnitOfWork) *gormrepo.GormRepo[MOD, CLS] {
+	return repo.base.Repo(repo.scope(uow.DB()))
+}
+
+// Transaction runs the function in a transaction, with a GormRepo scoped to the tenant of the context
+// Transaction 在事务中运行函数，传入限定在上下文租户范围内的 GormRepo
+func (repo *TenantRepo[MOD, CLS]) Transaction(ctx context.Context, db *gorm.DB, run func(repo *gormrepo.GormRepo[MOD, CLS]) error, opts ...*sql.TxOptions) error {
+	return gormrepo.Transaction(ctx, db, func(uow *gormrepo.UnitOfWork) error {
+		return run(repo.Bind(uow))
+	}, opts...)
+}
+
+// scope returns a session of db filtered to the tenant of the context each operation runs with
+// The filter is a GORM scope, evaluated when each statement executes, so a repo moved to another context by WithContext
+// filters and stamps by the same tenant, and the operations run on the session do not add their conditions to each other
+//
+// scope 返回按每个操作运行时上下文的租户过滤的 db 会话
+// 过滤条件是 GORM scope，在每条语句执行时求值，因此通过 WithContext 切换到其他上下文的仓储
+// 过滤和写入使用同一个租户，且在该会话上运行的各个操作之间不会互相叠加条件
+func (repo *TenantRepo[MOD, CLS]) scope(db *gorm.DB) *gorm.DB {
+	return db.Scopes(repo.filter).Session(&gorm.Session{})
+}
+
+// filter adds the tenant predicate of the statement context, failing with ErrNoTenant when there is none
+// filter 添加语句上下文的租户条件，没有租户时以 ErrNoTenant 失败
+func (repo *TenantRepo[MOD, CLS]) filter(db *gorm.DB) *gorm.DB {
+	ctx := db.Statement.Context
+	if IsCrossTenant(ctx) {
+		return db
+	}
+	tenantID, ok := TenantFrom(ctx)
+	if !ok {
+		_ = db.AddError(ErrNoTenant)
+		return db
+	}
+	return db.Where(clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: repo.cls.TenantColumn().Name()},
+		Value:  tenantID,
+	})
+}
+
+// Interceptor returns the interceptor stamping the tenant on created and saved records
+// It rejects updates changing the tenant column, saves overwriting records of other tenants, and upserts
+// Used by the repos of TenantRepo, it can also guard other repos of MOD, whose reads it does not filter
+//
+// Interceptor 返回在创建和保存的记录上写入租户的拦截器
+// 它会拒绝修改租户列的更新、覆盖其他租户记录的保存，以及 upsert
+// 由 TenantRepo 的仓储使用，也可以保护 MOD 的其他仓储，但不会过滤其读取
+func (repo *TenantRepo[MOD, CLS]) Interceptor() gormrepo.Interceptor {
+	return func(inv *gormrepo.Invocation, next func() error) error {
+		if IsCrossTenant(inv.Context) {
+			return next()
+		}
+		tenantID, ok := TenantFrom(inv.Context)
+		if !ok {
+			return ErrNoTenant
+		}
+		if inv.Operation.IsRead() {
+			return next()
+		}
+		// The conflicting row is found by the conflict columns alone, so the update could overwrite the row of another tenant
+		// 冲突的行仅由冲突列确定，因此更新可能覆盖其他租户的行
+		if inv.Operation == gormrepo.OpUpsert {
+			return errors.WithMessage(ErrUpsertNotScoped, "use FirstOrCreate, or upsert in a cross-tenant context")
+		}
+		field, err := repo.tenantField(inv.DB)
+		if err != nil {
+			return err
+		}
+		if value, ok := inv.Values[field.DBName]; ok && !sameTenant(value, tenantID) {
+			return errors.WithMessagef(ErrTenantMismatch, "cannot move records to tenant %v", value)
+		}
+		switch inv.Operation {
+		case gormrepo.OpCreate, gormrepo.OpCreates, gormrepo.OpCreateInBatches:
+			if err := stamp(inv, field, tenantID); err != nil {
+				return err
+			}
+		case gormrepo.OpSave, gormrepo.OpSaves:
+			if err := stamp(inv, field, tenantID); err != nil {
+				return err
+			}
+			if err := checkOwner[MOD](inv, field, tenantID); err != nil {
+				return err
+			}
+		}
+		return next()
+	}
+}
+
+func (repo *TenantRepo[MOD, CLS]) tenantField(db *gorm.DB) (*schema.Field, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(MOD)); err != nil {
+		return nil, errors.WithMessage(err, "parse tenant schema")
+	}
+	field := stmt.Schema.LookUpField(repo.cls.TenantColumn().Name())
+	if field == nil {
+		return nil, errors.Errorf("gormtenant: tenant column %s is not a field of %s", repo.cls.TenantColumn().Name(), stmt.Schema.Name)
+	}
+	return field, nil
+}
+
+// stamp sets the tenant on the records, failing when one already has another tenant
+// stamp 在记录上设置租户，当某条记录已属于其他租户时失败
+func stamp(inv *gormrepo.Invocation, field *schema.Field, tenantID interface{}) error {
+	for _, rv := range objectValues(inv.Object) {
+		if value, isZero := field.ValueOf(inv.Context, rv); !isZero && !sameTenant(value, tenantID) {
+			return errors.WithMessagef(ErrTenantMismatch, "record has tenant %v", value)
+		}
+		if err := field.Set(inv.Context, rv, tenantID); err != nil {
+			return errors.WithMessage(err, "set tenant")
+		}
+	}
+	return nil
+}
+
+// checkOwner fails when a saved primary key belongs to a row of another tenant
+// Save falls back to an upsert when its update matches no row, which would otherwise overwrite that row
+//
+// checkOwner 在保存的主键属于其他租户的行时失败
+// Save 在更新未匹配到行时会回退为 upsert，否则会覆盖该行
+func checkOwner[MOD any](inv *gormrepo.Invocation, field *schema.Field, tenantID interface{}) error {
+	primaryField := field.Schema.PrioritizedPrimaryField
+	if primaryField == nil {
+		return nil
+	}
+	var keys []interface{}
+	for _, rv := range objectValues(inv.Object) {
+		if value, isZero := primaryField.ValueOf(inv.Context, rv); !isZero {
+			keys = append(keys, value)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	// A new statement drops the tenant predicate, so that rows of other tenants are seen
+	// 新的语句会去掉租户条件，从而可以看到其他租户的行
+	var count int64
+	if err := inv.DB.Session(&gorm.Session{NewDB: true}).Model((*MOD)(nil)).
+		Where(clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: primaryField.DBName}, Values: keys}).
+		Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID}).
+		Count(&count).Error; err != nil {
+		return errors.WithMessage(err, "check tenant of saved records")
+	}
+	if count > 0 {
+		return errors.WithMessagef(ErrTenantMismatch, "%d saved records", count)
+	}
+	return nil
+}
+
+// objectValues returns the struct values of the *MOD or []*MOD written
+// objectValues 返回写入的 *MOD 或 []*MOD 的结构体值
+func objectValues(object interface{}) []reflect.Value {
+	rv := reflect.ValueOf(object)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return []reflect.Value{rv.Elem()}
+	case reflect.Slice:
+		var res = make([]reflect.Value, 0, rv.Len())
+		for idx := 0; idx < rv.Len(); idx++ {
+			if elem := rv.Index(idx); !elem.IsNil() {
+				res = append(res, elem.Elem())
+			}
+		}
+		return res
+	default:
+		return nil
+	}
+}
+
+// sameTenant compares tenant IDs by their text, so that e.g. int 1 and uint64 1 are the same tenant
+// sameTenant 按文本比较租户 ID，例如 int 1 和 uint64 1 视为同一租户
+func sameTenant(a, b interface{}) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
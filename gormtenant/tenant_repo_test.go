@@ -0,0 +1,298 @@
+package gormtenant_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcngen"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormclass"
+	"github.com/yyle88/gormrepo/gormtenant"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/osexistpath/osmustexist"
+	"github.com/yyle88/runpath"
+	"gorm.io/gorm"
+)
+
+type Invoice struct {
+	ID       uint
+	TenantID uint64 `gorm:"index"`
+	Number   string
+	Total    int64
+}
+
+func (*Invoice) TableName() string {
+	return "invoices"
+}
+
+// TenantColumn declares TenantID as the tenant column of Invoice
+// TenantColumn 声明 TenantID 为 Invoice 的租户列
+func (a *InvoiceColumns) TenantColumn() gormrepo.ColumnNameFace {
+	return a.TenantID
+}
+
+// Tests the generation of columns for models.
+// 测试模型列的生成。
+func TestGenerateColumns(t *testing.T) {
+	absPath := runpath.Path() // Retrieve the absolute path of the source file based on the current test file's location
+	// 获取当前测试文件位置基础上的源文件绝对路径
+	t.Log(absPath)
+
+	// Check the existence of the target file. The file should be created beforehand to ensure it can be located via the code.
+	// 检查目标文件是否存在。文件应手动创建，确保代码能够找到它。
+	require.True(t, osmustexist.IsFile(absPath))
+
+	// List the models to have columns generated. Both instance and non-instance types are supported.
+	// 设置需要生成列的模型，这里支持指针类型和非指针类型。
+	objects := []any{&Invoice{}}
+
+	options := gormcngen.NewOptions().
+		WithColumnClassExportable(true). // Generate exportable struct names (e.g., ExampleColumns) // 生成可导出的结构体名称（例如 ExampleColumns）
+		WithColumnsMethodRecvName("a").
+		WithColumnsCheckFieldType(true)
+
+	// Configure code generation settings
+	// 配置代码生成设置
+	cfg := gormcngen.NewConfigs(objects, options, absPath).
+		WithIsGenPreventEdit(false)
+	cfg.Gen() // Generate and write the code to the target location (e.g., "gormcnm.gen.go") // 生成并将代码写入目标位置（例如 "gormcnm.gen.go"）
+}
+
+// setupInvoices seeds invoices 1 and 2 of tenant 1 and invoice 3 of tenant 2
+// setupInvoices 写入租户 1 的发票 1 和 2 以及租户 2 的发票 3
+func setupInvoices(t *testing.T, db *gorm.DB) {
+	require.NoError(t, db.Create([]*Invoice{
+		{ID: 1, TenantID: 1, Number: "A-1", Total: 100},
+		{ID: 2, TenantID: 1, Number: "A-2", Total: 200},
+		{ID: 3, TenantID: 2, Number: "B-1", Total: 300},
+	}).Error)
+}
+
+func TestTenantRepo_Reads(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Invoice{})
+	setupInvoices(t, db)
+	repo := gormtenant.NewTenantRepo(gormclass.Use(&Invoice{}))
+	ctx := gormtenant.WithTenant(context.Background(), 1)
+
+	invoices, err := repo.With(ctx, db).Find(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db
+	})
+	require.NoError(t, err)
+	require.Len(t, invoices, 2)
+
+	_, err = repo.With(ctx, db).First(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db.Where(cls.ID.Eq(3))
+	})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// The predicate also covers the extension helpers running without the interceptors
+	// 条件同样覆盖不经过拦截器运行的扩展辅助方法
+	sum, err := gormrepo.Sum(repo.With(ctx, db), func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db
+	}, func(cls *InvoiceColumns) gormcnm.ColumnName[int64] {
+		return cls.Total
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(300), sum.V)
+
+	// The operations of a repo do not add their conditions to each other
+	// 同一仓储的各个操作之间不会互相叠加条件
+	tenantRepo := repo.With(ctx, db)
+	for _, id := range []uint{1, 2} {
+		invoice, err := tenantRepo.First(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+			return db.Where(cls.ID.Eq(id))
+		})
+		require.NoError(t, err)
+		require.Equal(t, id, invoice.ID)
+	}
+
+	count, err := repo.With(gormtenant.CrossTenant(context.Background()), db).Count(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+}
+
+func TestTenantRepo_FailClosed(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Invoice{})
+	setupInvoices(t, db)
+	repo := gormtenant.NewTenantRepo(gormclass.Use(&Invoice{}))
+	ctx := context.Background()
+
+	_, err := repo.With(ctx, db).Find(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db
+	})
+	require.ErrorIs(t, err, gormtenant.ErrNoTenant)
+
+	_, err = gormrepo.PluckColumn(repo.With(ctx, db), func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db
+	}, func(cls *InvoiceColumns) gormcnm.ColumnName[string] {
+		return cls.Number
+	})
+	require.ErrorIs(t, err, gormtenant.ErrNoTenant)
+
+	require.ErrorIs(t, repo.With(ctx, db).DeleteW(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db
+	}), gormtenant.ErrNoTenant)
+	require.ErrorIs(t, repo.With(ctx, db).Create(&Invoice{Number: "X-1"}), gormtenant.ErrNoTenant)
+
+	// Zero tenant IDs count as missing
+	// 零值的租户 ID 视为缺失
+	for _, tenantID := range []interface{}{0, uint64(0), ""} {
+		_, err := repo.With(gormtenant.WithTenant(ctx, tenantID), db).Find(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+			return db
+		})
+		require.ErrorIs(t, err, gormtenant.ErrNoTenant)
+		require.ErrorIs(t, repo.With(gormtenant.WithTenant(ctx, tenantID), db).Create(&Invoice{Number: "X-2"}), gormtenant.ErrNoTenant)
+	}
+
+	var count int64
+	require.NoError(t, db.Model(&Invoice{}).Count(&count).Error)
+	require.Equal(t, int64(3), count)
+}
+
+func TestTenantRepo_WithContext(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Invoice{})
+	setupInvoices(t, db)
+	repo := gormtenant.NewTenantRepo(gormclass.Use(&Invoice{}))
+
+	// Filtering and stamping both follow the context the repo runs with
+	// 过滤和写入都使用仓储运行时的上下文
+	tenantRepo := repo.With(gormtenant.WithTenant(context.Background(), 1), db).WithContext(gormtenant.WithTenant(context.Background(), 2))
+	invoices, err := tenantRepo.Find(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db
+	})
+	require.NoError(t, err)
+	require.Len(t, invoices, 1)
+	require.Equal(t, uint(3), invoices[0].ID)
+
+	invoice := &Invoice{Number: "B-2"}
+	require.NoError(t, tenantRepo.Create(invoice))
+	require.Equal(t, uint64(2), invoice.TenantID)
+
+	_, err = repo.With(gormtenant.WithTenant(context.Background(), 1), db).WithContext(context.Background()).Find(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db
+	})
+	require.ErrorIs(t, err, gormtenant.ErrNoTenant)
+}
+
+func TestTenantRepo_Writes(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Invoice{})
+	setupInvoices(t, db)
+	repo := gormtenant.NewTenantRepo(gormclass.Use(&Invoice{}))
+	ctx := gormtenant.WithTenant(context.Background(), 2)
+
+	// Creates stamp the tenant, and reject records of another tenant
+	// 创建会写入租户，并拒绝其他租户的记录
+	invoice := &Invoice{Number: "B-2", Total: 400}
+	require.NoError(t, repo.With(ctx, db).Create(invoice))
+	require.Equal(t, uint64(2), invoice.TenantID)
+	require.ErrorIs(t, repo.With(ctx, db).Create(&Invoice{TenantID: 1, Number: "A-3"}), gormtenant.ErrTenantMismatch)
+
+	// Updates and deletes only touch the rows of the tenant
+	// 更新和删除只涉及该租户的行
+	require.NoError(t, repo.With(ctx, db).UpdateExactly(2, func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db
+	}, func(cls *InvoiceColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Total.KeAdd(1))
+	}))
+	require.ErrorIs(t, repo.With(ctx, db).UpdatesM(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db
+	}, func(cls *InvoiceColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.TenantID.Kv(1))
+	}), gormtenant.ErrTenantMismatch)
+
+	require.NoError(t, repo.With(ctx, db).Delete(&Invoice{ID: 1}))
+	var one Invoice
+	require.NoError(t, db.First(&one, 1).Error)
+	require.Equal(t, int64(100), one.Total)
+
+	// Saves cannot take over the rows of another tenant
+	// 保存不能接管其他租户的行
+	require.ErrorIs(t, repo.With(ctx, db).Save(&Invoice{ID: 2, Number: "B-9"}), gormtenant.ErrTenantMismatch)
+	var two Invoice
+	require.NoError(t, db.First(&two, 2).Error)
+	require.Equal(t, "A-2", two.Number)
+
+	require.NoError(t, repo.Transaction(ctx, db, func(repo *gormrepo.GormRepo[Invoice, *InvoiceColumns]) error {
+		invoice.Number = "B-2b"
+		return repo.Save(invoice)
+	}))
+	var res Invoice
+	require.NoError(t, db.First(&res, invoice.ID).Error)
+	require.Equal(t, "B-2b", res.Number)
+	require.Equal(t, uint64(2), res.TenantID)
+}
+
+func TestTenantRepo_UpsertAndFirstOrCreate(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &Invoice{})
+	setupInvoices(t, db)
+	repo := gormtenant.NewTenantRepo(gormclass.Use(&Invoice{}))
+	ctx := gormtenant.WithTenant(context.Background(), 1)
+	conflictColumns := func(cls *InvoiceColumns) []gormrepo.ColumnNameFace {
+		return []gormrepo.ColumnNameFace{cls.ID}
+	}
+	updateColumns := func(cls *InvoiceColumns) []gormrepo.ColumnNameFace {
+		return []gormrepo.ColumnNameFace{cls.Number, cls.Total}
+	}
+
+	// Upserts could overwrite the rows of another tenant, so they are rejected
+	// upsert 可能覆盖其他租户的行，因此被拒绝
+	_, err := repo.With(ctx, db).Upsert(&Invoice{ID: 3, Number: "A-9", Total: 900}, conflictColumns, updateColumns)
+	require.ErrorIs(t, err, gormtenant.ErrUpsertNotScoped)
+	var three Invoice
+	require.NoError(t, db.First(&three, 3).Error)
+	require.Equal(t, "B-1", three.Number)
+	require.Equal(t, uint64(2), three.TenantID)
+
+	// Cross-tenant contexts upsert the records as given
+	// 跨租户上下文按给定的记录执行 upsert
+	inserted, err := repo.With(gormtenant.CrossTenant(ctx), db).Upsert(&Invoice{ID: 3, TenantID: 2, Number: "B-1b", Total: 301}, conflictColumns, updateColumns)
+	require.NoError(t, err)
+	require.False(t, inserted)
+	require.NoError(t, db.First(&three, 3).Error)
+	require.Equal(t, "B-1b", three.Number)
+
+	// FirstOrCreate looks up the rows of the tenant and stamps the tenant on the created record
+	// FirstOrCreate 查找该租户的行，并在创建的记录上写入租户
+	invoice, created, err := repo.With(ctx, db).FirstOrCreate(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db.Where(cls.Number.Eq("B-1b"))
+	}, func() *Invoice {
+		return &Invoice{Number: "B-1b", Total: 10}
+	})
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, uint64(1), invoice.TenantID)
+	var res Invoice
+	require.NoError(t, db.First(&res, invoice.ID).Error)
+	require.Equal(t, uint64(1), res.TenantID)
+
+	_, _, err = repo.With(ctx, db).FirstOrCreate(func(db *gorm.DB, cls *InvoiceColumns) *gorm.DB {
+		return db.Where(cls.Number.Eq("A-8"))
+	}, func() *Invoice {
+		return &Invoice{TenantID: 2, Number: "A-8"}
+	})
+	require.ErrorIs(t, err, gormtenant.ErrTenantMismatch)
+}
+
+func (a *Invoice) Columns() *InvoiceColumns {
+	return &InvoiceColumns{
+		// Auto-generated: column names and types mapping. DO NOT EDIT. // 自动生成：列名和类型映射。请勿编辑。
+		ID:       gormcnm.Cnm(a.ID, "id"),
+		TenantID: gormcnm.Cnm(a.TenantID, "tenant_id"),
+		Number:   gormcnm.Cnm(a.Number, "number"),
+		Total:    gormcnm.Cnm(a.Total, "total"),
+	}
+}
+
+type InvoiceColumns struct {
+	// Auto-generated: embedding operation functions to make it simple to use. DO NOT EDIT. // 自动生成：嵌入操作函数便于使用。请勿编辑。
+	gormcnm.ColumnOperationClass
+	// Auto-generated: column names and types in database table. DO NOT EDIT. // 自动生成：数据库表的列名和类型。请勿编辑。
+	ID       gormcnm.ColumnName[uint]
+	TenantID gormcnm.ColumnName[uint64]
+	Number   gormcnm.ColumnName[string]
+	Total    gormcnm.ColumnName[int64]
+}
@@ -0,0 +1,196 @@
+package gormrepo
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// Operation names the GormRepo method or extension helper seen by interceptors
+// The E and R variants report the operation of their base method, e.g. FirstE as OpFirst and DeleteWR as OpDeleteW
+// Each batch of Iterate is one OpIterate, and the insert of FirstOrCreate is an OpCreate
+//
+// Operation 表示拦截器看到的 GormRepo 方法或扩展辅助函数
+// E 和 R 变体报告其基础方法的操作，例如 FirstE 报告为 OpFirst，DeleteWR 报告为 OpDeleteW
+// Iterate 的每个批次是一次 OpIterate，FirstOrCreate 的插入是一次 OpCreate
+type Operation string
+
+// Operations of GormRepo
+// GormRepo 的操作
+const (
+	OpFirst            Operation = "first"
+	OpTake             Operation = "take"
+	OpLast             Operation = "last"
+	OpFindOne          Operation = "find_one"
+	OpExist            Operation = "exist"
+	OpFind             Operation = "find"
+	OpFindN            Operation = "find_n"
+	OpFindC            Operation = "find_c"
+	OpFindPage         Operation = "find_page"
+	OpFindPageAndCount Operation = "find_page_and_count"
+	OpCount            Operation = "count"
+	OpFindAfter        Operation = "find_after"
+	OpFindBefore       Operation = "find_before"
+	OpIterate          Operation = "iterate"
+	OpPluckColumn      Operation = "pluck_column"
+	OpDistinctColumn   Operation = "distinct_column"
+	OpScalarOf         Operation = "scalar_of"
+	OpSum              Operation = "sum"
+	OpAvg              Operation = "avg"
+	OpMin              Operation = "min"
+	OpMax              Operation = "max"
+	OpGroupCount       Operation = "group_count"
+	OpGroupAgg         Operation = "group_agg"
+	OpFindByIDs        Operation = "find_by_ids"
+	OpFindByColumnIn   Operation = "find_by_column_in"
+	OpFindMap          Operation = "find_map"
+	OpFindGroup        Operation = "find_group"
+	OpUpdate           Operation = "update"
+	OpUpdates          Operation = "updates"
+	OpUpdatesO         Operation = "updates_o"
+	OpUpdatesC         Operation = "updates_c"
+	OpInvoke           Operation = "invoke"
+	OpCreate           Operation = "create"
+	OpCreates          Operation = "creates"
+	OpCreateInBatches  Operation = "create_in_batches"
+	OpSave             Operation = "save"
+	OpSaves            Operation = "saves"
+	OpDelete           Operation = "delete"
+	OpDeleteW          Operation = "delete_w"
+	OpDeleteM          Operation = "delete_m"
+	OpUpsert           Operation = "upsert"
+)
+
+// IsRead reports whether the operation only reads
+// IsRead 判断操作是否只读
+func (op Operation) IsRead() bool {
+	switch op {
+	case OpFirst, OpTake, OpLast, OpFindOne, OpExist, OpFind, OpFindN, OpFindC, OpFindPage, OpFindPageAndCount, OpCount,
+		OpFindAfter, OpFindBefore, OpIterate, OpPluckColumn, OpDistinctColumn, OpScalarOf,
+		OpSum, OpAvg, OpMin, OpMax, OpGroupCount, OpGroupAgg, OpFindByIDs, OpFindByColumnIn, OpFindMap, OpFindGroup:
+		return true
+	default:
+		return false
+	}
+}
+
+// Invocation describes one GormRepo call passing through the interceptors
+// Interceptors may replace DB (e.g. add a predicate) and replace or mutate Values before calling next, updates are built from them
+//
+// Invocation 描述经过拦截器的一次 GormRepo 调用
+// 拦截器可以在调用 next 之前替换 DB（例如添加条件）以及替换或修改 Values，更新语句根据它们构建
+type Invocation struct {
+	Context      context.Context        // Context of the repo connection // 仓储连接的上下文
+	Operation    Operation              // Called operation // 调用的操作
+	ModelType    reflect.Type           // Type of MOD // MOD 的类型
+	TableName    string                 // Table of MOD, empty when the schema cannot be parsed // MOD 的表名，无法解析 schema 时为空
+	Where        interface{}            // The where func(db *gorm.DB, cls CLS) *gorm.DB, nil when the operation has none // where 函数，操作没有时为 nil
+	Object       interface{}            // The *MOD or []*MOD written, nil on reads and where-based writes // 写入的 *MOD 或 []*MOD，读取和基于 where 的写入时为 nil
+	Values       map[string]interface{} // Column values of update operations // 更新操作的列值
+	Dest         interface{}            // Destination of read operations, e.g. *MOD, *[]*MOD, *int64, *bool or the *[]T of PluckColumn // 读取操作的目标
+	DB           *gorm.DB               // Connection the operation runs on // 操作运行所用的连接
+	RowsAffected int64                  // Rows affected by the last statement, set after next returns // 最后一条语句影响的行数，next 返回后设置
+
+	run func(db *gorm.DB) *gorm.DB // Runs the operation on db, used to render its statement // 在 db 上运行操作，用于渲染其语句
+}
+
+// ObjectsOf returns the *MOD or []*MOD of Invocation.Object as a slice, nil when it holds neither
+// ObjectsOf 以切片形式返回 Invocation.Object 中的 *MOD 或 []*MOD，两者都不是时返回 nil
+func ObjectsOf[MOD any](object interface{}) []*MOD {
+	switch value := object.(type) {
+	case *MOD:
+		return []*MOD{value}
+	case []*MOD:
+		return value
+	default:
+		return nil
+	}
+}
+
+// Interceptor wraps GormRepo operations, running code before and after next and seeing its error
+// Returning without calling next short-circuits the operation, a read then returns what the interceptor put into Dest
+// Interceptors cover the operations listed above; GormWrap runs directly
+//
+// Interceptor 包装 GormRepo 操作，在 next 前后运行代码并能看到其错误
+// 不调用 next 直接返回会短路该操作，读取操作此时返回拦截器放入 Dest 的内容
+// 拦截器覆盖上面列出的操作；GormWrap 直接运行
+type Interceptor func(inv *Invocation, next func() error) error
+
+// WithInterceptors returns a new BaseRepo whose repos run the interceptors, appended after the existing ones
+// The first interceptor is the outermost one
+//
+// WithInterceptors 返回新的 BaseRepo，其仓储会运行这些拦截器，追加在已有拦截器之后
+// 第一个拦截器位于最外层
+func (repo *BaseRepo[MOD, CLS]) WithInterceptors(interceptors ...Interceptor) *BaseRepo[MOD, CLS] {
+	return &BaseRepo[MOD, CLS]{
+		cls:          repo.cls,
+		interceptors: appendInterceptors(repo.interceptors, interceptors),
+	}
+}
+
+// WithInterceptors returns a new GormRepo running the interceptors, appended after the existing ones
+// WithInterceptors 返回运行这些拦截器的新 GormRepo，追加在已有拦截器之后
+func (repo *GormRepo[MOD, CLS]) WithInterceptors(interceptors ...Interceptor) *GormRepo[MOD, CLS] {
+	return &GormRepo[MOD, CLS]{
+		db:           repo.db,
+		cls:          repo.cls,
+		interceptors: appendInterceptors(repo.interceptors, interceptors),
+	}
+}
+
+// derive returns a GormRepo on db keeping the CLS and the interceptors of repo
+// derive 返回基于 db 的 GormRepo，保留 repo 的 CLS 和拦截器
+func (repo *GormRepo[MOD, CLS]) derive(db *gorm.DB) *GormRepo[MOD, CLS] {
+	return &GormRepo[MOD, CLS]{
+		db:           db,
+		cls:          repo.cls,
+		interceptors: repo.interceptors,
+	}
+}
+
+// wrapOf returns a GormWrap on db with the CLS of repo
+// wrapOf 返回基于 db 且使用 repo 的 CLS 的 GormWrap
+func (repo *GormRepo[MOD, CLS]) wrapOf(db *gorm.DB) *GormWrap[MOD, CLS] {
+	return NewGormWrap(db, (*MOD)(nil), repo.cls)
+}
+
+// intercept runs the operation through the interceptors and returns the rows affected by its last statement
+// Without interceptors run is called directly, with no Invocation built
+//
+// intercept 让操作经过拦截器运行，返回其最后一条语句影响的行数
+// 没有拦截器时直接调用 run，不构建 Invocation
+func (repo *GormRepo[MOD, CLS]) intercept(inv *Invocation, run func(db *gorm.DB) *gorm.DB) (int64, error) {
+	if len(repo.interceptors) == 0 {
+		return rowsAffected(run(repo.db))
+	}
+	inv.Context = repo.db.Statement.Context
+	inv.ModelType = reflect.TypeOf((*MOD)(nil)).Elem()
+	if sch, err := ParseSchema[MOD](repo.db); err == nil {
+		inv.TableName = sch.Table
+	}
+	inv.DB = repo.db
+	inv.run = run
+
+	var next = func() error {
+		result := run(inv.DB)
+		inv.RowsAffected = result.RowsAffected
+		return result.Error
+	}
+	for idx := len(repo.interceptors) - 1; idx >= 0; idx-- {
+		interceptor, following := repo.interceptors[idx], next
+		next = func() error {
+			return interceptor(inv, following)
+		}
+	}
+	if err := next(); err != nil {
+		return 0, err
+	}
+	return inv.RowsAffected, nil
+}
+
+func appendInterceptors(existing []Interceptor, interceptors []Interceptor) []Interceptor {
+	var res = make([]Interceptor, 0, len(existing)+len(interceptors))
+	res = append(res, existing...)
+	return append(res, interceptors...)
+}
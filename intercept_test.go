@@ -0,0 +1,272 @@
+package gormrepo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/rese"
+	"gorm.io/gorm"
+)
+
+// TestBaseRepo_WithInterceptors tests the order of the chain and the invocation seen by interceptors
+// TestBaseRepo_WithInterceptors 测试拦截器链的顺序以及拦截器看到的调用信息
+func TestBaseRepo_WithInterceptors(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	var events []string
+	var seen []gormrepo.Invocation
+	trace := func(name string) gormrepo.Interceptor {
+		return func(inv *gormrepo.Invocation, next func() error) error {
+			events = append(events, name+" before "+string(inv.Operation))
+			err := next()
+			events = append(events, name+" after "+string(inv.Operation))
+			seen = append(seen, *inv)
+			return err
+		}
+	}
+
+	base := gormrepo.NewBaseRepo(&Account{}, (&Account{}).Columns()).
+		WithInterceptors(trace("outer")).
+		WithInterceptors(trace("inner"))
+	repo := base.Repo(db)
+
+	_, err := repo.First(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"outer before first", "inner before first", "inner after first", "outer after first"}, events)
+
+	inv := seen[0]
+	require.Equal(t, gormrepo.OpFirst, inv.Operation)
+	require.True(t, inv.Operation.IsRead())
+	require.Equal(t, reflect.TypeOf(Account{}), inv.ModelType)
+	require.Equal(t, "accounts", inv.TableName)
+	require.NotNil(t, inv.Where)
+	require.NotNil(t, inv.Context)
+
+	// Update values and rows affected are visible to the interceptors
+	// 拦截器可以看到更新值和影响的行数
+	seen = nil
+	require.NoError(t, repo.UpdatesM(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	}, func(cls *AccountColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Nickname.Kv("new-nickname"))
+	}))
+	require.Len(t, seen, 2)
+	require.Equal(t, gormrepo.OpUpdates, seen[0].Operation)
+	require.False(t, seen[0].Operation.IsRead())
+	require.Equal(t, map[string]interface{}{"nickname": "new-nickname"}, seen[0].Values)
+	require.Equal(t, int64(1), seen[0].RowsAffected)
+
+	// Derived repos keep the interceptors
+	// 派生的仓储保留拦截器
+	seen = nil
+	require.NoError(t, repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+		_, err := repo.Mold().Count(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db
+		})
+		return err
+	}))
+	require.Len(t, seen, 2)
+	require.Equal(t, gormrepo.OpCount, seen[0].Operation)
+}
+
+// TestGormRepo_WithInterceptors_Error tests that interceptors see the error of the operation
+// TestGormRepo_WithInterceptors_Error 测试拦截器可以看到操作的错误
+func TestGormRepo_WithInterceptors_Error(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	var captured error
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{})).WithInterceptors(func(inv *gormrepo.Invocation, next func() error) error {
+		captured = next()
+		return captured
+	})
+
+	_, erb := repo.FirstE(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("missing"))
+	})
+	require.NotNil(t, erb)
+	require.True(t, erb.NotExist)
+	require.ErrorIs(t, captured, gorm.ErrRecordNotFound)
+}
+
+// TestGormRepo_WithInterceptors_ShortCircuit tests interceptors answering reads and rejecting writes without running them
+// TestGormRepo_WithInterceptors_ShortCircuit 测试拦截器不运行操作而直接应答读取和拒绝写入
+func TestGormRepo_WithInterceptors_ShortCircuit(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	errDenied := errors.New("denied")
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{})).WithInterceptors(func(inv *gormrepo.Invocation, next func() error) error {
+		if !inv.Operation.IsRead() {
+			return errDenied
+		}
+		if dest, ok := inv.Dest.(*Account); ok && inv.Operation == gormrepo.OpFirst {
+			*dest = Account{Username: "cached"}
+			return nil
+		}
+		return next()
+	})
+
+	res, err := repo.First(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, "cached", res.Username)
+
+	require.ErrorIs(t, repo.DeleteW(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	}), errDenied)
+	require.ErrorIs(t, repo.Create(&Account{Username: "demo-3-username"}), errDenied)
+
+	count, err := repo.Count(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+}
+
+// TestGormRepo_WithInterceptors_DB tests an interceptor narrowing the query through the connection
+// TestGormRepo_WithInterceptors_DB 测试拦截器通过连接收窄查询
+func TestGormRepo_WithInterceptors_DB(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{})).WithInterceptors(func(inv *gormrepo.Invocation, next func() error) error {
+		inv.DB = inv.DB.Where("username = ?", "demo-2-username")
+		return next()
+	})
+
+	accounts, err := repo.Find(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, "demo-2-username", accounts[0].Username)
+}
+
+// TestGormRepo_WithInterceptors_Values tests updates built from the values replaced by an interceptor
+// TestGormRepo_WithInterceptors_Values 测试根据拦截器替换的值构建更新
+func TestGormRepo_WithInterceptors_Values(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{})).WithInterceptors(func(inv *gormrepo.Invocation, next func() error) error {
+		if !inv.Operation.IsRead() {
+			inv.Values = map[string]interface{}{"nickname": "replaced-nickname"}
+		}
+		return next()
+	})
+
+	require.NoError(t, repo.Update(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	}, func(cls *AccountColumns) (string, interface{}) {
+		return cls.Nickname.Kv("new-nickname")
+	}))
+	account, err := repo.First(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, "replaced-nickname", account.Nickname)
+
+	require.NoError(t, repo.UpdatesO(account, func(cls *AccountColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Nickname.Kv("other-nickname"))
+	}))
+	require.Equal(t, "replaced-nickname", rese.V1(repo.First(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	})).Nickname)
+}
+
+// TestGormRepo_WithInterceptors_Helpers tests that the extension helpers run through the interceptors with their own operations
+// TestGormRepo_WithInterceptors_Helpers 测试扩展辅助函数以各自的操作经过拦截器运行
+func TestGormRepo_WithInterceptors_Helpers(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	var operations []gormrepo.Operation
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{})).WithInterceptors(func(inv *gormrepo.Invocation, next func() error) error {
+		operations = append(operations, inv.Operation)
+		if inv.Operation.IsRead() {
+			inv.DB = inv.DB.Where("username = ?", "demo-2-username")
+		}
+		return next()
+	})
+	everything := func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	}
+
+	page, err := repo.FindAfter(everything, func(cls *AccountColumns) []gormrepo.KeysetColumn {
+		return []gormrepo.KeysetColumn{gormrepo.KeysetAsc(cls.Username)}
+	}, &gormrepo.KeysetPagination{Limit: 5, Codec: gormrepo.NewCursorCodec([]byte("keyset-secret"))})
+	require.NoError(t, err)
+	require.Len(t, page.Records, 1)
+
+	for one, err := range repo.Iterate(everything, nil, 5) {
+		require.NoError(t, err)
+		require.Equal(t, "demo-2-username", one.Username)
+	}
+
+	nicknames, err := gormrepo.PluckColumn(repo, everything, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+		return cls.Nickname
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"demo-2-nickname"}, nicknames)
+
+	sum, err := gormrepo.Sum(repo, everything, func(cls *AccountColumns) gormcnm.ColumnName[uint] {
+		return cls.ID
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint(2), sum.V)
+
+	groups, err := gormrepo.GroupCount(repo, everything, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+		return cls.Username
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"demo-2-username": 1}, groups)
+
+	chunks, err := gormrepo.FindByIDs(repo, []uint{1, 2}, nil)
+	require.NoError(t, err)
+	require.Len(t, chunks.Records, 1)
+	require.Equal(t, []uint{1}, chunks.Missing)
+
+	accounts, err := gormrepo.FindMap(repo, everything, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+		return cls.Username
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+
+	_, err = repo.Upsert(&Account{Username: "demo-3-username"}, func(cls *AccountColumns) []gormrepo.ColumnNameFace {
+		return []gormrepo.ColumnNameFace{cls.Username}
+	}, func(cls *AccountColumns) []gormrepo.ColumnNameFace {
+		return []gormrepo.ColumnNameFace{cls.Nickname}
+	})
+	require.NoError(t, err)
+
+	_, created, err := repo.FirstOrCreate(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-4-username"))
+	}, func() *Account {
+		return &Account{Username: "demo-4-username"}
+	})
+	require.NoError(t, err)
+	require.True(t, created)
+
+	require.Equal(t, []gormrepo.Operation{
+		gormrepo.OpFindAfter,
+		gormrepo.OpIterate,
+		gormrepo.OpPluckColumn,
+		gormrepo.OpSum,
+		gormrepo.OpGroupCount,
+		gormrepo.OpFindByIDs,
+		gormrepo.OpFindMap,
+		gormrepo.OpUpsert,
+		gormrepo.OpFirst,
+		gormrepo.OpCreate,
+	}, operations)
+}
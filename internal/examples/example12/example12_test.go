@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/yyle88/done"
 	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
 	"github.com/yyle88/gormrepo/internal/examples/example12/internal/models"
 	"github.com/yyle88/rese"
 	"gorm.io/driver/sqlite"
@@ -276,3 +277,41 @@ func TestComplexAggregationWithFilters(t *testing.T) {
 
 	t.Logf("Total high-value orders: %d", totalHighValueCount)
 }
+
+// TestTypedAggregation 演示使用类型安全的聚合函数替代手写 SQL 字符串
+func TestTypedAggregation(t *testing.T) {
+	repo := gormrepo.NewGormRepo(gormrepo.Use(testDB, &models.SaleRecord{}))
+
+	// 按区域统计记录数，返回 map[string]int64
+	regionCounts, err := gormrepo.GroupCount(repo, func(db *gorm.DB, cls *models.SaleRecordColumns) *gorm.DB {
+		return db
+	}, func(cls *models.SaleRecordColumns) gormcnm.ColumnName[string] {
+		return cls.Region
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), regionCounts["North"])
+
+	// 按分类聚合销售额，返回 map[string]*Aggregate[float64]
+	categoryStats, err := gormrepo.GroupAgg(repo, func(db *gorm.DB, cls *models.SaleRecordColumns) *gorm.DB {
+		return db
+	}, func(cls *models.SaleRecordColumns) gormcnm.ColumnName[string] {
+		return cls.Category
+	}, func(cls *models.SaleRecordColumns) gormcnm.ColumnName[float64] {
+		return cls.TotalAmount
+	})
+	require.NoError(t, err)
+	for category, stats := range categoryStats {
+		t.Logf("%s: Sales=$%.2f, Records=%d, Avg=$%.2f, Max=$%.2f",
+			category, stats.Sum.V, stats.Count, stats.Avg.V, stats.Max.V)
+	}
+	require.Equal(t, int64(5), categoryStats["Electronics"].Count)
+
+	// 没有匹配记录时 SUM 为 NULL，Valid 为 false，而不是静默返回 0
+	totalQty, err := gormrepo.Sum(repo, func(db *gorm.DB, cls *models.SaleRecordColumns) *gorm.DB {
+		return db.Where(cls.Region.Eq("Nowhere"))
+	}, func(cls *models.SaleRecordColumns) gormcnm.ColumnName[int] {
+		return cls.Quantity
+	})
+	require.NoError(t, err)
+	require.False(t, totalQty.Valid)
+}
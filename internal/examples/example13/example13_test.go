@@ -1,13 +1,16 @@
 package example13_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcnm"
 	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormclass"
 	"github.com/yyle88/gormrepo/internal/examples/example13/internal/models"
 	"github.com/yyle88/must"
 	"github.com/yyle88/rese"
@@ -459,3 +462,58 @@ func TestConcurrentTransactions(t *testing.T) {
 	t.Logf("Concurrent transactions completed - Initial: $%.2f, Final: $%.2f, Expected: $%.2f",
 		initialBalance, finalAccount.Balance, expectedBalance)
 }
+
+// TestUnitOfWorkTransfer 演示使用工作单元在同一事务中操作多个模型的仓储
+func TestUnitOfWorkTransfer(t *testing.T) {
+	accountRepo := gormrepo.NewBaseRepo(gormclass.Use(&models.Account{}))
+	txnRepo := gormrepo.NewBaseRepo(gormclass.Use(&models.Transaction{}))
+
+	fromAccount := "ACC003"
+	toAccount := "ACC004"
+	transferAmount := 500.00
+
+	balanceOf := func(accountNumber string) float64 {
+		account, err := accountRepo.Repo(testDB).First(func(db *gorm.DB, cls *models.AccountColumns) *gorm.DB {
+			return db.Where(cls.AccountNumber.Eq(accountNumber))
+		})
+		require.NoError(t, err)
+		return account.Balance
+	}
+	fromBalance := balanceOf(fromAccount)
+	toBalance := balanceOf(toAccount)
+
+	// 在一个事务中完成扣款、加款和交易记录
+	err := gormrepo.Transaction(context.Background(), testDB, func(uow *gormrepo.UnitOfWork) error {
+		txAccountRepo := accountRepo.Bind(uow)
+
+		if err := txAccountRepo.UpdatesM(func(db *gorm.DB, cls *models.AccountColumns) *gorm.DB {
+			return db.Where(cls.AccountNumber.Eq(fromAccount))
+		}, func(cls *models.AccountColumns) gormcnm.ColumnValueMap {
+			return cls.Kw(cls.Balance.KeAdd(-transferAmount))
+		}); err != nil {
+			return err
+		}
+		if err := txAccountRepo.UpdatesM(func(db *gorm.DB, cls *models.AccountColumns) *gorm.DB {
+			return db.Where(cls.AccountNumber.Eq(toAccount))
+		}, func(cls *models.AccountColumns) gormcnm.ColumnValueMap {
+			return cls.Kw(cls.Balance.KeAdd(transferAmount))
+		}); err != nil {
+			return err
+		}
+
+		return txnRepo.Bind(uow).Create(&models.Transaction{
+			TransactionID:     fmt.Sprintf("TXN-%d", time.Now().UnixNano()),
+			FromAccountNumber: fromAccount,
+			ToAccountNumber:   toAccount,
+			Amount:            transferAmount,
+			TransactionType:   "TRANSFER",
+			Description:       "Unit of work transfer",
+			Status:            "COMPLETED",
+			Reference:         "UOW001",
+		})
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, fromBalance-transferAmount, balanceOf(fromAccount))
+	require.Equal(t, toBalance+transferAmount, balanceOf(toAccount))
+}
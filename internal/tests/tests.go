@@ -33,9 +33,33 @@ func NewDBRun(t *testing.T, run func(db *gorm.DB)) {
 // NewMemDB 创建内存 SQLite 数据库连接，自动清理
 // 返回数据库连接，通过 t.Cleanup 处理清理工作
 func NewMemDB(t *testing.T) *gorm.DB {
-	dsn := fmt.Sprintf("file:db-%s?mode=memory&cache=shared", uuid.New().String())
+	return openMemDB(t, "", logger.Info)
+}
+
+// NewMemDBWithTables creates an in-mem SQLite database like NewMemDB, with the tables of the models migrated
+// Seed the rows in each test, so the data a test relies on stays visible in it
+// NewMemDBWithTables 与 NewMemDB 一样创建内存 SQLite 数据库，并迁移这些模型的表
+// 在各个测试中写入数据行，使测试依赖的数据在测试中可见
+func NewMemDBWithTables(t *testing.T, models ...interface{}) *gorm.DB {
+	db := NewMemDB(t)
+	must.Done(db.AutoMigrate(models...))
+	return db
+}
+
+// NewConcurrentMemDB creates an in-mem SQLite database for concurrent writers, with the tables of the models migrated
+// Connections wait up to 5s on the locks of other connections instead of failing busy, and the SQL log is silenced
+// NewConcurrentMemDB 为并发写入创建内存 SQLite 数据库，并迁移这些模型的表
+// 连接最多等待 5 秒其他连接的锁，而不是以 busy 失败，并且关闭 SQL 日志
+func NewConcurrentMemDB(t *testing.T, models ...interface{}) *gorm.DB {
+	db := openMemDB(t, "&_busy_timeout=5000", logger.Silent)
+	must.Done(db.AutoMigrate(models...))
+	return db
+}
+
+func openMemDB(t *testing.T, params string, level logger.LogLevel) *gorm.DB {
+	dsn := fmt.Sprintf("file:db-%s?mode=memory&cache=shared%s", uuid.New().String(), params)
 	db := rese.P1(gorm.Open(sqlite.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logger.Default.LogMode(level),
 	}))
 	t.Cleanup(func() {
 		must.Done(rese.P1(db.DB()).Close())
@@ -25,3 +25,26 @@ func TestNewMemDB(t *testing.T) {
 	require.NoError(t, db.Raw("SELECT 1").Scan(&result).Error)
 	require.Equal(t, 1, result)
 }
+
+type tableRow struct {
+	ID   uint
+	Name string
+}
+
+func TestNewMemDBWithTables(t *testing.T) {
+	db := tests.NewMemDBWithTables(t, &tableRow{})
+
+	require.NoError(t, db.Create(&tableRow{Name: "a"}).Error)
+	var count int64
+	require.NoError(t, db.Model(&tableRow{}).Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
+
+func TestNewConcurrentMemDB(t *testing.T) {
+	db := tests.NewConcurrentMemDB(t, &tableRow{})
+
+	var timeout int
+	require.NoError(t, db.Raw("PRAGMA busy_timeout").Scan(&timeout).Error)
+	require.Equal(t, 5000, timeout)
+	require.True(t, db.Migrator().HasTable(&tableRow{}))
+}
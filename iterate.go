@@ -0,0 +1,63 @@
+package gormrepo
+
+import (
+	"iter"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Iterate streams records matching the where condition in batches, without loading them all in memory
+// Batches are located via keyset conditions on the ordering plus primary key, not via OFFSET
+// Nullable ordering columns yield an error up front, see KeysetColumn
+// Each batch passes through the interceptors as one OpIterate operation
+// Stops when the consumer breaks out of the loop, or yields the error when the context is canceled
+//
+// Iterate 分批流式读取符合 where 条件的记录，无需一次性加载到内存
+// 批次通过排序列加主键的键集条件定位，而不是 OFFSET
+// 可为 NULL 的排序列会直接返回错误，参见 KeysetColumn
+// 每个批次作为一次 OpIterate 操作经过拦截器
+// 当调用方跳出循环时停止，上下文取消时返回该错误
+func (repo *GormRepo[MOD, CLS]) Iterate(where func(db *gorm.DB, cls CLS) *gorm.DB, ordering func(cls CLS) []KeysetColumn, batchSize int) iter.Seq2[*MOD, error] {
+	return func(yield func(*MOD, error) bool) {
+		if batchSize <= 0 {
+			yield(nil, errors.Errorf("batch size %d is not positive", batchSize))
+			return
+		}
+		var columns []KeysetColumn
+		if ordering != nil {
+			columns = ordering(repo.cls)
+		}
+		keys, err := newKeyset[MOD](repo.db, columns)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		ctx := repo.db.Statement.Context
+		var values []interface{}
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			var results = make([]*MOD, 0, batchSize)
+			if _, err := repo.intercept(&Invocation{Operation: OpIterate, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+				db = where(db, repo.cls)
+				db = keys.scope(values, false)(db)
+				return db.Limit(batchSize).Find(&results)
+			}); err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, one := range results {
+				if !yield(one, nil) {
+					return
+				}
+			}
+			if len(results) < batchSize {
+				return
+			}
+			values = keys.values(ctx, results[len(results)-1])
+		}
+	}
+}
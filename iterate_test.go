@@ -0,0 +1,124 @@
+package gormrepo_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"gorm.io/gorm"
+)
+
+// setupIterateData creates accounts to be streamed in batches
+// setupIterateData 创建用于分批流式读取的账户
+func setupIterateData(t *testing.T, db *gorm.DB, size int) {
+	must.Done(db.AutoMigrate(&Account{}))
+	for idx := 0; idx < size; idx++ {
+		must.Done(db.Create(newAccount(fmt.Sprintf("iterate-%02d-username", idx))).Error)
+	}
+}
+
+// TestGormRepo_Iterate tests streaming all records across several batches
+// TestGormRepo_Iterate 测试跨多个批次流式读取全部记录
+func TestGormRepo_Iterate(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupIterateData(t, db, 7)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	var usernames []string
+	for one, err := range repo.Iterate(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Like("iterate-%"))
+	}, func(cls *AccountColumns) []gormrepo.KeysetColumn {
+		return []gormrepo.KeysetColumn{gormrepo.KeysetDesc(cls.Username)}
+	}, 3) {
+		require.NoError(t, err)
+		usernames = append(usernames, one.Username)
+	}
+	require.Len(t, usernames, 7)
+	require.Equal(t, "iterate-06-username", usernames[0])
+	require.Equal(t, "iterate-00-username", usernames[6])
+}
+
+// TestGormRepo_Iterate_Nullable tests that iterating by a column holding NULL fails instead of dropping rows
+// TestGormRepo_Iterate_Nullable 测试按包含 NULL 的列迭代时返回错误，而不是丢弃行
+func TestGormRepo_Iterate_Nullable(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupIterateData(t, db, 5)
+	must.Done(db.Model(&Account{}).Where("username = ?", "iterate-02-username").Update("deleted_at", time.Now()).Error)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db.Unscoped(), &Account{}))
+
+	var count int
+	var errs []error
+	for one, err := range repo.Iterate(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Like("iterate-%"))
+	}, func(cls *AccountColumns) []gormrepo.KeysetColumn {
+		return []gormrepo.KeysetColumn{gormrepo.KeysetAsc(cls.DeletedAt)}
+	}, 2) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		require.NotNil(t, one)
+		count++
+	}
+	require.Zero(t, count)
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], "nullable")
+}
+
+// TestGormRepo_Iterate_Break tests that breaking out of the loop stops the iteration
+// TestGormRepo_Iterate_Break 测试跳出循环后迭代停止
+func TestGormRepo_Iterate_Break(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupIterateData(t, db, 7)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	var count int
+	for one, err := range repo.Iterate(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	}, nil, 2) {
+		require.NoError(t, err)
+		require.NotNil(t, one)
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	require.Equal(t, 3, count)
+}
+
+// TestGormRepo_Iterate_Canceled tests that a canceled context ends the iteration with the error
+// TestGormRepo_Iterate_Canceled 测试上下文取消后迭代以该错误结束
+func TestGormRepo_Iterate_Canceled(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupIterateData(t, db, 7)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{})).WithContext(ctx)
+
+	var count int
+	var lastErr error
+	for one, err := range repo.Iterate(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	}, nil, 2) {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		require.NotNil(t, one)
+		count++
+		if count == 2 {
+			cancel()
+		}
+	}
+	require.ErrorIs(t, lastErr, context.Canceled)
+	require.Equal(t, 2, count)
+}
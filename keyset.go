@@ -0,0 +1,351 @@
+package gormrepo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormcnm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidCursor is returned when a cursor is malformed, tampered or built with another ordering
+//
+// ErrInvalidCursor 当游标格式错误、被篡改或由不同排序生成时返回
+var ErrInvalidCursor = errors.New("gormrepo: invalid cursor")
+
+// KeysetColumn defines one ordering column in keyset pagination
+// Use KeysetAsc and KeysetDesc to build it from CLS column definitions
+// Nullable columns (pointers, sql.Null types, gorm.DeletedAt) are rejected, since the cursor condition cannot locate NULL
+//
+// KeysetColumn 定义键集分页中的一个排序列
+// 使用 KeysetAsc 和 KeysetDesc 从 CLS 列定义构建
+// 可为 NULL 的列（指针、sql.Null 类型、gorm.DeletedAt）会被拒绝，因为游标条件无法定位 NULL
+type KeysetColumn struct {
+	Name string // Column name // 列名
+	Desc bool   // Descending when true // 为 true 时降序
+}
+
+// KeysetAsc creates an ascending keyset ordering column
+//
+// KeysetAsc 创建升序的键集排序列
+func KeysetAsc[T any](column gormcnm.ColumnName[T]) KeysetColumn {
+	return KeysetColumn{Name: column.Name(), Desc: false}
+}
+
+// KeysetDesc creates a descending keyset ordering column
+//
+// KeysetDesc 创建降序的键集排序列
+func KeysetDesc[T any](column gormcnm.ColumnName[T]) KeysetColumn {
+	return KeysetColumn{Name: column.Name(), Desc: true}
+}
+
+// KeysetPagination defines parameters enabling keyset (cursor) paginated queries
+// Cursor is empty on the first page, then takes NextCursor or PrevCursor from KeysetPage
+//
+// KeysetPagination 定义键集（游标）分页查询参数
+// 首页时 Cursor 为空，之后使用 KeysetPage 返回的 NextCursor 或 PrevCursor
+type KeysetPagination struct {
+	Limit  int          // Max records to retrieve, must be positive // 最大检索记录数，必须为正数
+	Cursor string       // Opaque cursor, empty means the beginning // 不透明游标，为空表示起点
+	Codec  *CursorCodec // Codec signing and verifying cursors // 签名和校验游标的编解码器
+}
+
+// KeysetPage is the result of keyset paginated queries
+// NextCursor is empty when there are no more records, PrevCursor is empty on the first page
+//
+// KeysetPage 是键集分页查询的结果
+// 没有更多记录时 NextCursor 为空，首页时 PrevCursor 为空
+type KeysetPage[MOD any] struct {
+	Records    []*MOD // Records in the page // 当前页记录
+	NextCursor string // Cursor to the next page // 下一页游标
+	PrevCursor string // Cursor to the previous page // 上一页游标
+}
+
+// FindAfter retrieves the page of records after the cursor, using keyset pagination
+// Primary key columns are appended as tie-breaker so the ordering stays stable
+//
+// FindAfter 使用键集分页检索游标之后的一页记录
+// 会追加主键列作为决胜列，保证排序稳定
+func (repo *GormRepo[MOD, CLS]) FindAfter(where func(db *gorm.DB, cls CLS) *gorm.DB, ordering func(cls CLS) []KeysetColumn, page *KeysetPagination) (*KeysetPage[MOD], error) {
+	return repo.findKeyset(where, ordering, page, false)
+}
+
+// FindBefore retrieves the page of records before the cursor, using keyset pagination
+// Records are returned in the same ordering as FindAfter, empty cursor means the last page
+//
+// FindBefore 使用键集分页检索游标之前的一页记录
+// 返回记录的顺序与 FindAfter 一致，游标为空时表示最后一页
+func (repo *GormRepo[MOD, CLS]) FindBefore(where func(db *gorm.DB, cls CLS) *gorm.DB, ordering func(cls CLS) []KeysetColumn, page *KeysetPagination) (*KeysetPage[MOD], error) {
+	return repo.findKeyset(where, ordering, page, true)
+}
+
+func (repo *GormRepo[MOD, CLS]) findKeyset(where func(db *gorm.DB, cls CLS) *gorm.DB, ordering func(cls CLS) []KeysetColumn, page *KeysetPagination, backward bool) (*KeysetPage[MOD], error) {
+	if page == nil || page.Limit <= 0 {
+		return nil, errors.New("keyset pagination needs a positive limit")
+	}
+	if page.Codec == nil {
+		return nil, errors.New("cursor codec is missing")
+	}
+	keys, err := newKeyset[MOD](repo.db, ordering(repo.cls))
+	if err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	if page.Cursor != "" {
+		if values, err = page.Codec.decode(page.Cursor, keys); err != nil {
+			return nil, err
+		}
+	}
+	var op = OpFindAfter
+	if backward {
+		op = OpFindBefore
+	}
+	var results = make([]*MOD, 0, page.Limit+1)
+	if _, err := repo.intercept(&Invocation{Operation: op, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		db = where(db, repo.cls)
+		db = keys.scope(values, backward)(db)
+		return db.Limit(page.Limit + 1).Find(&results)
+	}); err != nil {
+		return nil, err
+	}
+	hasMore := len(results) > page.Limit
+	if hasMore {
+		results = results[:page.Limit]
+	}
+	if backward {
+		slices.Reverse(results)
+	}
+
+	ctx := repo.db.Statement.Context
+	var encodeCursor = func(one *MOD) (string, error) {
+		return page.Codec.encode(keys, keys.values(ctx, one))
+	}
+	var result = &KeysetPage[MOD]{Records: results}
+	if len(results) == 0 {
+		return result, nil
+	}
+	// Going forward, rows exist ahead when hasMore, and behind when a cursor was given. Going backward is symmetric.
+	// 正向翻页时，hasMore 表示后面还有数据，传入游标表示前面还有数据。反向翻页时对称。
+	hasNext, hasPrev := hasMore, page.Cursor != ""
+	if backward {
+		hasNext, hasPrev = page.Cursor != "", hasMore
+	}
+	if hasNext {
+		if result.NextCursor, err = encodeCursor(results[len(results)-1]); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if result.PrevCursor, err = encodeCursor(results[0]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// keysetField is one resolved ordering column with its schema field
+// keysetField 是一个解析后的排序列及其 schema 字段
+type keysetField struct {
+	column string        // SQL column name // SQL 列名
+	desc   bool          // Descending when true // 为 true 时降序
+	field  *schema.Field // Schema field reading values // 读取值的 schema 字段
+}
+
+// keyset is the full ordering with primary key tie-breaker appended
+// keyset 是追加了主键决胜列的完整排序
+type keyset []*keysetField
+
+func newKeyset[MOD any](db *gorm.DB, columns []KeysetColumn) (keyset, error) {
+	sch, err := ParseSchema[MOD](db)
+	if err != nil {
+		return nil, err
+	}
+	var keys keyset
+	for _, column := range columns {
+		field, err := lookupField(sch, column.Name)
+		if err != nil {
+			return nil, err
+		}
+		// Comparisons with NULL match nothing, so rows holding NULL would be skipped without an error
+		// 与 NULL 的比较不匹配任何行，因此持有 NULL 的行会被静默跳过
+		if nullable(field) {
+			return nil, errors.Errorf("keyset column %s of table %s is nullable (%s), order by non-null columns", column.Name, sch.Table, field.FieldType)
+		}
+		keys = append(keys, &keysetField{column: column.Name, desc: column.Desc, field: field})
+	}
+	var desc = len(keys) > 0 && keys[len(keys)-1].desc
+	for _, field := range sch.PrimaryFields {
+		if !slices.ContainsFunc(keys, func(key *keysetField) bool { return key.field == field }) {
+			keys = append(keys, &keysetField{column: field.DBName, desc: desc, field: field})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.Errorf("table %s has no ordering columns", sch.Table)
+	}
+	return keys, nil
+}
+
+// nullable reports whether the field can hold NULL: pointers and sql.Null-like structs with a Valid flag
+// nullable 判断字段是否可以为 NULL：指针以及带有 Valid 标志的类似 sql.Null 的结构体
+func nullable(field *schema.Field) bool {
+	switch field.FieldType.Kind() {
+	case reflect.Ptr:
+		return true
+	case reflect.Struct:
+		valid, ok := field.FieldType.FieldByName("Valid")
+		return ok && valid.Type.Kind() == reflect.Bool
+	default:
+		return false
+	}
+}
+
+// scope applies the ordering and, when values are given, the condition locating rows past the values
+// The condition is expanded as (a > ?) OR (a = ? AND b > ?) ... so mixed directions are supported
+//
+// scope 应用排序，当给定 values 时同时应用定位到 values 之后的条件
+// 条件展开为 (a > ?) OR (a = ? AND b > ?) ... 以支持混合排序方向
+func (keys keyset) scope(values []interface{}, backward bool) ScopeFunction {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(values) > 0 {
+			var ors = make([]string, 0, len(keys))
+			var args = make([]interface{}, 0, len(keys)*(len(keys)+1)/2)
+			for idx, key := range keys {
+				var ands = make([]string, 0, idx+1)
+				for pre := 0; pre < idx; pre++ {
+					ands = append(ands, keys[pre].column+" = ?")
+					args = append(args, values[pre])
+				}
+				var op = ">"
+				if key.desc != backward {
+					op = "<"
+				}
+				ands = append(ands, key.column+" "+op+" ?")
+				args = append(args, values[idx])
+				ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+			}
+			db = db.Where(strings.Join(ors, " OR "), args...)
+		}
+		var orders = make([]string, 0, len(keys))
+		for _, key := range keys {
+			if key.desc != backward {
+				orders = append(orders, key.column+" DESC")
+			} else {
+				orders = append(orders, key.column+" ASC")
+			}
+		}
+		return db.Order(strings.Join(orders, ", "))
+	}
+}
+
+func (keys keyset) values(ctx context.Context, one interface{}) []interface{} {
+	var rv = reflect.Indirect(reflect.ValueOf(one))
+	var values = make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		value, _ := key.field.ValueOf(ctx, rv)
+		values = append(values, value)
+	}
+	return values
+}
+
+func (keys keyset) columns() []string {
+	var names = make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, key.column)
+	}
+	return names
+}
+
+func (keys keyset) descs() []bool {
+	var descs = make([]bool, 0, len(keys))
+	for _, key := range keys {
+		descs = append(descs, key.desc)
+	}
+	return descs
+}
+
+// CursorCodec encodes keyset cursors as opaque strings signed with HMAC-SHA256
+// Encoding is deterministic and tampered cursors are rejected with ErrInvalidCursor
+//
+// CursorCodec 将键集游标编码为使用 HMAC-SHA256 签名的不透明字符串
+// 编码结果是确定的，被篡改的游标会以 ErrInvalidCursor 拒绝
+type CursorCodec struct {
+	secret []byte // HMAC secret key // HMAC 密钥
+}
+
+// NewCursorCodec creates a CursorCodec with the secret key
+// Use the same secret across instances so cursors stay valid between them
+//
+// NewCursorCodec 使用密钥创建 CursorCodec
+// 多实例间使用相同密钥，以便游标在实例间通用
+func NewCursorCodec(secret []byte) *CursorCodec {
+	return &CursorCodec{secret: slices.Clone(secret)}
+}
+
+// cursorPayload is the signed content of a cursor
+// cursorPayload 是游标中被签名的内容
+type cursorPayload struct {
+	Columns []string          `json:"c"`
+	Descs   []bool            `json:"d"` // Directions of the columns, so a cursor cannot page an ordering flipped // 各列的方向，使游标不能用于方向翻转的排序
+	Values  []json.RawMessage `json:"v"`
+}
+
+func (codec *CursorCodec) encode(keys keyset, values []interface{}) (string, error) {
+	var payload = &cursorPayload{Columns: keys.columns(), Descs: keys.descs()}
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", errors.Wrap(err, "encode cursor")
+		}
+		payload.Values = append(payload.Values, data)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", errors.Wrap(err, "encode cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(codec.sign(data)), nil
+}
+
+func (codec *CursorCodec) decode(cursor string, keys keyset) ([]interface{}, error) {
+	encoded, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, codec.sign(data)) {
+		return nil, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if !slices.Equal(payload.Columns, keys.columns()) || !slices.Equal(payload.Descs, keys.descs()) || len(payload.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+	var values = make([]interface{}, 0, len(keys))
+	for idx, key := range keys {
+		ptr := reflect.New(key.field.FieldType)
+		if err := json.Unmarshal(payload.Values[idx], ptr.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values = append(values, ptr.Elem().Interface())
+	}
+	return values, nil
+}
+
+func (codec *CursorCodec) sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, codec.secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
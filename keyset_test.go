@@ -0,0 +1,152 @@
+package gormrepo_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"gorm.io/gorm"
+)
+
+// setupKeysetData creates accounts with repeated nicknames, so the primary key tie-breaker matters
+// setupKeysetData 创建带有重复昵称的账户，以便验证主键决胜列
+func setupKeysetData(t *testing.T, db *gorm.DB) {
+	must.Done(db.AutoMigrate(&Account{}))
+	for idx := 0; idx < 5; idx++ {
+		account := newAccount(fmt.Sprintf("keyset-%d-username", idx))
+		account.Nickname = fmt.Sprintf("keyset-nickname-%d", idx/2)
+		must.Done(db.Create(account).Error)
+	}
+}
+
+// TestGormRepo_FindAfter tests paging forward and backward through keyset cursors
+// TestGormRepo_FindAfter 测试通过键集游标向后和向前翻页
+func TestGormRepo_FindAfter(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupKeysetData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+	codec := gormrepo.NewCursorCodec([]byte("keyset-secret"))
+
+	where := func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Like("keyset-%"))
+	}
+	ordering := func(cls *AccountColumns) []gormrepo.KeysetColumn {
+		return []gormrepo.KeysetColumn{gormrepo.KeysetDesc(cls.Nickname)}
+	}
+	usernames := func(page *gormrepo.KeysetPage[Account]) []string {
+		var names []string
+		for _, one := range page.Records {
+			names = append(names, one.Username)
+		}
+		return names
+	}
+
+	page1, err := repo.FindAfter(where, ordering, &gormrepo.KeysetPagination{Limit: 2, Codec: codec})
+	require.NoError(t, err)
+	require.Equal(t, []string{"keyset-4-username", "keyset-3-username"}, usernames(page1))
+	require.NotEmpty(t, page1.NextCursor)
+	require.Empty(t, page1.PrevCursor)
+
+	page2, err := repo.FindAfter(where, ordering, &gormrepo.KeysetPagination{Limit: 2, Cursor: page1.NextCursor, Codec: codec})
+	require.NoError(t, err)
+	require.Equal(t, []string{"keyset-2-username", "keyset-1-username"}, usernames(page2))
+	require.NotEmpty(t, page2.NextCursor)
+	require.NotEmpty(t, page2.PrevCursor)
+
+	page3, err := repo.FindAfter(where, ordering, &gormrepo.KeysetPagination{Limit: 2, Cursor: page2.NextCursor, Codec: codec})
+	require.NoError(t, err)
+	require.Equal(t, []string{"keyset-0-username"}, usernames(page3))
+	require.Empty(t, page3.NextCursor)
+
+	back2, err := repo.FindBefore(where, ordering, &gormrepo.KeysetPagination{Limit: 2, Cursor: page3.PrevCursor, Codec: codec})
+	require.NoError(t, err)
+	require.Equal(t, usernames(page2), usernames(back2))
+	require.Equal(t, page2.NextCursor, back2.NextCursor)
+
+	back1, err := repo.FindBefore(where, ordering, &gormrepo.KeysetPagination{Limit: 2, Cursor: back2.PrevCursor, Codec: codec})
+	require.NoError(t, err)
+	require.Equal(t, usernames(page1), usernames(back1))
+	require.Empty(t, back1.PrevCursor)
+
+	last, err := repo.FindBefore(where, ordering, &gormrepo.KeysetPagination{Limit: 2, Codec: codec})
+	require.NoError(t, err)
+	require.Equal(t, []string{"keyset-1-username", "keyset-0-username"}, usernames(last))
+	require.Empty(t, last.NextCursor)
+}
+
+// TestGormRepo_FindAfter_Nullable tests that nullable ordering columns are rejected instead of skipping NULL rows
+// TestGormRepo_FindAfter_Nullable 测试可为 NULL 的排序列会被拒绝，而不是跳过 NULL 的行
+func TestGormRepo_FindAfter_Nullable(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupKeysetData(t, db)
+	must.Done(db.Model(&Account{}).Where("username = ?", "keyset-2-username").Update("deleted_at", time.Now()).Error)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db.Unscoped(), &Account{}))
+	_, err := repo.FindAfter(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	}, func(cls *AccountColumns) []gormrepo.KeysetColumn {
+		return []gormrepo.KeysetColumn{gormrepo.KeysetAsc(cls.DeletedAt)}
+	}, &gormrepo.KeysetPagination{Limit: 2, Codec: gormrepo.NewCursorCodec([]byte("keyset-secret"))})
+	require.ErrorContains(t, err, "nullable")
+}
+
+// TestGormRepo_FindAfter_InvalidCursor tests that tampered and foreign cursors are rejected
+// TestGormRepo_FindAfter_InvalidCursor 测试被篡改和来源不符的游标会被拒绝
+func TestGormRepo_FindAfter_InvalidCursor(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupKeysetData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+	codec := gormrepo.NewCursorCodec([]byte("keyset-secret"))
+
+	where := func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	}
+	ordering := func(cls *AccountColumns) []gormrepo.KeysetColumn {
+		return []gormrepo.KeysetColumn{gormrepo.KeysetAsc(cls.Username)}
+	}
+
+	page, err := repo.FindAfter(where, ordering, &gormrepo.KeysetPagination{Limit: 2, Codec: codec})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	t.Run("deterministic", func(t *testing.T) {
+		again, err := repo.FindAfter(where, ordering, &gormrepo.KeysetPagination{Limit: 2, Codec: codec})
+		require.NoError(t, err)
+		require.Equal(t, page.NextCursor, again.NextCursor)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		_, err := repo.FindAfter(where, ordering, &gormrepo.KeysetPagination{Limit: 2, Cursor: "x" + page.NextCursor, Codec: codec})
+		require.ErrorIs(t, err, gormrepo.ErrInvalidCursor)
+	})
+
+	t.Run("other-secret", func(t *testing.T) {
+		_, err := repo.FindAfter(where, ordering, &gormrepo.KeysetPagination{Limit: 2, Cursor: page.NextCursor, Codec: gormrepo.NewCursorCodec([]byte("other"))})
+		require.ErrorIs(t, err, gormrepo.ErrInvalidCursor)
+	})
+
+	t.Run("other-ordering", func(t *testing.T) {
+		_, err := repo.FindAfter(where, func(cls *AccountColumns) []gormrepo.KeysetColumn {
+			return []gormrepo.KeysetColumn{gormrepo.KeysetAsc(cls.Nickname)}
+		}, &gormrepo.KeysetPagination{Limit: 2, Cursor: page.NextCursor, Codec: codec})
+		require.ErrorIs(t, err, gormrepo.ErrInvalidCursor)
+
+		_, err = repo.FindAfter(where, func(cls *AccountColumns) []gormrepo.KeysetColumn {
+			return []gormrepo.KeysetColumn{gormrepo.KeysetDesc(cls.Username)}
+		}, &gormrepo.KeysetPagination{Limit: 2, Cursor: page.NextCursor, Codec: codec})
+		require.ErrorIs(t, err, gormrepo.ErrInvalidCursor)
+	})
+
+	t.Run("invalid-limit", func(t *testing.T) {
+		_, err := repo.FindAfter(where, ordering, &gormrepo.KeysetPagination{Limit: -1, Codec: codec})
+		require.Error(t, err)
+		_, err = repo.FindBefore(where, ordering, nil)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,123 @@
+package gormrepo
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrLockOutsideTransaction is returned when a row lock is requested outside a transaction
+// Row locks are released as soon as the statement ends when not in a transaction, so the lock would protect nothing
+//
+// ErrLockOutsideTransaction 在事务之外请求行锁时返回
+// 不在事务中时行锁在语句结束后立即释放，因此锁起不到任何保护作用
+var ErrLockOutsideTransaction = errors.New("gormrepo: row lock outside a transaction")
+
+// ForUpdate locks the selected rows against concurrent updates (SELECT ... FOR UPDATE) and returns a new GormRepo
+// Only valid inside a transaction, otherwise the query fails with ErrLockOutsideTransaction
+// SQLite has no row locks, GORM drops the clause there and the transaction serializes the writes
+//
+// ForUpdate 锁定查询到的行以防止并发更新（SELECT ... FOR UPDATE），返回新的 GormRepo
+// 只在事务中有效，否则查询返回 ErrLockOutsideTransaction
+// SQLite 没有行锁，GORM 会去掉该子句，由事务串行化写入
+func (repo *GormRepo[MOD, CLS]) ForUpdate() *GormRepo[MOD, CLS] {
+	return repo.derive(lockRows(repo.db, func(locking *clause.Locking) {
+		locking.Strength = clause.LockingStrengthUpdate
+	}))
+}
+
+// ForShare locks the selected rows against concurrent updates while allowing other shared locks (SELECT ... FOR SHARE)
+// Only valid inside a transaction, otherwise the query fails with ErrLockOutsideTransaction
+//
+// ForShare 锁定查询到的行以防止并发更新，同时允许其他共享锁（SELECT ... FOR SHARE）
+// 只在事务中有效，否则查询返回 ErrLockOutsideTransaction
+func (repo *GormRepo[MOD, CLS]) ForShare() *GormRepo[MOD, CLS] {
+	return repo.derive(lockRows(repo.db, func(locking *clause.Locking) {
+		locking.Strength = clause.LockingStrengthShare
+	}))
+}
+
+// SkipLocked skips rows locked by other transactions, combined with ForUpdate or ForShare (FOR UPDATE when neither was set)
+// Only valid inside a transaction, otherwise the query fails with ErrLockOutsideTransaction
+//
+// SkipLocked 跳过被其他事务锁定的行，与 ForUpdate 或 ForShare 组合（两者都未设置时为 FOR UPDATE）
+// 只在事务中有效，否则查询返回 ErrLockOutsideTransaction
+func (repo *GormRepo[MOD, CLS]) SkipLocked() *GormRepo[MOD, CLS] {
+	return repo.derive(lockRows(repo.db, func(locking *clause.Locking) {
+		locking.Options = clause.LockingOptionsSkipLocked
+	}))
+}
+
+// NoWait fails at once instead of waiting when rows are locked by other transactions, combined with ForUpdate or ForShare
+// Only valid inside a transaction, otherwise the query fails with ErrLockOutsideTransaction
+//
+// NoWait 当行被其他事务锁定时立即失败而不是等待，与 ForUpdate 或 ForShare 组合
+// 只在事务中有效，否则查询返回 ErrLockOutsideTransaction
+func (repo *GormRepo[MOD, CLS]) NoWait() *GormRepo[MOD, CLS] {
+	return repo.derive(lockRows(repo.db, func(locking *clause.Locking) {
+		locking.Options = clause.LockingOptionsNoWait
+	}))
+}
+
+// ForUpdate locks the selected rows against concurrent updates (SELECT ... FOR UPDATE) and returns a new GormWrap
+// Only valid inside a transaction, otherwise the query fails with ErrLockOutsideTransaction
+//
+// ForUpdate 锁定查询到的行以防止并发更新（SELECT ... FOR UPDATE），返回新的 GormWrap
+// 只在事务中有效，否则查询返回 ErrLockOutsideTransaction
+func (wrap *GormWrap[MOD, CLS]) ForUpdate() *GormWrap[MOD, CLS] {
+	return NewGormWrap(lockRows(wrap.db, func(locking *clause.Locking) {
+		locking.Strength = clause.LockingStrengthUpdate
+	}), (*MOD)(nil), wrap.cls)
+}
+
+// ForShare locks the selected rows while allowing other shared locks (SELECT ... FOR SHARE) and returns a new GormWrap
+// Only valid inside a transaction, otherwise the query fails with ErrLockOutsideTransaction
+//
+// ForShare 锁定查询到的行同时允许其他共享锁（SELECT ... FOR SHARE），返回新的 GormWrap
+// 只在事务中有效，否则查询返回 ErrLockOutsideTransaction
+func (wrap *GormWrap[MOD, CLS]) ForShare() *GormWrap[MOD, CLS] {
+	return NewGormWrap(lockRows(wrap.db, func(locking *clause.Locking) {
+		locking.Strength = clause.LockingStrengthShare
+	}), (*MOD)(nil), wrap.cls)
+}
+
+// SkipLocked skips rows locked by other transactions and returns a new GormWrap
+// Only valid inside a transaction, otherwise the query fails with ErrLockOutsideTransaction
+//
+// SkipLocked 跳过被其他事务锁定的行，返回新的 GormWrap
+// 只在事务中有效，否则查询返回 ErrLockOutsideTransaction
+func (wrap *GormWrap[MOD, CLS]) SkipLocked() *GormWrap[MOD, CLS] {
+	return NewGormWrap(lockRows(wrap.db, func(locking *clause.Locking) {
+		locking.Options = clause.LockingOptionsSkipLocked
+	}), (*MOD)(nil), wrap.cls)
+}
+
+// NoWait fails at once instead of waiting on locked rows and returns a new GormWrap
+// Only valid inside a transaction, otherwise the query fails with ErrLockOutsideTransaction
+//
+// NoWait 遇到被锁定的行时立即失败而不是等待，返回新的 GormWrap
+// 只在事务中有效，否则查询返回 ErrLockOutsideTransaction
+func (wrap *GormWrap[MOD, CLS]) NoWait() *GormWrap[MOD, CLS] {
+	return NewGormWrap(lockRows(wrap.db, func(locking *clause.Locking) {
+		locking.Options = clause.LockingOptionsNoWait
+	}), (*MOD)(nil), wrap.cls)
+}
+
+// lockRows merges the change into the locking clause of db, FOR UPDATE when db has none yet
+// Outside a transaction returns db carrying ErrLockOutsideTransaction, so the next query fails
+//
+// lockRows 将修改合并到 db 的锁定子句中，db 尚无锁定子句时为 FOR UPDATE
+// 在事务之外返回携带 ErrLockOutsideTransaction 的 db，使下一次查询失败
+func lockRows(db *gorm.DB, change func(locking *clause.Locking)) *gorm.DB {
+	if !InTransaction(db) {
+		return withError(db, errors.WithStack(ErrLockOutsideTransaction))
+	}
+	locking := clause.Locking{Strength: clause.LockingStrengthUpdate}
+	if item, ok := db.Statement.Clauses[locking.Name()]; ok {
+		if previous, ok := item.Expression.(clause.Locking); ok {
+			locking = previous
+		}
+	}
+	change(&locking)
+	return db.Clauses(locking)
+}
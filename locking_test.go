@@ -0,0 +1,66 @@
+package gormrepo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TestGormRepo_ForUpdate tests the locking clause chain inside a transaction
+// TestGormRepo_ForUpdate 测试事务中的锁定子句链
+func TestGormRepo_ForUpdate(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	require.NoError(t, repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+		locked := repo.ForShare().SkipLocked()
+		stmt := locked.Gorm().Where(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db
+		}).Statement
+		require.Equal(t, clause.Locking{
+			Strength: clause.LockingStrengthShare,
+			Options:  clause.LockingOptionsSkipLocked,
+		}, stmt.Clauses["FOR"].Expression)
+
+		// SQLite drops the clause, the query still runs in the transaction
+		// SQLite 去掉该子句，查询仍在事务中运行
+		res, err := repo.ForUpdate().NoWait().First(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq("demo-1-username"))
+		})
+		require.NoError(t, err)
+		require.Equal(t, "demo-1-nickname", res.Nickname)
+		return nil
+	}))
+}
+
+// TestGormRepo_ForUpdate_OutsideTransaction tests that row locks outside a transaction fail
+// TestGormRepo_ForUpdate_OutsideTransaction 测试事务之外的行锁会失败
+func TestGormRepo_ForUpdate_OutsideTransaction(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+	_, err := repo.ForUpdate().First(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	})
+	require.ErrorIs(t, err, gormrepo.ErrLockOutsideTransaction)
+
+	wrap := gormrepo.NewGormWrap(gormrepo.Use(db, &Account{}))
+	var accounts []*Account
+	require.ErrorIs(t, wrap.SkipLocked().Find(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db
+	}, &accounts).Error, gormrepo.ErrLockOutsideTransaction)
+
+	// The base repo stays usable
+	// 基础仓储仍然可用
+	_, err = repo.First(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	})
+	require.NoError(t, err)
+}
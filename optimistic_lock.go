@@ -0,0 +1,204 @@
+package gormrepo
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrStaleObject is returned when a versioned update matches no row, the record was changed or removed meanwhile
+// ErrStaleObject 在带版本的更新没有匹配到任何行时返回，说明记录已被修改或删除
+var ErrStaleObject = errors.New("gormrepo: stale object")
+
+// VersionColumnFace is implemented by column structs (CLS) declaring the optimistic lock version column
+// Alternative to tagging the model field with `gormrepo:"version"`
+// The version column must be an integer field
+// Versioned models also match and increment the version in UpdatesO, UpdatesC, Save and Saves, ErrStaleObject when no row matches
+// Saves writes the records one by one in a transaction, so one stale record rolls back all of them
+//
+// VersionColumnFace 由声明乐观锁版本列的列结构体（CLS）实现
+// 也可以使用 `gormrepo:"version"` 标签标记模型字段
+// 版本列必须是整数字段
+// 带版本的模型在 UpdatesO、UpdatesC、Save 和 Saves 中还会匹配并递增版本，没有行匹配时返回 ErrStaleObject
+// Saves 在事务中逐条写入记录，因此一条过期记录会回滚全部记录
+type VersionColumnFace interface {
+	VersionColumn() ColumnNameFace
+}
+
+// versionField returns the version field of the model, nil when the model is not versioned
+// versionField 返回模型的版本字段，模型不带版本时返回 nil
+func versionField[MOD any](db *gorm.DB, cls any) (*schema.Field, error) {
+	sch, err := ParseSchema[MOD](db)
+	if err != nil {
+		return nil, err
+	}
+	var field *schema.Field
+	if face, ok := cls.(VersionColumnFace); ok {
+		if field, err = lookupField(sch, face.VersionColumn().Name()); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, item := range sch.Fields {
+			if item.Tag.Get("gormrepo") == "version" {
+				field = item
+				break
+			}
+		}
+		if field == nil {
+			return nil, nil
+		}
+	}
+	switch field.FieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field, nil
+	default:
+		return nil, errors.Errorf("version field %s must be an integer, got %s", field.Name, field.FieldType)
+	}
+}
+
+// versionValues reads the current version of the object and computes the next one
+// versionValues 读取对象的当前版本并计算下一个版本
+func versionValues(db *gorm.DB, field *schema.Field, object interface{}) (oldVersion interface{}, newVersion interface{}) {
+	rv := reflect.Indirect(reflect.ValueOf(object))
+	oldVersion, _ = field.ValueOf(db.Statement.Context, rv)
+	next := reflect.New(field.FieldType).Elem()
+	value := reflect.ValueOf(oldVersion)
+	if value.CanInt() {
+		next.SetInt(value.Int() + 1)
+	} else {
+		next.SetUint(value.Uint() + 1)
+	}
+	return oldVersion, next.Interface()
+}
+
+// versionEq is the condition matching the version read by the caller
+// versionEq 是匹配调用方读取到的版本的条件
+func versionEq(field *schema.Field, oldVersion interface{}) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: oldVersion}
+}
+
+// checkStale sets ErrStaleObject on a versioned write that affected no row, and restores the version of the object
+// checkStale 在带版本的写入没有影响任何行时设置 ErrStaleObject，并恢复对象的版本
+func checkStale(result *gorm.DB, field *schema.Field, object interface{}, oldVersion interface{}) *gorm.DB {
+	if result.Error == nil && result.RowsAffected > 0 {
+		return result
+	}
+	rv := reflect.Indirect(reflect.ValueOf(object))
+	_ = field.Set(result.Statement.Context, rv, oldVersion)
+	if result.Error == nil {
+		_ = result.AddError(errors.WithStack(ErrStaleObject))
+	}
+	return result
+}
+
+// updatesVersioned updates the object by primary key, adding the version comparison and increment when versioned
+// updatesVersioned 按主键更新对象，带版本时添加版本比较和递增
+func updatesVersioned[MOD any](db *gorm.DB, cls any, object *MOD, values map[string]interface{}) *gorm.DB {
+	field, err := versionField[MOD](db, cls)
+	if err != nil {
+		return withError(db, err)
+	}
+	if field == nil {
+		return db.Model(object).Updates(values)
+	}
+	oldVersion, newVersion := versionValues(db, field, object)
+	values[field.DBName] = newVersion
+	result := db.Model(object).Where(versionEq(field, oldVersion)).Updates(values)
+	return checkStale(result, field, object, oldVersion)
+}
+
+// saveVersioned saves the object, comparing and incrementing the version when versioned and already persisted
+// Versioned objects with a primary key are updated and never inserted, so a removed row reports ErrStaleObject
+//
+// saveVersioned 保存对象，带版本且已持久化时比较并递增版本
+// 带主键的带版本对象只会更新而不会插入，因此被删除的行会报告 ErrStaleObject
+func saveVersioned[MOD any](db *gorm.DB, cls any, one *MOD) *gorm.DB {
+	field, err := versionField[MOD](db, cls)
+	if err != nil {
+		return withError(db, err)
+	}
+	if field == nil {
+		return db.Save(one)
+	}
+	sch, err := ParseSchema[MOD](db)
+	if err != nil {
+		return withError(db, err)
+	}
+	if sch.PrioritizedPrimaryField != nil {
+		rv := reflect.Indirect(reflect.ValueOf(one))
+		if _, isZero := sch.PrioritizedPrimaryField.ValueOf(db.Statement.Context, rv); isZero {
+			return db.Create(one)
+		}
+	}
+	oldVersion, newVersion := versionValues(db, field, one)
+	rv := reflect.Indirect(reflect.ValueOf(one))
+	if err := field.Set(db.Statement.Context, rv, newVersion); err != nil {
+		return withError(db, err)
+	}
+	result := db.Where(versionEq(field, oldVersion)).Select("*").Updates(one)
+	return checkStale(result, field, one, oldVersion)
+}
+
+// savesVersioned saves the objects, one by one in a transaction when versioned, so one stale object rolls back them all
+// On failure the objects get back their versions and zero primary keys, the writes having been rolled back
+//
+// savesVersioned 保存这些对象，带版本时在事务中逐个保存，因此一个过期对象会回滚全部对象
+// 失败时对象恢复原来的版本和零值主键，因为写入已被回滚
+func savesVersioned[MOD any](db *gorm.DB, cls any, ones []*MOD) *gorm.DB {
+	field, err := versionField[MOD](db, cls)
+	if err != nil {
+		return withError(db, err)
+	}
+	if field == nil {
+		return db.Save(ones)
+	}
+	sch, err := ParseSchema[MOD](db)
+	if err != nil {
+		return withError(db, err)
+	}
+	ctx := db.Statement.Context
+	var oldVersions = make([]interface{}, len(ones))
+	var newOnes = make([]bool, len(ones))
+	for idx, one := range ones {
+		rv := reflect.Indirect(reflect.ValueOf(one))
+		oldVersions[idx], _ = field.ValueOf(ctx, rv)
+		if sch.PrioritizedPrimaryField != nil {
+			_, newOnes[idx] = sch.PrioritizedPrimaryField.ValueOf(ctx, rv)
+		}
+	}
+	var rowsAffected int64
+	if err := db.Transaction(func(db *gorm.DB) error {
+		for _, one := range ones {
+			result := saveVersioned(db, cls, one)
+			if result.Error != nil {
+				return result.Error
+			}
+			rowsAffected += result.RowsAffected
+		}
+		return nil
+	}); err != nil {
+		for idx, one := range ones {
+			rv := reflect.Indirect(reflect.ValueOf(one))
+			_ = field.Set(ctx, rv, oldVersions[idx])
+			if newOnes[idx] {
+				_ = sch.PrioritizedPrimaryField.Set(ctx, rv, reflect.Zero(sch.PrioritizedPrimaryField.FieldType).Interface())
+			}
+		}
+		return withError(db, err)
+	}
+	tx := db.Session(&gorm.Session{})
+	tx.RowsAffected = rowsAffected
+	return tx
+}
+
+// withError returns a new session of db carrying the error, for operations returning *gorm.DB
+// withError 返回携带错误的 db 新会话，用于返回 *gorm.DB 的操作
+func withError(db *gorm.DB, err error) *gorm.DB {
+	tx := db.Session(&gorm.Session{})
+	_ = tx.AddError(err)
+	return tx
+}
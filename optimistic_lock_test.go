@@ -0,0 +1,181 @@
+package gormrepo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"gorm.io/gorm"
+)
+
+// VersionColumn declares Revision as the optimistic lock version column of Article
+// VersionColumn 声明 Revision 为 Article 的乐观锁版本列
+func (a *ArticleColumns) VersionColumn() gormrepo.ColumnNameFace {
+	return a.Revision
+}
+
+// TestGormRepo_UpdatesO_Version tests compare-and-swap updates with the version tag
+// TestGormRepo_UpdatesO_Version 测试使用版本标签的比较并交换更新
+func TestGormRepo_UpdatesO_Version(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Document{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Document{}))
+
+	document := &Document{Title: "v1"}
+	require.NoError(t, repo.Create(document))
+
+	stale, err := repo.First(func(db *gorm.DB, cls *DocumentColumns) *gorm.DB {
+		return db.Where(cls.ID.Eq(document.ID))
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpdatesO(document, func(cls *DocumentColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Title.Kv("v2"))
+	}))
+	require.Equal(t, int64(1), document.Version)
+
+	// The copy read before the update still holds version 0
+	// 更新之前读取的副本仍持有版本 0
+	require.ErrorIs(t, repo.UpdatesO(stale, func(cls *DocumentColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Title.Kv("lost"))
+	}), gormrepo.ErrStaleObject)
+	require.Equal(t, int64(0), stale.Version)
+
+	res, err := repo.First(func(db *gorm.DB, cls *DocumentColumns) *gorm.DB {
+		return db.Where(cls.ID.Eq(document.ID))
+	})
+	require.NoError(t, err)
+	require.Equal(t, "v2", res.Title)
+	require.Equal(t, int64(1), res.Version)
+}
+
+// TestGormRepo_Save_Version tests versioned Save with the version column declared by CLS
+// TestGormRepo_Save_Version 测试由 CLS 声明版本列的带版本 Save
+func TestGormRepo_Save_Version(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Article{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Article{}))
+
+	article := &Article{Title: "v1"}
+	require.NoError(t, repo.Save(article))
+	require.NotZero(t, article.ID)
+
+	stale := *article
+
+	article.Title = "v2"
+	require.NoError(t, repo.Save(article))
+	require.Equal(t, 1, article.Revision)
+
+	stale.Title = "lost"
+	require.ErrorIs(t, repo.Save(&stale), gormrepo.ErrStaleObject)
+	require.Equal(t, 0, stale.Revision)
+
+	res, err := repo.First(func(db *gorm.DB, cls *ArticleColumns) *gorm.DB {
+		return db.Where(cls.ID.Eq(article.ID))
+	})
+	require.NoError(t, err)
+	require.Equal(t, "v2", res.Title)
+	require.Equal(t, 1, res.Revision)
+}
+
+// TestGormWrap_UpdatesO_Version tests that GormWrap reports ErrStaleObject via .Error
+// TestGormWrap_UpdatesO_Version 测试 GormWrap 通过 .Error 报告 ErrStaleObject
+func TestGormWrap_UpdatesO_Version(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Document{}))
+
+	wrap := gormrepo.NewGormWrap(gormrepo.Use(db, &Document{}))
+
+	document := &Document{Title: "v1"}
+	require.NoError(t, wrap.Create(document).Error)
+
+	stale := *document
+	require.NoError(t, wrap.Save(document).Error)
+	require.Equal(t, int64(1), document.Version)
+
+	require.ErrorIs(t, wrap.UpdatesO(&stale, func(cls *DocumentColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Title.Kv("lost"))
+	}).Error, gormrepo.ErrStaleObject)
+}
+
+// TestGormRepo_UpdatesC_Version tests that UpdatesC matches and increments the version too
+// TestGormRepo_UpdatesC_Version 测试 UpdatesC 同样匹配并递增版本
+func TestGormRepo_UpdatesC_Version(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Document{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Document{}))
+
+	document := &Document{Title: "v1"}
+	require.NoError(t, repo.Create(document))
+	stale := *document
+
+	count, err := repo.UpdatesCR(document, func(db *gorm.DB, cls *DocumentColumns) *gorm.DB {
+		return db.Where(cls.Title.Eq("v1"))
+	}, func(cls *DocumentColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Title.Kv("v2"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	require.Equal(t, int64(1), document.Version)
+
+	require.ErrorIs(t, repo.UpdatesC(&stale, func(db *gorm.DB, cls *DocumentColumns) *gorm.DB {
+		return db.Where(cls.Title.Eq("v2"))
+	}, func(cls *DocumentColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Title.Kv("lost"))
+	}), gormrepo.ErrStaleObject)
+	require.Equal(t, int64(0), stale.Version)
+
+	res, err := repo.First(func(db *gorm.DB, cls *DocumentColumns) *gorm.DB {
+		return db.Where(cls.ID.Eq(document.ID))
+	})
+	require.NoError(t, err)
+	require.Equal(t, "v2", res.Title)
+	require.Equal(t, int64(1), res.Version)
+}
+
+// TestGormRepo_Saves_Version tests that Saves matches and increments the version of each record, all or nothing
+// TestGormRepo_Saves_Version 测试 Saves 匹配并递增每条记录的版本，要么全部成功要么全部失败
+func TestGormRepo_Saves_Version(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Article{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Article{}))
+
+	articles := []*Article{{Title: "a1"}, {Title: "b1"}}
+	require.NoError(t, repo.Saves(articles))
+	require.NotZero(t, articles[0].ID)
+	require.Equal(t, 0, articles[0].Revision)
+
+	stale := *articles[1]
+
+	articles[0].Title = "a2"
+	articles[1].Title = "b2"
+	require.NoError(t, repo.Saves(articles))
+	require.Equal(t, 1, articles[0].Revision)
+	require.Equal(t, 1, articles[1].Revision)
+
+	// The stale record rolls back the other ones, which get back their versions and zero primary keys
+	// 过期记录回滚其它记录，它们恢复原来的版本和零值主键
+	fresh := &Article{Title: "c1"}
+	articles[0].Title = "a3"
+	stale.Title = "lost"
+	require.ErrorIs(t, repo.Saves([]*Article{articles[0], fresh, &stale}), gormrepo.ErrStaleObject)
+	require.Equal(t, 1, articles[0].Revision)
+	require.Equal(t, 0, stale.Revision)
+	require.Zero(t, fresh.ID)
+
+	res, err := repo.Find(func(db *gorm.DB, cls *ArticleColumns) *gorm.DB {
+		return db.Order(cls.ID.Ob("asc").Ox())
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	require.Equal(t, "a2", res[0].Title)
+	require.Equal(t, "b2", res[1].Title)
+	require.Equal(t, 1, res[1].Revision)
+}
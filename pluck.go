@@ -0,0 +1,62 @@
+package gormrepo
+
+import (
+	"github.com/yyle88/gormcnm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PluckColumn retrieves values of one column from records matching the where condition
+// The result type T comes from the ColumnName[T] in CLS, so the compiler checks it
+// Go methods cannot have type params, so this is a function taking the repo
+//
+// PluckColumn 检索符合 where 条件的记录的单列值
+// 结果类型 T 来自 CLS 中的 ColumnName[T]，由编译器检查
+// Go 方法不能有类型参数，因此这是一个接收仓储的函数
+func PluckColumn[MOD any, CLS any, T any](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, column func(cls CLS) gormcnm.ColumnName[T]) ([]T, error) {
+	var results []T
+	if _, err := repo.intercept(&Invocation{Operation: OpPluckColumn, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		return where(db, repo.cls).Model((*MOD)(nil)).Pluck(column(repo.cls).Name(), &results)
+	}); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DistinctColumn retrieves distinct values of one column from records matching the where condition
+// Same as PluckColumn but removes duplicate values with SELECT DISTINCT
+//
+// DistinctColumn 检索符合 where 条件的记录的单列去重值
+// 与 PluckColumn 相同，但使用 SELECT DISTINCT 去除重复值
+func DistinctColumn[MOD any, CLS any, T any](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, column func(cls CLS) gormcnm.ColumnName[T]) ([]T, error) {
+	var results []T
+	if _, err := repo.intercept(&Invocation{Operation: OpDistinctColumn, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		return where(db, repo.cls).Model((*MOD)(nil)).Distinct().Pluck(column(repo.cls).Name(), &results)
+	}); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ScalarOf retrieves the column value of the first record matching the where condition
+// Like First, records are ordered by primary key after the orders set in where
+// Returns gorm.ErrRecordNotFound when no record matches
+//
+// ScalarOf 检索符合 where 条件的第一条记录的列值
+// 与 First 相同，在 where 中设置的排序之后按主键排序
+// 没有匹配记录时返回 gorm.ErrRecordNotFound
+func ScalarOf[MOD any, CLS any, T any](repo *GormRepo[MOD, CLS], where func(db *gorm.DB, cls CLS) *gorm.DB, column func(cls CLS) gormcnm.ColumnName[T]) (T, error) {
+	var results = make([]T, 0, 1)
+	primaryKey := clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: clause.PrimaryKey}}
+	if _, err := repo.intercept(&Invocation{Operation: OpScalarOf, Where: where, Dest: &results}, func(db *gorm.DB) *gorm.DB {
+		return where(db, repo.cls).Model((*MOD)(nil)).Order(primaryKey).Limit(1).Pluck(column(repo.cls).Name(), &results)
+	}); err != nil {
+		var zero T
+		return zero, err
+	}
+	if len(results) == 0 {
+		var zero T
+		return zero, gorm.ErrRecordNotFound
+	}
+	return results[0], nil
+}
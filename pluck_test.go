@@ -0,0 +1,119 @@
+package gormrepo_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"gorm.io/gorm"
+)
+
+// TestPluckColumn tests typed values of one column
+// TestPluckColumn 测试单列的类型化取值
+func TestPluckColumn(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	nicknames, err := gormrepo.PluckColumn(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.In([]string{"demo-1-username", "demo-2-username"}))
+	}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+		return cls.Nickname
+	})
+	require.NoError(t, err)
+	sort.Strings(nicknames)
+	require.Equal(t, []string{"demo-1-nickname", "demo-2-nickname"}, nicknames)
+
+	ids, err := gormrepo.PluckColumn(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq("demo-1-username"))
+	}, func(cls *AccountColumns) gormcnm.ColumnName[uint] {
+		return cls.ID
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	require.NotZero(t, ids[0])
+}
+
+// TestDistinctColumn tests distinct typed values of one column
+// TestDistinctColumn 测试单列的类型化去重取值
+func TestDistinctColumn(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	for _, username := range []string{"distinct-1", "distinct-2", "distinct-3"} {
+		account := newAccount(username)
+		account.Nickname = "same-nickname"
+		must.Done(db.Create(account).Error)
+	}
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	nicknames, err := gormrepo.DistinctColumn(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Like("distinct-%"))
+	}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+		return cls.Nickname
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"same-nickname"}, nicknames)
+}
+
+// TestScalarOf tests single typed value and the not found case
+// TestScalarOf 测试单个类型化取值和未找到的情况
+func TestScalarOf(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupDemoData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	t.Run("case-1", func(t *testing.T) {
+		nickname, err := gormrepo.ScalarOf(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq("demo-2-username"))
+		}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+			return cls.Nickname
+		})
+		require.NoError(t, err)
+		require.Equal(t, "demo-2-nickname", nickname)
+	})
+
+	t.Run("case-2", func(t *testing.T) {
+		nickname, err := gormrepo.ScalarOf(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq("demo-x-username"))
+		}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+			return cls.Nickname
+		})
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		require.Empty(t, nickname)
+	})
+
+	t.Run("order-by-primary-key", func(t *testing.T) {
+		// Like First, the primary key comes after the orders of where
+		// 与 First 相同，主键排在 where 的排序之后
+		var statements []string
+		must.Done(db.Callback().Query().After("gorm:query").Register("test:scalar_sql", func(db *gorm.DB) {
+			statements = append(statements, db.Statement.SQL.String())
+		}))
+		username, err := gormrepo.ScalarOf(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Like("demo-%"))
+		}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+			return cls.Username
+		})
+		require.NoError(t, err)
+		require.Equal(t, "demo-1-username", username)
+
+		username, err = gormrepo.ScalarOf(repo, func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Like("demo-%")).Order(cls.Nickname.Ob("desc").Ox())
+		}, func(cls *AccountColumns) gormcnm.ColumnName[string] {
+			return cls.Username
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, "demo-1-username", username)
+		require.Len(t, statements, 2)
+		require.Contains(t, statements[0], "ORDER BY `accounts`.`id`")
+		require.Contains(t, statements[1], "ORDER BY nickname desc,`accounts`.`id`")
+	})
+}
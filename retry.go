@@ -0,0 +1,176 @@
+package gormrepo
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// RetryPolicy configures how a transaction is retried on deadlocks and serialization failures
+// Zero values fall back to the defaults of DefaultRetryPolicy
+//
+// RetryPolicy 配置事务在死锁和序列化失败时的重试方式
+// 零值字段使用 DefaultRetryPolicy 的默认值
+type RetryPolicy struct {
+	MaxAttempts int                  // Max attempts including the first one // 包含首次在内的最大尝试次数
+	BaseDelay   time.Duration        // Delay before the second attempt, doubles each attempt // 第二次尝试前的等待时间，每次翻倍
+	MaxDelay    time.Duration        // Upper bound of the delay // 等待时间上限
+	Retryable   func(err error) bool // Decides whether the error is retryable, default IsRetryable // 判断错误是否可重试，默认 IsRetryable
+}
+
+// RetryCanceledError is returned when the context ends while waiting for the next attempt
+// errors.Is and errors.As match both the context error and the error of the last attempt
+//
+// RetryCanceledError 在等待下次尝试期间上下文结束时返回
+// errors.Is 和 errors.As 同时匹配上下文错误和最后一次尝试的错误
+type RetryCanceledError struct {
+	Canceled error // Error of the context, context.Canceled or context.DeadlineExceeded // 上下文的错误
+	Last     error // Error of the last attempt // 最后一次尝试的错误
+}
+
+// Error returns the last error followed by the context error
+// Error 返回最后一次尝试的错误以及上下文错误
+func (e *RetryCanceledError) Error() string {
+	return e.Last.Error() + ": " + e.Canceled.Error()
+}
+
+// Unwrap exposes the context error and the last error to errors.Is and errors.As
+// Unwrap 向 errors.Is 和 errors.As 暴露上下文错误和最后一次尝试的错误
+func (e *RetryCanceledError) Unwrap() []error {
+	return []error{e.Canceled, e.Last}
+}
+
+// DefaultRetryPolicy returns the policy with 3 attempts and exponential backoff from 10ms to 1s
+//
+// DefaultRetryPolicy 返回尝试 3 次、等待时间从 10ms 指数增长到 1s 的策略
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   10 * time.Millisecond,
+		MaxDelay:    time.Second,
+		Retryable:   IsRetryable,
+	}
+}
+
+// normalize fills zero fields with defaults
+// normalize 使用默认值填充零值字段
+func (policy *RetryPolicy) normalize() *RetryPolicy {
+	res := DefaultRetryPolicy()
+	if policy == nil {
+		return res
+	}
+	if policy.MaxAttempts > 0 {
+		res.MaxAttempts = policy.MaxAttempts
+	}
+	if policy.BaseDelay > 0 {
+		res.BaseDelay = policy.BaseDelay
+	}
+	if policy.MaxDelay > 0 {
+		res.MaxDelay = policy.MaxDelay
+	}
+	if policy.Retryable != nil {
+		res.Retryable = policy.Retryable
+	}
+	return res
+}
+
+// delay returns the backoff before the next attempt, with jitter to spread competing writers
+// delay 返回下次尝试前的等待时间，带随机抖动以错开竞争的写入者
+func (policy *RetryPolicy) delay(attempt int) time.Duration {
+	delay := policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// IsRetryable reports whether the error is a transient conflict that succeeds when the transaction is re-run
+// SQLite: database is locked (SQLITE_BUSY, SQLITE_LOCKED)
+// Postgres: serialization failure 40001, deadlock 40P01
+// MySQL: deadlock 1213
+// Lock wait timeouts of Postgres and MySQL are not covered, use IsRetryableOrLockTimeout to retry them too
+//
+// IsRetryable 判断错误是否为重新运行事务即可成功的临时冲突
+// SQLite：数据库被锁定（SQLITE_BUSY、SQLITE_LOCKED）
+// Postgres：序列化失败 40001、死锁 40P01
+// MySQL：死锁 1213
+// 不涵盖 Postgres 和 MySQL 的锁等待超时，需要同时重试时使用 IsRetryableOrLockTimeout
+func IsRetryable(err error) bool {
+	err = ClassifyError(err)
+	if errors.Is(err, ErrSerializationFailure) || errors.Is(err, ErrDeadlock) {
+		return true
+	}
+	// SQLite has no lock waits beyond busy_timeout, there busy and locked are the write conflict itself
+	// SQLite 除 busy_timeout 外没有锁等待，busy 和 locked 本身就是写冲突
+	_, ok := sqliteCode(err)
+	return ok && errors.Is(err, ErrLockTimeout)
+}
+
+// IsRetryableOrLockTimeout extends IsRetryable with the lock wait timeouts, Postgres 55P03 and MySQL 1205
+// Opt in with RetryPolicy{Retryable: IsRetryableOrLockTimeout} when re-running after a lock wait is acceptable
+//
+// IsRetryableOrLockTimeout 在 IsRetryable 的基础上增加锁等待超时，即 Postgres 55P03 和 MySQL 1205
+// 当可以接受锁等待后重新运行时，通过 RetryPolicy{Retryable: IsRetryableOrLockTimeout} 启用
+func IsRetryableOrLockTimeout(err error) bool {
+	return IsRetryable(err) || errors.Is(ClassifyError(err), ErrLockTimeout)
+}
+
+// RetryTransaction runs the unit of work in a transaction, re-running it in a new transaction on retryable errors
+// Each attempt gets a fresh transaction, so repos bound inside run are fresh too
+// When db is already in a transaction, runs once in a savepoint, retrying belongs to the outermost transaction
+//
+// RetryTransaction 在事务中运行工作单元，遇到可重试错误时在新事务中重新运行
+// 每次尝试都使用新事务，因此在 run 中绑定的仓储也是新的
+// 当 db 已在事务中时，只在保存点中运行一次，重试由最外层事务负责
+func RetryTransaction(ctx context.Context, db *gorm.DB, policy *RetryPolicy, run func(uow *UnitOfWork) error, opts ...*sql.TxOptions) error {
+	return retryTransaction(db.WithContext(ctx), policy, func(tx *gorm.DB) error {
+		return run(&UnitOfWork{tx: tx})
+	}, opts...)
+}
+
+// RetryTransaction runs run with a GormRepo bound to a transaction of db, retrying on retryable errors
+//
+// RetryTransaction 使用绑定到 db 事务的 GormRepo 运行 run，遇到可重试错误时重试
+func (repo *BaseRepo[MOD, CLS]) RetryTransaction(ctx context.Context, db *gorm.DB, policy *RetryPolicy, run func(repo *GormRepo[MOD, CLS]) error, opts ...*sql.TxOptions) error {
+	return retryTransaction(db.WithContext(ctx), policy, func(tx *gorm.DB) error {
+		return run(repo.Repo(tx))
+	}, opts...)
+}
+
+// RetryTransaction runs run with a GormRepo bound to a transaction of the repo connection, retrying on retryable errors
+//
+// RetryTransaction 使用绑定到仓储连接事务的 GormRepo 运行 run，遇到可重试错误时重试
+func (repo *GormRepo[MOD, CLS]) RetryTransaction(policy *RetryPolicy, run func(repo *GormRepo[MOD, CLS]) error, opts ...*sql.TxOptions) error {
+	return retryTransaction(repo.db, policy, func(tx *gorm.DB) error {
+		return run(repo.derive(tx))
+	}, opts...)
+}
+
+func retryTransaction(db *gorm.DB, policy *RetryPolicy, run func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	if InTransaction(db) {
+		return runTransaction(db, run, opts...)
+	}
+	policy = policy.normalize()
+	ctx := db.Statement.Context
+	for attempt := 1; ; attempt++ {
+		err := runTransaction(db, run, opts...)
+		if err == nil || !policy.Retryable(err) {
+			return err
+		}
+		if attempt >= policy.MaxAttempts {
+			return errors.Wrapf(err, "transaction failed after %d attempts", attempt)
+		}
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryCanceledError{Canceled: ctx.Err(), Last: err}
+		case <-timer.C:
+		}
+	}
+}
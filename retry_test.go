@@ -0,0 +1,137 @@
+package gormrepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormclass"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+)
+
+// stateError mimics the Postgres driver errors exposing SQLState
+// stateError 模拟暴露 SQLState 的 Postgres 驱动错误
+type stateError struct {
+	state string
+}
+
+func (e *stateError) Error() string    { return "ERROR: conflict (SQLSTATE " + e.state + ")" }
+func (e *stateError) SQLState() string { return e.state }
+
+// TestIsRetryable tests the classification of retryable errors on each dialect
+// TestIsRetryable 测试各数据库方言的可重试错误分类
+func TestIsRetryable(t *testing.T) {
+	require.True(t, gormrepo.IsRetryable(sqlite3.Error{Code: sqlite3.ErrBusy}))
+	require.True(t, gormrepo.IsRetryable(errors.WithMessage(sqlite3.Error{Code: sqlite3.ErrLocked}, "wrap")))
+	require.True(t, gormrepo.IsRetryable(errors.WithMessage(&stateError{state: "40001"}, "wrap")))
+	require.True(t, gormrepo.IsRetryable(&stateError{state: "40P01"}))
+	require.True(t, gormrepo.IsRetryable(&MySQLError{Number: 1213, Message: "Error 1213 (40001): Deadlock found when trying to get lock"}))
+	require.False(t, gormrepo.IsRetryable(&stateError{state: "23505"}))
+	require.False(t, gormrepo.IsRetryable(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}))
+	require.False(t, gormrepo.IsRetryable(nil))
+
+	// Lock wait timeouts are retried only when opted in
+	// 只有在启用时才重试锁等待超时
+	require.False(t, gormrepo.IsRetryable(&stateError{state: "55P03"}))
+	require.False(t, gormrepo.IsRetryable(&MySQLError{Number: 1205, Message: "Error 1205 (HY000): Lock wait timeout exceeded"}))
+	require.True(t, gormrepo.IsRetryableOrLockTimeout(&stateError{state: "55P03"}))
+	require.True(t, gormrepo.IsRetryableOrLockTimeout(&MySQLError{Number: 1205, Message: "Error 1205 (HY000): Lock wait timeout exceeded"}))
+	require.True(t, gormrepo.IsRetryableOrLockTimeout(&stateError{state: "40P01"}))
+}
+
+// TestRepo_RetryTransaction tests re-running the closure with a fresh transaction on retryable errors
+// TestRepo_RetryTransaction 测试遇到可重试错误时使用新事务重新运行闭包
+func TestRepo_RetryTransaction(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	repo := gormrepo.NewBaseRepo(gormclass.Use(&Account{}))
+	ctx := context.Background()
+	policy := &gormrepo.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	t.Run("retry-then-commit", func(t *testing.T) {
+		username := uuid.New().String()
+		var attempts int
+		var previous *gormrepo.GormRepo[Account, *AccountColumns]
+		require.NoError(t, repo.RetryTransaction(ctx, db, policy, func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			attempts++
+			require.NotSame(t, previous, repo)
+			previous = repo
+			if err := repo.Create(newAccount(username)); err != nil {
+				return err
+			}
+			if attempts < 2 {
+				return sqlite3.Error{Code: sqlite3.ErrBusy}
+			}
+			return nil
+		}))
+		require.Equal(t, 2, attempts)
+		require.True(t, existAccount(t, db, username))
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		var attempts int
+		err := repo.RetryTransaction(ctx, db, policy, func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			attempts++
+			return &stateError{state: "40P01"}
+		})
+		require.Error(t, err)
+		require.True(t, gormrepo.IsRetryable(err))
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cause := &stateError{state: "40001"}
+		err := repo.RetryTransaction(ctx, db, &gormrepo.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute}, func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			cancel()
+			return cause
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.ErrorIs(t, err, cause)
+		var retryErr *gormrepo.RetryCanceledError
+		require.ErrorAs(t, err, &retryErr)
+		require.Same(t, cause, retryErr.Last)
+	})
+
+	t.Run("not-retryable", func(t *testing.T) {
+		var attempts int
+		erx := errors.New("expected")
+		require.ErrorIs(t, repo.RetryTransaction(ctx, db, policy, func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			attempts++
+			return erx
+		}), erx)
+		require.Equal(t, 1, attempts)
+	})
+}
+
+// TestRetryTransaction tests that a nested retrying transaction runs once and leaves retrying to the outermost one
+// TestRetryTransaction 测试嵌套的重试事务只运行一次，由最外层事务负责重试
+func TestRetryTransaction(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	ctx := context.Background()
+	policy := &gormrepo.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	var outer, inner int
+	require.NoError(t, gormrepo.RetryTransaction(ctx, db, policy, func(uow *gormrepo.UnitOfWork) error {
+		outer++
+		err := gormrepo.RetryTransaction(ctx, uow.DB(), policy, func(uow *gormrepo.UnitOfWork) error {
+			inner++
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		})
+		if outer < 2 {
+			return err
+		}
+		return nil
+	}))
+	require.Equal(t, 2, outer)
+	require.Equal(t, 2, inner)
+}
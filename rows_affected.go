@@ -0,0 +1,161 @@
+package gormrepo
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/yyle88/gormcnm"
+	"gorm.io/gorm"
+)
+
+// ErrRowsAffectedMismatch is matched with errors.Is by RowsAffectedError
+// ErrRowsAffectedMismatch 可通过 errors.Is 匹配 RowsAffectedError
+var ErrRowsAffectedMismatch = errors.New("gormrepo: rows affected mismatch")
+
+// RowsAffectedError reports that an update or delete affected a different count of rows than expected
+// RowsAffectedError 报告更新或删除影响的行数与期望不符
+type RowsAffectedError struct {
+	Operation string // Operation name, e.g. "update" or "delete" // 操作名称，例如 "update" 或 "delete"
+	Expected  int64  // Expected rows affected // 期望的影响行数
+	Actual    int64  // Actual rows affected // 实际的影响行数
+}
+
+// Error returns the operation with expected and actual counts
+// Error 返回操作名称以及期望和实际的行数
+func (e *RowsAffectedError) Error() string {
+	return fmt.Sprintf("%s: %s expected %d rows affected, got %d", ErrRowsAffectedMismatch, e.Operation, e.Expected, e.Actual)
+}
+
+// Is matches ErrRowsAffectedMismatch
+// Is 匹配 ErrRowsAffectedMismatch
+func (e *RowsAffectedError) Is(target error) bool {
+	return target == ErrRowsAffectedMismatch
+}
+
+// UpdateR updates a single column and returns the rows affected
+// R = RowsAffected, enables detecting a conditional update that matched nothing
+//
+// UpdateR 更新单个列并返回影响的行数
+// R = RowsAffected，用于检测没有匹配任何行的条件更新
+func (repo *GormRepo[MOD, CLS]) UpdateR(where func(db *gorm.DB, cls CLS) *gorm.DB, valueFunc func(cls CLS) (string, interface{})) (int64, error) {
+	column, value := valueFunc(repo.cls)
+	inv := &Invocation{Operation: OpUpdate, Where: where, Values: map[string]interface{}{column: value}}
+	return repo.intercept(inv, func(db *gorm.DB) *gorm.DB {
+		return repo.wrapOf(db).Updates(where, func(cls CLS) map[string]interface{} {
+			return inv.Values
+		})
+	})
+}
+
+// UpdatesR updates multiple columns and returns the rows affected
+//
+// UpdatesR 更新多个列并返回影响的行数
+func (repo *GormRepo[MOD, CLS]) UpdatesR(where func(db *gorm.DB, cls CLS) *gorm.DB, mapValues func(cls CLS) map[string]interface{}) (int64, error) {
+	inv := &Invocation{Operation: OpUpdates, Where: where, Values: mapValues(repo.cls)}
+	return repo.intercept(inv, func(db *gorm.DB) *gorm.DB {
+		return repo.wrapOf(db).Updates(where, func(cls CLS) map[string]interface{} {
+			return inv.Values
+		})
+	})
+}
+
+// UpdatesOR updates object using primary key as condition and returns the rows affected
+//
+// UpdatesOR 使用主键作为条件更新对象并返回影响的行数
+func (repo *GormRepo[MOD, CLS]) UpdatesOR(object *MOD, newValues func(cls CLS) gormcnm.ColumnValueMap) (int64, error) {
+	inv := &Invocation{Operation: OpUpdatesO, Object: object, Values: newValues(repo.cls)}
+	return repo.intercept(inv, func(db *gorm.DB) *gorm.DB {
+		return repo.wrapOf(db).UpdatesO(object, func(cls CLS) gormcnm.ColumnValueMap {
+			return inv.Values
+		})
+	})
+}
+
+// UpdatesCR updates object using primary key plus where clause and returns the rows affected
+//
+// UpdatesCR 使用主键加上 where 子句更新对象并返回影响的行数
+func (repo *GormRepo[MOD, CLS]) UpdatesCR(object *MOD, where func(db *gorm.DB, cls CLS) *gorm.DB, newValues func(cls CLS) gormcnm.ColumnValueMap) (int64, error) {
+	inv := &Invocation{Operation: OpUpdatesC, Where: where, Object: object, Values: newValues(repo.cls)}
+	return repo.intercept(inv, func(db *gorm.DB) *gorm.DB {
+		return repo.wrapOf(db).UpdatesC(object, where, func(cls CLS) gormcnm.ColumnValueMap {
+			return inv.Values
+		})
+	})
+}
+
+// DeleteWR deletes records matching the where condition and returns the rows affected
+//
+// DeleteWR 删除符合 where 条件的记录并返回影响的行数
+func (repo *GormRepo[MOD, CLS]) DeleteWR(where func(db *gorm.DB, cls CLS) *gorm.DB) (int64, error) {
+	return repo.intercept(&Invocation{Operation: OpDeleteW, Where: where}, func(db *gorm.DB) *gorm.DB {
+		// GORM Delete needs valid instance, not nil, otherwise gets ErrInvalidValue
+		// GORM Delete 需要有效实例，不能为 nil，否则报 ErrInvalidValue 错误
+		return where(db, repo.cls).Delete(new(MOD))
+	})
+}
+
+// UpdateOne updates the columns of exactly one record matching the where condition
+// Fails with RowsAffectedError and undoes the update when not exactly one row is affected
+//
+// UpdateOne 更新恰好一条符合 where 条件的记录的列
+// 影响的行数不是一条时返回 RowsAffectedError 并撤销更新
+func (repo *GormRepo[MOD, CLS]) UpdateOne(where func(db *gorm.DB, cls CLS) *gorm.DB, newValues func(cls CLS) gormcnm.ColumnValueMap) error {
+	return repo.UpdateExactly(1, where, newValues)
+}
+
+// UpdateExactly updates the columns of records matching the where condition, expecting exactly n affected rows
+// Runs in a nested transaction (a savepoint inside a repo transaction), rolled back when the count mismatches
+// On MySQL enable clientFoundRows in the DSN, otherwise rows updated with unchanged values are not counted
+//
+// UpdateExactly 更新符合 where 条件的记录的列，期望恰好影响 n 行
+// 在嵌套事务中运行（在仓储事务中时为保存点），行数不符时回滚
+// 在 MySQL 上需在 DSN 中开启 clientFoundRows，否则值未变化的行不被计数
+func (repo *GormRepo[MOD, CLS]) UpdateExactly(n int64, where func(db *gorm.DB, cls CLS) *gorm.DB, newValues func(cls CLS) gormcnm.ColumnValueMap) error {
+	return repo.expectRowsAffected("update", n, func(repo *GormRepo[MOD, CLS]) (int64, error) {
+		return repo.UpdatesR(where, func(cls CLS) map[string]interface{} {
+			return newValues(cls).AsMap()
+		})
+	})
+}
+
+// DeleteOne deletes exactly one record matching the where condition
+// Fails with RowsAffectedError and undoes the delete when not exactly one row is affected
+//
+// DeleteOne 删除恰好一条符合 where 条件的记录
+// 影响的行数不是一条时返回 RowsAffectedError 并撤销删除
+func (repo *GormRepo[MOD, CLS]) DeleteOne(where func(db *gorm.DB, cls CLS) *gorm.DB) error {
+	return repo.DeleteExactly(1, where)
+}
+
+// DeleteExactly deletes records matching the where condition, expecting exactly n affected rows
+// Runs in a nested transaction (a savepoint inside a repo transaction), rolled back when the count mismatches
+//
+// DeleteExactly 删除符合 where 条件的记录，期望恰好影响 n 行
+// 在嵌套事务中运行（在仓储事务中时为保存点），行数不符时回滚
+func (repo *GormRepo[MOD, CLS]) DeleteExactly(n int64, where func(db *gorm.DB, cls CLS) *gorm.DB) error {
+	return repo.expectRowsAffected("delete", n, func(repo *GormRepo[MOD, CLS]) (int64, error) {
+		return repo.DeleteWR(where)
+	})
+}
+
+// expectRowsAffected runs exec in a nested transaction, returning RowsAffectedError to roll it back on mismatch
+// expectRowsAffected 在嵌套事务中运行 exec，行数不符时返回 RowsAffectedError 以回滚
+func (repo *GormRepo[MOD, CLS]) expectRowsAffected(operation string, expected int64, exec func(repo *GormRepo[MOD, CLS]) (int64, error)) error {
+	return runTransaction(repo.db, func(tx *gorm.DB) error {
+		actual, err := exec(repo.derive(tx))
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return errors.WithStack(&RowsAffectedError{Operation: operation, Expected: expected, Actual: actual})
+		}
+		return nil
+	})
+}
+
+func rowsAffected(result *gorm.DB) (int64, error) {
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,91 @@
+package gormrepo_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormcnm"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"gorm.io/gorm"
+)
+
+// TestGormRepo_UpdatesR tests the variants returning rows affected
+// TestGormRepo_UpdatesR 测试返回影响行数的变体
+func TestGormRepo_UpdatesR(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupAggregateData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	count, err := repo.UpdatesR(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Nickname.Eq("group-a"))
+	}, func(cls *AccountColumns) map[string]interface{} {
+		return cls.Kw(cls.Password.Kv("changed")).AsMap()
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	count, err = repo.UpdateR(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Nickname.Eq("group-x"))
+	}, func(cls *AccountColumns) (string, interface{}) {
+		return cls.Password.Kv("changed")
+	})
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	count, err = repo.DeleteWR(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Nickname.Eq("group-b"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+// TestGormRepo_UpdateExactly tests count assertions and the rollback on mismatch
+// TestGormRepo_UpdateExactly 测试行数断言以及行数不符时的回滚
+func TestGormRepo_UpdateExactly(t *testing.T) {
+	db := tests.NewMemDB(t)
+	setupAggregateData(t, db)
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	groupA := func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Nickname.Eq("group-a"))
+	}
+	newValues := func(cls *AccountColumns) gormcnm.ColumnValueMap {
+		return cls.Kw(cls.Password.Kv("changed"))
+	}
+	countChanged := func() int64 {
+		count, err := repo.Count(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Password.Eq("changed"))
+		})
+		require.NoError(t, err)
+		return count
+	}
+
+	t.Run("mismatch-rollback", func(t *testing.T) {
+		err := repo.UpdateOne(groupA, newValues)
+		require.ErrorIs(t, err, gormrepo.ErrRowsAffectedMismatch)
+		var rowsErr *gormrepo.RowsAffectedError
+		require.True(t, errors.As(err, &rowsErr))
+		require.Equal(t, int64(1), rowsErr.Expected)
+		require.Equal(t, int64(2), rowsErr.Actual)
+		require.Zero(t, countChanged())
+	})
+
+	t.Run("match", func(t *testing.T) {
+		require.NoError(t, repo.UpdateExactly(2, groupA, newValues))
+		require.Equal(t, int64(2), countChanged())
+	})
+
+	t.Run("in-transaction", func(t *testing.T) {
+		require.ErrorIs(t, repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			return repo.DeleteOne(groupA)
+		}), gormrepo.ErrRowsAffectedMismatch)
+		require.Equal(t, int64(2), countChanged())
+
+		require.NoError(t, repo.DeleteExactly(2, groupA))
+		require.Zero(t, countChanged())
+	})
+}
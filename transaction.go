@@ -0,0 +1,100 @@
+package gormrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork holds the transaction connection shared by the repos of several models
+// Use BaseRepo.Bind to get repos bound to the transaction
+//
+// UnitOfWork 持有多个模型仓储共享的事务连接
+// 使用 BaseRepo.Bind 获取绑定到该事务的仓储
+type UnitOfWork struct {
+	tx *gorm.DB // Transaction connection // 事务连接
+}
+
+// DB returns the transaction connection, enabling custom operations in the transaction
+//
+// DB 返回事务连接，用于在事务中执行自定义操作
+func (uow *UnitOfWork) DB() *gorm.DB {
+	return uow.tx
+}
+
+// Transaction runs a nested unit of work in a savepoint of the current transaction
+// Rolls back to the savepoint when run returns an error, the outer transaction continues
+//
+// Transaction 在当前事务的保存点中运行嵌套的工作单元
+// run 返回错误时回滚到保存点，外层事务继续
+func (uow *UnitOfWork) Transaction(run func(uow *UnitOfWork) error) error {
+	return runTransaction(uow.tx, func(tx *gorm.DB) error {
+		return run(&UnitOfWork{tx: tx})
+	})
+}
+
+// Transaction runs a unit of work in a transaction, repos of several models can be bound to it
+// Commits when run returns nil, otherwise rolls back and returns the error
+// When db is already in a transaction, runs in a savepoint and opts are ignored
+//
+// Transaction 在事务中运行工作单元，多个模型的仓储可以绑定到该工作单元
+// run 返回 nil 时提交，否则回滚并返回错误
+// 当 db 已在事务中时，在保存点中运行且忽略 opts
+func Transaction(ctx context.Context, db *gorm.DB, run func(uow *UnitOfWork) error, opts ...*sql.TxOptions) error {
+	return runTransaction(db.WithContext(ctx), func(tx *gorm.DB) error {
+		return run(&UnitOfWork{tx: tx})
+	}, opts...)
+}
+
+// InTransaction reports whether db is bound to an open transaction
+// InTransaction 判断 db 是否绑定到已开启的事务
+func InTransaction(db *gorm.DB) bool {
+	committer, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok && committer != nil
+}
+
+// Bind creates a GormRepo bound to the transaction of the unit of work
+//
+// Bind 创建绑定到工作单元事务的 GormRepo
+func (repo *BaseRepo[MOD, CLS]) Bind(uow *UnitOfWork) *GormRepo[MOD, CLS] {
+	return repo.Repo(uow.tx)
+}
+
+// Transaction runs run with a GormRepo bound to a transaction of db
+// Commits when run returns nil, otherwise rolls back and returns the error
+// Pass sql.TxOptions to set isolation level or read-only mode
+//
+// Transaction 使用绑定到 db 事务的 GormRepo 运行 run
+// run 返回 nil 时提交，否则回滚并返回错误
+// 传入 sql.TxOptions 以设置隔离级别或只读模式
+func (repo *BaseRepo[MOD, CLS]) Transaction(ctx context.Context, db *gorm.DB, run func(repo *GormRepo[MOD, CLS]) error, opts ...*sql.TxOptions) error {
+	return runTransaction(db.WithContext(ctx), func(tx *gorm.DB) error {
+		return run(repo.Repo(tx))
+	}, opts...)
+}
+
+// Transaction runs run with a GormRepo bound to a transaction of the repo connection
+// When the repo is already bound to a transaction, runs in a nested savepoint
+//
+// Transaction 使用绑定到仓储连接事务的 GormRepo 运行 run
+// 当仓储已绑定到事务时，在嵌套的保存点中运行
+func (repo *GormRepo[MOD, CLS]) Transaction(run func(repo *GormRepo[MOD, CLS]) error, opts ...*sql.TxOptions) error {
+	return runTransaction(repo.db, func(tx *gorm.DB) error {
+		return run(repo.derive(tx))
+	}, opts...)
+}
+
+// runTransaction is the single entry of all repo transactions
+// GORM begins a transaction, or a savepoint when db is already in a transaction
+// The transaction context carries the queue of OnCommit and OnRollback callbacks
+//
+// runTransaction 是所有仓储事务的统一入口
+// GORM 开启事务，当 db 已在事务中时开启保存点
+// 事务上下文携带 OnCommit 和 OnRollback 回调队列
+func runTransaction(db *gorm.DB, run func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	db, hooks := beginHooks(db)
+	err := db.Transaction(run, opts...)
+	hooks.finish(err)
+	return err
+}
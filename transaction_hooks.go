@@ -0,0 +1,156 @@
+package gormrepo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ErrNotInTransaction is returned when registering a callback outside a repo transaction
+// Transactions begun directly with db.Transaction or db.Begin carry no callback queue
+//
+// ErrNotInTransaction 在仓储事务之外注册回调时返回
+// 直接使用 db.Transaction 或 db.Begin 开启的事务不携带回调队列
+var ErrNotInTransaction = errors.New("gormrepo: not in a repo transaction")
+
+// txHooksKey is the context key of the callback queue of the current transaction
+// txHooksKey 是当前事务回调队列的上下文键
+type txHooksKey struct{}
+
+// txHooks queues the callbacks registered in one transaction or savepoint
+// On commit of a savepoint the callbacks move into the parent, so they run only when the outermost transaction commits
+//
+// txHooks 保存在一个事务或保存点中注册的回调
+// 保存点提交时回调移交给上层，因此只在最外层事务提交时运行
+type txHooks struct {
+	mutex      sync.Mutex
+	parent     *txHooks
+	onCommit   []func()
+	onRollback []func()
+}
+
+// beginHooks attaches a new callback queue to the context of db, nested in the queue of the enclosing transaction
+// Returns no queue when db is in a transaction begun outside the repo, since its commit cannot be observed
+//
+// beginHooks 将新的回调队列附加到 db 的上下文，嵌套在外层事务的队列中
+// 当 db 处于仓储之外开启的事务中时不返回队列，因为无法观察到其提交
+func beginHooks(db *gorm.DB) (*gorm.DB, *txHooks) {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent, _ := ctx.Value(txHooksKey{}).(*txHooks)
+	if parent == nil && InTransaction(db) {
+		return db, nil
+	}
+	hooks := &txHooks{parent: parent}
+	return db.WithContext(context.WithValue(ctx, txHooksKey{}, hooks)), hooks
+}
+
+// finish runs or hands over the callbacks when the transaction or savepoint ends
+// On rollback runs the rollback callbacks and drops the commit callbacks
+// On commit of a savepoint hands both over to the parent, on commit of the outermost transaction runs the commit callbacks
+//
+// finish 在事务或保存点结束时运行或移交回调
+// 回滚时运行回滚回调并丢弃提交回调
+// 保存点提交时将两者移交给上层，最外层事务提交时运行提交回调
+func (hooks *txHooks) finish(err error) {
+	if hooks == nil {
+		return
+	}
+	hooks.mutex.Lock()
+	onCommit, onRollback := hooks.onCommit, hooks.onRollback
+	hooks.onCommit, hooks.onRollback = nil, nil
+	hooks.mutex.Unlock()
+
+	if err != nil {
+		for _, fn := range onRollback {
+			fn()
+		}
+		return
+	}
+	if hooks.parent != nil {
+		hooks.parent.mutex.Lock()
+		hooks.parent.onCommit = append(hooks.parent.onCommit, onCommit...)
+		hooks.parent.onRollback = append(hooks.parent.onRollback, onRollback...)
+		hooks.parent.mutex.Unlock()
+		return
+	}
+	for _, fn := range onCommit {
+		fn()
+	}
+}
+
+// hooksOf returns the callback queue of the transaction carried by ctx
+// hooksOf 返回 ctx 携带的事务回调队列
+func hooksOf(ctx context.Context) (*txHooks, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	hooks, ok := ctx.Value(txHooksKey{}).(*txHooks)
+	return hooks, ok
+}
+
+// OnCommit registers fn to run after the repo transaction of db commits
+// Callbacks run in registration order, and are dropped when the transaction or the enclosing savepoint rolls back
+// Returns ErrNotInTransaction when db is not in a repo transaction
+//
+// OnCommit 注册在 db 所在的仓储事务提交后运行的 fn
+// 回调按注册顺序运行，当事务或所在的保存点回滚时被丢弃
+// 当 db 不在仓储事务中时返回 ErrNotInTransaction
+func OnCommit(db *gorm.DB, fn func()) error {
+	hooks, ok := hooksOf(db.Statement.Context)
+	if !ok {
+		return errors.WithStack(ErrNotInTransaction)
+	}
+	hooks.mutex.Lock()
+	defer hooks.mutex.Unlock()
+	hooks.onCommit = append(hooks.onCommit, fn)
+	return nil
+}
+
+// OnRollback registers fn to run after the repo transaction of db, or the enclosing savepoint, rolls back
+// Returns ErrNotInTransaction when db is not in a repo transaction
+//
+// OnRollback 注册在 db 所在的仓储事务或保存点回滚后运行的 fn
+// 当 db 不在仓储事务中时返回 ErrNotInTransaction
+func OnRollback(db *gorm.DB, fn func()) error {
+	hooks, ok := hooksOf(db.Statement.Context)
+	if !ok {
+		return errors.WithStack(ErrNotInTransaction)
+	}
+	hooks.mutex.Lock()
+	defer hooks.mutex.Unlock()
+	hooks.onRollback = append(hooks.onRollback, fn)
+	return nil
+}
+
+// OnCommit registers fn to run after the transaction of the unit of work commits
+//
+// OnCommit 注册在工作单元的事务提交后运行的 fn
+func (uow *UnitOfWork) OnCommit(fn func()) error {
+	return OnCommit(uow.tx, fn)
+}
+
+// OnRollback registers fn to run after the transaction of the unit of work rolls back
+//
+// OnRollback 注册在工作单元的事务回滚后运行的 fn
+func (uow *UnitOfWork) OnRollback(fn func()) error {
+	return OnRollback(uow.tx, fn)
+}
+
+// OnCommit registers fn to run after the repo transaction commits
+//
+// OnCommit 注册在仓储事务提交后运行的 fn
+func (repo *GormRepo[MOD, CLS]) OnCommit(fn func()) error {
+	return OnCommit(repo.db, fn)
+}
+
+// OnRollback registers fn to run after the repo transaction rolls back
+//
+// OnRollback 注册在仓储事务回滚后运行的 fn
+func (repo *GormRepo[MOD, CLS]) OnRollback(fn func()) error {
+	return OnRollback(repo.db, fn)
+}
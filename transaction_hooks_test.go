@@ -0,0 +1,100 @@
+package gormrepo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormclass"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"gorm.io/gorm"
+)
+
+// TestGormRepo_OnCommit tests that commit callbacks run in order only after the outermost commit
+// TestGormRepo_OnCommit 测试提交回调只在最外层提交后按顺序运行
+func TestGormRepo_OnCommit(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	var events []string
+	require.NoError(t, repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+		must.Done(repo.OnCommit(func() { events = append(events, "outer-1") }))
+		must.Done(repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			must.Done(repo.OnCommit(func() { events = append(events, "inner") }))
+			return nil
+		}))
+		must.Done(repo.OnCommit(func() { events = append(events, "outer-2") }))
+		require.Empty(t, events)
+		return nil
+	}))
+	require.Equal(t, []string{"outer-1", "inner", "outer-2"}, events)
+}
+
+// TestGormRepo_OnRollback tests that rollback drops commit callbacks, including those of committed savepoints
+// TestGormRepo_OnRollback 测试回滚会丢弃提交回调，包括已提交保存点中的回调
+func TestGormRepo_OnRollback(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+	erx := errors.New("expected")
+
+	t.Run("outer-rollback", func(t *testing.T) {
+		var events []string
+		require.ErrorIs(t, repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			must.Done(repo.OnCommit(func() { events = append(events, "commit") }))
+			must.Done(repo.OnRollback(func() { events = append(events, "rollback-outer") }))
+			must.Done(repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+				must.Done(repo.OnCommit(func() { events = append(events, "commit-inner") }))
+				must.Done(repo.OnRollback(func() { events = append(events, "rollback-inner") }))
+				return nil
+			}))
+			return erx
+		}), erx)
+		require.Equal(t, []string{"rollback-outer", "rollback-inner"}, events)
+	})
+
+	t.Run("savepoint-rollback", func(t *testing.T) {
+		var events []string
+		require.NoError(t, repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			must.Done(repo.OnCommit(func() { events = append(events, "commit-outer") }))
+			require.ErrorIs(t, repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+				must.Done(repo.OnCommit(func() { events = append(events, "commit-inner") }))
+				must.Done(repo.OnRollback(func() { events = append(events, "rollback-inner") }))
+				return erx
+			}), erx)
+			return nil
+		}))
+		require.Equal(t, []string{"rollback-inner", "commit-outer"}, events)
+	})
+}
+
+// TestOnCommit tests callbacks on the unit of work and outside repo transactions
+// TestOnCommit 测试工作单元上的回调以及仓储事务之外的情况
+func TestOnCommit(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	var committed bool
+	require.NoError(t, gormrepo.Transaction(context.Background(), db, func(uow *gormrepo.UnitOfWork) error {
+		return uow.OnCommit(func() { committed = true })
+	}))
+	require.True(t, committed)
+
+	require.ErrorIs(t, gormrepo.OnCommit(db, func() {}), gormrepo.ErrNotInTransaction)
+
+	// Transactions begun outside the repo cannot report their commit
+	// 仓储之外开启的事务无法报告其提交
+	repo := gormrepo.NewBaseRepo(gormclass.Use(&Account{}))
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return repo.Repo(tx).Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			require.ErrorIs(t, repo.OnCommit(func() {}), gormrepo.ErrNotInTransaction)
+			return nil
+		})
+	}))
+}
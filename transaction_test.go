@@ -0,0 +1,129 @@
+package gormrepo_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/gormclass"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"gorm.io/gorm"
+)
+
+// existAccount checks whether the account with the username exists
+// existAccount 检查指定用户名的账户是否存在
+func existAccount(t *testing.T, db *gorm.DB, username string) bool {
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+	exist, err := repo.Exist(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq(username))
+	})
+	require.NoError(t, err)
+	return exist
+}
+
+// TestRepo_Transaction tests commit and rollback of BaseRepo.Transaction
+// TestRepo_Transaction 测试 BaseRepo.Transaction 的提交和回滚
+func TestRepo_Transaction(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	repo := gormrepo.NewBaseRepo(gormclass.Use(&Account{}))
+	ctx := context.Background()
+
+	t.Run("commit", func(t *testing.T) {
+		username := uuid.New().String()
+		require.NoError(t, repo.Transaction(ctx, db, func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			return repo.Create(newAccount(username))
+		}))
+		require.True(t, existAccount(t, db, username))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		username := uuid.New().String()
+		erx := errors.New("expected")
+		require.ErrorIs(t, repo.Transaction(ctx, db, func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			require.NoError(t, repo.Create(newAccount(username)))
+			return erx
+		}), erx)
+		require.False(t, existAccount(t, db, username))
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		require.NoError(t, repo.Transaction(ctx, db, func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			_, err := repo.Count(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+				return db
+			})
+			return err
+		}, &sql.TxOptions{ReadOnly: true}))
+	})
+}
+
+// TestGormRepo_Transaction tests nested savepoints via GormRepo.Transaction
+// TestGormRepo_Transaction 测试通过 GormRepo.Transaction 使用嵌套保存点
+func TestGormRepo_Transaction(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	outerName := uuid.New().String()
+	innerName := uuid.New().String()
+	erx := errors.New("expected")
+	require.NoError(t, repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+		if err := repo.Create(newAccount(outerName)); err != nil {
+			return err
+		}
+		// Savepoint rolls back alone, the outer transaction still commits
+		// 保存点单独回滚，外层事务仍然提交
+		require.ErrorIs(t, repo.Transaction(func(repo *gormrepo.GormRepo[Account, *AccountColumns]) error {
+			require.NoError(t, repo.Create(newAccount(innerName)))
+			return erx
+		}), erx)
+		return nil
+	}))
+	require.True(t, existAccount(t, db, outerName))
+	require.False(t, existAccount(t, db, innerName))
+}
+
+// TestTransaction tests the unit of work handing out tx-bound repos
+// TestTransaction 测试工作单元分发绑定事务的仓储
+func TestTransaction(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	accounts := gormrepo.NewBaseRepo(gormclass.Use(&Account{}))
+
+	username1 := uuid.New().String()
+	username2 := uuid.New().String()
+	erx := errors.New("expected")
+	require.NoError(t, gormrepo.Transaction(context.Background(), db, func(uow *gormrepo.UnitOfWork) error {
+		if err := accounts.Bind(uow).Create(newAccount(username1)); err != nil {
+			return err
+		}
+		require.ErrorIs(t, uow.Transaction(func(uow *gormrepo.UnitOfWork) error {
+			require.NoError(t, accounts.Bind(uow).Create(newAccount(username2)))
+			return erx
+		}), erx)
+		return nil
+	}))
+	require.True(t, existAccount(t, db, username1))
+	require.False(t, existAccount(t, db, username2))
+}
+
+// TestInTransaction tests telling transaction connections apart from plain ones
+// TestInTransaction 测试区分事务连接和普通连接
+func TestInTransaction(t *testing.T) {
+	db := tests.NewMemDB(t)
+
+	require.False(t, gormrepo.InTransaction(db))
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		require.True(t, gormrepo.InTransaction(tx))
+		require.True(t, gormrepo.InTransaction(tx.Session(&gorm.Session{NewDB: true})))
+		return nil
+	}))
+}
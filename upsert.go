@@ -0,0 +1,165 @@
+package gormrepo
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ColumnNameFace is the column name abstraction satisfied by every gormcnm.ColumnName[T]
+// Enables listing columns of different types in one slice, e.g. []ColumnNameFace{cls.ID, cls.Name}
+//
+// ColumnNameFace 是所有 gormcnm.ColumnName[T] 都满足的列名抽象
+// 使不同类型的列能放在同一个切片中，例如 []ColumnNameFace{cls.ID, cls.Name}
+type ColumnNameFace interface {
+	Name() string
+}
+
+// Upsert inserts the record, or updates the update columns when the conflict columns hit an existing row
+// The write is one native statement: ON CONFLICT on SQLite and Postgres, ON DUPLICATE KEY on MySQL
+// Without update columns the conflicting insert does nothing
+// Returns true when the row was inserted, false when it was found (and updated)
+// MySQL reports this through RowsAffected; elsewhere an update affects one row like an insert, so it comes from a lookup just before the write
+// The lookup ignores the scopes of the connection and shares a transaction with the write
+// SQLite serializes that transaction against other writers, so the flag is exact, or the upsert fails with a busy error IsRetryable accepts
+// On Postgres (READ COMMITTED) the flag is best-effort: a concurrent insert or delete in between can make it wrong, while the write stays atomic
+// The record is reloaded from database when found, so its primary key gets populated
+//
+// Upsert 插入记录，当冲突列命中已有行时更新指定的更新列
+// 写入是一条原生语句：SQLite 和 Postgres 上为 ON CONFLICT，MySQL 上为 ON DUPLICATE KEY
+// 没有更新列时，冲突的插入不做任何操作
+// 插入时返回 true，找到（并更新）时返回 false
+// MySQL 通过 RowsAffected 报告这一点；其他数据库上更新与插入一样影响一行，因此通过写入前的一次查找判断
+// 该查找忽略连接上的 scopes，并与写入共享同一事务
+// SQLite 将该事务与其他写入串行化，因此结果准确，否则 upsert 以 IsRetryable 接受的 busy 错误失败
+// 在 Postgres（READ COMMITTED）上结果是尽力而为的：两者之间的并发插入或删除可能使其不准确，但写入保持原子性
+// 找到已有行时会从数据库重新加载记录，使其主键被填充
+func (repo *GormRepo[MOD, CLS]) Upsert(one *MOD, conflictColumns func(cls CLS) []ColumnNameFace, updateColumns func(cls CLS) []ColumnNameFace) (bool, error) {
+	sch, err := ParseSchema[MOD](repo.db)
+	if err != nil {
+		return false, err
+	}
+	var rv = reflect.Indirect(reflect.ValueOf(one))
+	var ctx = repo.db.Statement.Context
+	var columns []clause.Column
+	var conflictValues = make(map[string]interface{})
+	for _, column := range conflictColumns(repo.cls) {
+		field, err := lookupField(sch, column.Name())
+		if err != nil {
+			return false, err
+		}
+		columns = append(columns, clause.Column{Name: field.DBName})
+		conflictValues[field.DBName], _ = field.ValueOf(ctx, rv)
+	}
+	// located selects the conflicting row on a new statement, so the scopes of the connection cannot hide it
+	// located 在新语句上选择冲突的行，因此连接上的 scopes 无法隐藏它
+	var located = func(db *gorm.DB) *gorm.DB {
+		return db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(new(MOD)).Where(conflictValues)
+	}
+	var assignments []string
+	for _, column := range updateColumns(repo.cls) {
+		field, err := lookupField(sch, column.Name())
+		if err != nil {
+			return false, err
+		}
+		assignments = append(assignments, field.DBName)
+	}
+
+	var onConflict = clause.OnConflict{Columns: columns, DoNothing: len(assignments) == 0}
+	if !onConflict.DoNothing {
+		onConflict.DoUpdates = clause.AssignmentColumns(assignments)
+	}
+	var inserted bool
+	if _, err := repo.intercept(&Invocation{Operation: OpUpsert, Object: one}, func(db *gorm.DB) *gorm.DB {
+		if onConflict.DoNothing || db.Dialector.Name() == "mysql" {
+			result := db.Clauses(onConflict).Create(one)
+			if onConflict.DoNothing {
+				inserted = result.RowsAffected > 0
+			} else {
+				inserted = result.RowsAffected == 1
+			}
+			return result
+		}
+		var result *gorm.DB
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			var count int64
+			if err := located(tx).Count(&count).Error; err != nil {
+				return err
+			}
+			result = tx.Clauses(onConflict).Create(one)
+			inserted = count == 0
+			return result.Error
+		}); err != nil {
+			return withError(db, err)
+		}
+		return result
+	}); err != nil {
+		return false, err
+	}
+	if inserted {
+		return true, nil
+	}
+	// Load into a fresh instance, since the conflicting insert may have left values in the primary key
+	// A row deleted meanwhile leaves the record as written
+	// 加载到新实例中，因为冲突的插入可能在主键中留下了值
+	// 期间被删除的行会使记录保持写入时的值
+	var res = new(MOD)
+	if err := located(repo.db).Take(res).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	*one = *res
+	return false, nil
+}
+
+// FirstOrCreate finds the first record matching the where condition, or creates the one built by build
+// Returns true when the record was created, false when it was found
+// When a concurrent insert wins with a unique violation, retries the lookup once and returns the winner
+//
+// FirstOrCreate 查找符合 where 条件的第一条记录，找不到时创建 build 构建的记录
+// 创建时返回 true，找到时返回 false
+// 当并发插入胜出导致唯一约束冲突时，重新查找一次并返回胜出的记录
+func (repo *GormRepo[MOD, CLS]) FirstOrCreate(where func(db *gorm.DB, cls CLS) *gorm.DB, build func() *MOD) (*MOD, bool, error) {
+	one, err := repo.First(where)
+	if err == nil {
+		return one, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+	one = build()
+	// Insert in a nested transaction (a savepoint when already in one), so a failure does not abort the outer transaction
+	// 在嵌套事务中插入（已在事务中时为保存点），插入失败不会中止外层事务
+	// The insert passes through the interceptors as OpCreate, like Create
+	// 插入与 Create 一样作为 OpCreate 经过拦截器
+	if _, err := repo.intercept(&Invocation{Operation: OpCreate, Object: one}, func(db *gorm.DB) *gorm.DB {
+		var result *gorm.DB
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			result = tx.Create(one)
+			return result.Error
+		}); err != nil {
+			return withError(db, err)
+		}
+		return result
+	}); err != nil {
+		if !isUniqueViolation(err) {
+			return nil, false, err
+		}
+		one, err = repo.First(where)
+		if err != nil {
+			return nil, false, err
+		}
+		return one, false, nil
+	}
+	return one, true, nil
+}
+
+// isUniqueViolation reports whether the error is a unique constraint violation
+// isUniqueViolation 判断错误是否为唯一约束冲突
+func isUniqueViolation(err error) bool {
+	return errors.Is(ClassifyError(err), ErrUniqueViolation)
+}
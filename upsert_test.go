@@ -0,0 +1,133 @@
+package gormrepo_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gormrepo"
+	"github.com/yyle88/gormrepo/internal/tests"
+	"github.com/yyle88/must"
+	"gorm.io/gorm"
+)
+
+// TestGormRepo_Upsert tests insert on the first call and update on the conflicting call
+// TestGormRepo_Upsert 测试首次调用时插入、冲突调用时更新
+func TestGormRepo_Upsert(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	conflictColumns := func(cls *AccountColumns) []gormrepo.ColumnNameFace {
+		return []gormrepo.ColumnNameFace{cls.Username}
+	}
+	updateColumns := func(cls *AccountColumns) []gormrepo.ColumnNameFace {
+		return []gormrepo.ColumnNameFace{cls.Nickname}
+	}
+
+	username := uuid.New().String()
+	account1 := newAccount(username)
+	inserted, err := repo.Upsert(account1, conflictColumns, updateColumns)
+	require.NoError(t, err)
+	require.True(t, inserted)
+	require.NotZero(t, account1.ID)
+
+	account2 := newAccount(username)
+	inserted, err = repo.Upsert(account2, conflictColumns, updateColumns)
+	require.NoError(t, err)
+	require.False(t, inserted)
+	require.Equal(t, account1.ID, account2.ID)
+
+	res, err := repo.First(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+		return db.Where(cls.Username.Eq(username))
+	})
+	require.NoError(t, err)
+	require.Equal(t, account2.Nickname, res.Nickname)
+	require.Equal(t, account1.Password, res.Password)
+
+	// Without update columns the conflicting insert changes nothing
+	// 没有更新列时，冲突的插入不做任何修改
+	account3 := newAccount(username)
+	inserted, err = repo.Upsert(account3, conflictColumns, func(cls *AccountColumns) []gormrepo.ColumnNameFace {
+		return nil
+	})
+	require.NoError(t, err)
+	require.False(t, inserted)
+	require.Equal(t, account1.ID, account3.ID)
+	require.Equal(t, account2.Nickname, account3.Nickname)
+}
+
+// TestGormRepo_Upsert_Scoped tests that scopes on the connection cannot hide the conflicting row from the inserted flag
+// TestGormRepo_Upsert_Scoped 测试连接上的 scopes 无法对 inserted 标志隐藏冲突的行
+func TestGormRepo_Upsert_Scoped(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	username := uuid.New().String()
+	account := newAccount(username)
+	must.Done(db.Create(account).Error)
+
+	scoped := db.Scopes(func(db *gorm.DB) *gorm.DB {
+		return db.Where("nickname = ?", "no-such-nickname")
+	}).Session(&gorm.Session{})
+	repo := gormrepo.NewGormRepo(gormrepo.Use(scoped, &Account{}))
+
+	one := newAccount(username)
+	inserted, err := repo.Upsert(one, func(cls *AccountColumns) []gormrepo.ColumnNameFace {
+		return []gormrepo.ColumnNameFace{cls.Username}
+	}, func(cls *AccountColumns) []gormrepo.ColumnNameFace {
+		return []gormrepo.ColumnNameFace{cls.Password}
+	})
+	require.NoError(t, err)
+	require.False(t, inserted)
+	require.Equal(t, account.ID, one.ID)
+}
+
+// TestGormRepo_FirstOrCreate tests create on miss, find on hit, and recovery from a lost insert race
+// TestGormRepo_FirstOrCreate 测试未命中时创建、命中时查找，以及插入竞争失败后的恢复
+func TestGormRepo_FirstOrCreate(t *testing.T) {
+	db := tests.NewMemDB(t)
+	must.Done(db.AutoMigrate(&Account{}))
+
+	repo := gormrepo.NewGormRepo(gormrepo.Use(db, &Account{}))
+
+	t.Run("create-then-find", func(t *testing.T) {
+		username := uuid.New().String()
+		where := func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq(username))
+		}
+
+		one, created, err := repo.FirstOrCreate(where, func() *Account {
+			return newAccount(username)
+		})
+		require.NoError(t, err)
+		require.True(t, created)
+		require.NotZero(t, one.ID)
+
+		two, created, err := repo.FirstOrCreate(where, func() *Account {
+			return newAccount(username)
+		})
+		require.NoError(t, err)
+		require.False(t, created)
+		require.Equal(t, one.ID, two.ID)
+	})
+
+	t.Run("lost-race", func(t *testing.T) {
+		username := uuid.New().String()
+		winner := newAccount(username)
+
+		one, created, err := repo.FirstOrCreate(func(db *gorm.DB, cls *AccountColumns) *gorm.DB {
+			return db.Where(cls.Username.Eq(username))
+		}, func() *Account {
+			// Simulate a concurrent insert landing between the lookup and the create
+			// 模拟在查找和创建之间发生的并发插入
+			must.Done(db.Create(winner).Error)
+			return newAccount(username)
+		})
+		require.NoError(t, err)
+		require.False(t, created)
+		require.Equal(t, winner.ID, one.ID)
+		require.Equal(t, winner.Nickname, one.Nickname)
+	})
+}
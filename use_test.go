@@ -73,6 +73,22 @@ type AccountColumns struct {
 	Nickname  gormcnm.ColumnName[string]
 }
 
+// Document is the test struct declaring its version column with the gormrepo tag
+// Document 是使用 gormrepo 标签声明版本列的测试结构体
+type Document struct {
+	ID      uint
+	Title   string
+	Version int64 `gormrepo:"version"`
+}
+
+// Article is the test struct whose version column is declared by its columns struct
+// Article 是由其列结构体声明版本列的测试结构体
+type Article struct {
+	ID       uint
+	Title    string
+	Revision int
+}
+
 // TestGenerateColumns tests the column generation
 // TestGenerateColumns 测试列生成
 func TestGenerateColumns(t *testing.T) {
@@ -86,7 +102,7 @@ func TestGenerateColumns(t *testing.T) {
 
 	// List the models to have columns generated. Both instance and non-instance types are supported.
 	// 设置需要生成列的模型，这里支持指针类型和非指针类型。
-	objects := []any{&Account{}}
+	objects := []any{&Account{}, &Document{}, &Article{}}
 
 	options := gormcngen.NewOptions().
 		WithColumnClassExportable(true). // Generate exportable struct names (e.g., ExampleColumns) // 生成可导出的结构体名称（例如 ExampleColumns）
@@ -117,3 +133,39 @@ func TestUmc(t *testing.T) {
 		require.NotNil(t, repo)
 	})
 }
+
+func (a *Document) Columns() *DocumentColumns {
+	return &DocumentColumns{
+		// Auto-generated: column names and types mapping. DO NOT EDIT. // 自动生成：列名和类型映射。请勿编辑。
+		ID:      gormcnm.Cnm(a.ID, "id"),
+		Title:   gormcnm.Cnm(a.Title, "title"),
+		Version: gormcnm.Cnm(a.Version, "version"),
+	}
+}
+
+type DocumentColumns struct {
+	// Auto-generated: embedding operation functions to make it simple to use. DO NOT EDIT. // 自动生成：嵌入操作函数便于使用。请勿编辑。
+	gormcnm.ColumnOperationClass
+	// Auto-generated: column names and types in database table. DO NOT EDIT. // 自动生成：数据库表的列名和类型。请勿编辑。
+	ID      gormcnm.ColumnName[uint]
+	Title   gormcnm.ColumnName[string]
+	Version gormcnm.ColumnName[int64]
+}
+
+func (a *Article) Columns() *ArticleColumns {
+	return &ArticleColumns{
+		// Auto-generated: column names and types mapping. DO NOT EDIT. // 自动生成：列名和类型映射。请勿编辑。
+		ID:       gormcnm.Cnm(a.ID, "id"),
+		Title:    gormcnm.Cnm(a.Title, "title"),
+		Revision: gormcnm.Cnm(a.Revision, "revision"),
+	}
+}
+
+type ArticleColumns struct {
+	// Auto-generated: embedding operation functions to make it simple to use. DO NOT EDIT. // 自动生成：嵌入操作函数便于使用。请勿编辑。
+	gormcnm.ColumnOperationClass
+	// Auto-generated: column names and types in database table. DO NOT EDIT. // 自动生成：数据库表的列名和类型。请勿编辑。
+	ID       gormcnm.ColumnName[uint]
+	Title    gormcnm.ColumnName[string]
+	Revision gormcnm.ColumnName[int]
+}
@@ -26,7 +26,7 @@ func (wrap *GormWrap[MOD, CLS]) Repo() *GormRepo[MOD, CLS] {
 // Mold 在 GormRepo 的 DB 实例上设置默认模型模板 (MOD)
 // 返回新的 GormRepo 实例以支持链式操作
 func (repo *GormRepo[MOD, CLS]) Mold() *GormRepo[MOD, CLS] {
-	return NewGormRepo(repo.db.Model((*MOD)(nil)), (*MOD)(nil), repo.cls)
+	return repo.derive(repo.db.Model((*MOD)(nil)))
 }
 
 // Mold sets the default model template (MOD) on the GormWrap DB instance
@@ -44,7 +44,7 @@ func (wrap *GormWrap[MOD, CLS]) Mold() *GormWrap[MOD, CLS] {
 // WithContext 在 GormRepo 的 DB 实例上设置上下文
 // 返回新的 GormRepo 实例以支持链式操作
 func (repo *GormRepo[MOD, CLS]) WithContext(ctx context.Context) *GormRepo[MOD, CLS] {
-	return NewGormRepo(repo.db.WithContext(ctx), (*MOD)(nil), repo.cls)
+	return repo.derive(repo.db.WithContext(ctx))
 }
 
 // WithContext sets the context on the GormWrap DB instance